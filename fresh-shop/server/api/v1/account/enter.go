@@ -6,4 +6,5 @@ type ApiGroup struct {
 	SysRechargeApi
 	UserFinanceTypeApi
 	UserFinanceCashApi
+	UserCommissionApi
 }
@@ -0,0 +1,121 @@
+package account
+
+import (
+	"fresh-shop/server/global"
+	accountReq "fresh-shop/server/model/account/request"
+	"fresh-shop/server/model/common/request"
+	"fresh-shop/server/model/common/response"
+	"fresh-shop/server/service"
+	"fresh-shop/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type UserCommissionApi struct {
+}
+
+var userCommissionService = service.ServiceGroupApp.AccountServiceGroup.UserCommissionService
+
+// GetUserCommissionList 分页获取分销佣金记录
+// @Tags UserCommission
+// @Summary 分页获取分销佣金记录
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query accountReq.UserCommissionSearch true "分页获取分销佣金记录"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /userCommission/getUserCommissionList [get]
+func (userCommissionApi *UserCommissionApi) GetUserCommissionList(c *gin.Context) {
+	var pageInfo accountReq.UserCommissionSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if list, total, err := userCommissionService.GetUserCommissionInfoList(pageInfo); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithDetailed(response.PageResult{
+			List:     list,
+			Total:    total,
+			Page:     pageInfo.Page,
+			PageSize: pageInfo.PageSize,
+		}, "获取成功", c)
+	}
+}
+
+// GetMyCommissionList 我的佣金明细
+// @Tags UserCommission
+// @Summary 分页获取当前用户的佣金明细
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query accountReq.MyCommissionSearch true "状态 0冻结中 1已结算 2已撤销"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /userCommission/getMyCommissionList [get]
+func (userCommissionApi *UserCommissionApi) GetMyCommissionList(c *gin.Context) {
+	var pageInfo accountReq.MyCommissionSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if list, total, err := userCommissionService.GetMyCommissionList(utils.GetUserID(c), pageInfo); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithDetailed(response.PageResult{
+			List:     list,
+			Total:    total,
+			Page:     pageInfo.Page,
+			PageSize: pageInfo.PageSize,
+		}, "获取成功", c)
+	}
+}
+
+// GetMyTeam 我的团队统计
+// @Tags UserCommission
+// @Summary 获取当前用户的团队人数、团队业绩和累计佣金
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /userCommission/getMyTeam [get]
+func (userCommissionApi *UserCommissionApi) GetMyTeam(c *gin.Context) {
+	if team, err := userCommissionService.GetMyTeam(utils.GetUserID(c)); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithData(gin.H{"team": team}, c)
+	}
+}
+
+// GetMyTeamMembers 我的直推成员
+// @Tags UserCommission
+// @Summary 分页获取当前用户邀请的成员
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query request.PageInfo true "页码, 每页大小"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /userCommission/getMyTeamMembers [get]
+func (userCommissionApi *UserCommissionApi) GetMyTeamMembers(c *gin.Context) {
+	var pageInfo request.PageInfo
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if list, total, err := userCommissionService.GetMyTeamMembers(utils.GetUserID(c), pageInfo); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithDetailed(response.PageResult{
+			List:     list,
+			Total:    total,
+			Page:     pageInfo.Page,
+			PageSize: pageInfo.PageSize,
+		}, "获取成功", c)
+	}
+}
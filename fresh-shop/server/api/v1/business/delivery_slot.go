@@ -0,0 +1,196 @@
+package business
+
+import (
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/business"
+	businessReq "fresh-shop/server/model/business/request"
+	"fresh-shop/server/model/common/request"
+	"fresh-shop/server/model/common/response"
+	"fresh-shop/server/service"
+	"fresh-shop/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type DeliverySlotApi struct {
+}
+
+var deliverySlotService = service.ServiceGroupApp.BusinessServiceGroup.DeliverySlotService
+
+// CreateDeliverySlot 创建DeliverySlot
+// @Tags DeliverySlot
+// @Summary 创建DeliverySlot
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body business.DeliverySlot true "创建DeliverySlot"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /deliverySlot/createDeliverySlot [post]
+func (deliverySlotApi *DeliverySlotApi) CreateDeliverySlot(c *gin.Context) {
+	var deliverySlot business.DeliverySlot
+	err := c.ShouldBindJSON(&deliverySlot)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := deliverySlotService.CreateDeliverySlot(deliverySlot); err != nil {
+		global.Log.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败, "+err.Error(), c)
+	} else {
+		response.OkWithMessage("创建成功", c)
+	}
+}
+
+// DeleteDeliverySlot 删除DeliverySlot
+// @Tags DeliverySlot
+// @Summary 删除DeliverySlot
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body business.DeliverySlot true "删除DeliverySlot"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"删除成功"}"
+// @Router /deliverySlot/deleteDeliverySlot [delete]
+func (deliverySlotApi *DeliverySlotApi) DeleteDeliverySlot(c *gin.Context) {
+	var deliverySlot business.DeliverySlot
+	err := c.ShouldBindJSON(&deliverySlot)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := deliverySlotService.DeleteDeliverySlot(deliverySlot); err != nil {
+		global.Log.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage("删除失败", c)
+	} else {
+		response.OkWithMessage("删除成功", c)
+	}
+}
+
+// DeleteDeliverySlotByIds 批量删除DeliverySlot
+// @Tags DeliverySlot
+// @Summary 批量删除DeliverySlot
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.IdsReq true "批量删除DeliverySlot"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"批量删除成功"}"
+// @Router /deliverySlot/deleteDeliverySlotByIds [delete]
+func (deliverySlotApi *DeliverySlotApi) DeleteDeliverySlotByIds(c *gin.Context) {
+	var IDS request.IdsReq
+	err := c.ShouldBindJSON(&IDS)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := deliverySlotService.DeleteDeliverySlotByIds(IDS); err != nil {
+		global.Log.Error("批量删除失败!", zap.Error(err))
+		response.FailWithMessage("批量删除失败", c)
+	} else {
+		response.OkWithMessage("批量删除成功", c)
+	}
+}
+
+// UpdateDeliverySlot 更新DeliverySlot
+// @Tags DeliverySlot
+// @Summary 更新DeliverySlot
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body business.DeliverySlot true "更新DeliverySlot"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"更新成功"}"
+// @Router /deliverySlot/updateDeliverySlot [put]
+func (deliverySlotApi *DeliverySlotApi) UpdateDeliverySlot(c *gin.Context) {
+	var deliverySlot business.DeliverySlot
+	err := c.ShouldBindJSON(&deliverySlot)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := deliverySlotService.UpdateDeliverySlot(deliverySlot); err != nil {
+		global.Log.Error("更新失败!", zap.Error(err))
+		response.FailWithMessage("更新失败, "+err.Error(), c)
+	} else {
+		response.OkWithMessage("更新成功", c)
+	}
+}
+
+// FindDeliverySlot 用id查询DeliverySlot
+// @Tags DeliverySlot
+// @Summary 用id查询DeliverySlot
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query business.DeliverySlot true "用id查询DeliverySlot"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"查询成功"}"
+// @Router /deliverySlot/findDeliverySlot [get]
+func (deliverySlotApi *DeliverySlotApi) FindDeliverySlot(c *gin.Context) {
+	var deliverySlot business.DeliverySlot
+	err := c.ShouldBindQuery(&deliverySlot)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if redeliverySlot, err := deliverySlotService.GetDeliverySlot(deliverySlot.ID); err != nil {
+		global.Log.Error("查询失败!", zap.Error(err))
+		response.FailWithMessage("查询失败", c)
+	} else {
+		response.OkWithData(gin.H{"redeliverySlot": redeliverySlot}, c)
+	}
+}
+
+// GetDeliverySlotList 分页获取DeliverySlot列表
+// @Tags DeliverySlot
+// @Summary 分页获取DeliverySlot列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query businessReq.DeliverySlotSearch true "分页获取DeliverySlot列表"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /deliverySlot/getDeliverySlotList [get]
+func (deliverySlotApi *DeliverySlotApi) GetDeliverySlotList(c *gin.Context) {
+	var pageInfo businessReq.DeliverySlotSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if list, total, err := deliverySlotService.GetDeliverySlotInfoList(pageInfo); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithDetailed(response.PageResult{
+			List:     list,
+			Total:    total,
+			Page:     pageInfo.Page,
+			PageSize: pageInfo.PageSize,
+		}, "获取成功", c)
+	}
+}
+
+// GetAvailableDeliverySlots 获取收货地址可预约的配送时段
+// @Tags DeliverySlot
+// @Summary 获取收货地址可预约的配送时段
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query businessReq.AvailableDeliverySlotReq true "获取收货地址可预约的配送时段"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /deliverySlot/getAvailableDeliverySlots [get]
+func (deliverySlotApi *DeliverySlotApi) GetAvailableDeliverySlots(c *gin.Context) {
+	var req businessReq.AvailableDeliverySlotReq
+	err := c.ShouldBindQuery(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if req.AddressId == 0 {
+		response.FailWithMessage("收货地址不能为空", c)
+		return
+	}
+	userId := utils.GetUserID(c)
+	if list, err := deliverySlotService.GetAvailableDeliverySlots(userId, req.AddressId); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+	} else {
+		response.OkWithData(list, c)
+	}
+}
@@ -0,0 +1,190 @@
+package business
+
+import (
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/business"
+	businessReq "fresh-shop/server/model/business/request"
+	"fresh-shop/server/model/common/request"
+	"fresh-shop/server/model/common/response"
+	"fresh-shop/server/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type DeliveryZoneApi struct {
+}
+
+var deliveryZoneService = service.ServiceGroupApp.BusinessServiceGroup.DeliveryZoneService
+
+// CreateDeliveryZone 创建DeliveryZone
+// @Tags DeliveryZone
+// @Summary 创建DeliveryZone
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body business.DeliveryZone true "创建DeliveryZone"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /deliveryZone/createDeliveryZone [post]
+func (deliveryZoneApi *DeliveryZoneApi) CreateDeliveryZone(c *gin.Context) {
+	var deliveryZone business.DeliveryZone
+	err := c.ShouldBindJSON(&deliveryZone)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := deliveryZoneService.CreateDeliveryZone(deliveryZone); err != nil {
+		global.Log.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败, "+err.Error(), c)
+	} else {
+		response.OkWithMessage("创建成功", c)
+	}
+}
+
+// DeleteDeliveryZone 删除DeliveryZone
+// @Tags DeliveryZone
+// @Summary 删除DeliveryZone
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body business.DeliveryZone true "删除DeliveryZone"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"删除成功"}"
+// @Router /deliveryZone/deleteDeliveryZone [delete]
+func (deliveryZoneApi *DeliveryZoneApi) DeleteDeliveryZone(c *gin.Context) {
+	var deliveryZone business.DeliveryZone
+	err := c.ShouldBindJSON(&deliveryZone)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := deliveryZoneService.DeleteDeliveryZone(deliveryZone); err != nil {
+		global.Log.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage("删除失败", c)
+	} else {
+		response.OkWithMessage("删除成功", c)
+	}
+}
+
+// DeleteDeliveryZoneByIds 批量删除DeliveryZone
+// @Tags DeliveryZone
+// @Summary 批量删除DeliveryZone
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.IdsReq true "批量删除DeliveryZone"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"批量删除成功"}"
+// @Router /deliveryZone/deleteDeliveryZoneByIds [delete]
+func (deliveryZoneApi *DeliveryZoneApi) DeleteDeliveryZoneByIds(c *gin.Context) {
+	var IDS request.IdsReq
+	err := c.ShouldBindJSON(&IDS)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := deliveryZoneService.DeleteDeliveryZoneByIds(IDS); err != nil {
+		global.Log.Error("批量删除失败!", zap.Error(err))
+		response.FailWithMessage("批量删除失败", c)
+	} else {
+		response.OkWithMessage("批量删除成功", c)
+	}
+}
+
+// UpdateDeliveryZone 更新DeliveryZone
+// @Tags DeliveryZone
+// @Summary 更新DeliveryZone
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body business.DeliveryZone true "更新DeliveryZone"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"更新成功"}"
+// @Router /deliveryZone/updateDeliveryZone [put]
+func (deliveryZoneApi *DeliveryZoneApi) UpdateDeliveryZone(c *gin.Context) {
+	var deliveryZone business.DeliveryZone
+	err := c.ShouldBindJSON(&deliveryZone)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := deliveryZoneService.UpdateDeliveryZone(deliveryZone); err != nil {
+		global.Log.Error("更新失败!", zap.Error(err))
+		response.FailWithMessage("更新失败, "+err.Error(), c)
+	} else {
+		response.OkWithMessage("更新成功", c)
+	}
+}
+
+// FindDeliveryZone 用id查询DeliveryZone
+// @Tags DeliveryZone
+// @Summary 用id查询DeliveryZone
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query business.DeliveryZone true "用id查询DeliveryZone"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"查询成功"}"
+// @Router /deliveryZone/findDeliveryZone [get]
+func (deliveryZoneApi *DeliveryZoneApi) FindDeliveryZone(c *gin.Context) {
+	var deliveryZone business.DeliveryZone
+	err := c.ShouldBindQuery(&deliveryZone)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if redeliveryZone, err := deliveryZoneService.GetDeliveryZone(deliveryZone.ID); err != nil {
+		global.Log.Error("查询失败!", zap.Error(err))
+		response.FailWithMessage("查询失败", c)
+	} else {
+		response.OkWithData(gin.H{"redeliveryZone": redeliveryZone}, c)
+	}
+}
+
+// GetDeliveryZoneList 分页获取DeliveryZone列表
+// @Tags DeliveryZone
+// @Summary 分页获取DeliveryZone列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query businessReq.DeliveryZoneSearch true "分页获取DeliveryZone列表"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /deliveryZone/getDeliveryZoneList [get]
+func (deliveryZoneApi *DeliveryZoneApi) GetDeliveryZoneList(c *gin.Context) {
+	var pageInfo businessReq.DeliveryZoneSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if list, total, err := deliveryZoneService.GetDeliveryZoneInfoList(pageInfo); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithDetailed(response.PageResult{
+			List:     list,
+			Total:    total,
+			Page:     pageInfo.Page,
+			PageSize: pageInfo.PageSize,
+		}, "获取成功", c)
+	}
+}
+
+// FindDeliveryZoneByLocation 根据坐标查询所在配送区域
+// @Tags DeliveryZone
+// @Summary 根据坐标查询所在配送区域
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query businessReq.DeliveryZoneLocation true "根据坐标查询所在配送区域"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"查询成功"}"
+// @Router /deliveryZone/findDeliveryZoneByLocation [get]
+func (deliveryZoneApi *DeliveryZoneApi) FindDeliveryZoneByLocation(c *gin.Context) {
+	var location businessReq.DeliveryZoneLocation
+	err := c.ShouldBindQuery(&location)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if zone, deliverable, err := deliveryZoneService.FindDeliveryZoneByLocation(location.Longitude, location.Latitude); err != nil {
+		global.Log.Error("查询失败!", zap.Error(err))
+		response.FailWithMessage("查询失败", c)
+	} else {
+		response.OkWithData(gin.H{"zone": zone, "deliverable": deliverable}, c)
+	}
+}
@@ -3,4 +3,6 @@ package business
 type ApiGroup struct {
 	BannerApi
 	UserDeliveryApi
+	DeliveryZoneApi
+	DeliverySlotApi
 }
@@ -209,3 +209,35 @@ func (userDeliveryApi *UserDeliveryApi) GetUserDeliveryAllList(c *gin.Context) {
 		}, "获取成功", c)
 	}
 }
+
+// SetRiderAccount 设置配送员登录账号
+// @Tags UserDelivery
+// @Summary 设置配送员登录账号
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body businessReq.RiderAccountReq true "配送员id, 登录密码"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"设置成功"}"
+// @Router /userDelivery/setRiderAccount [put]
+func (userDeliveryApi *UserDeliveryApi) SetRiderAccount(c *gin.Context) {
+	var req businessReq.RiderAccountReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if req.ID == 0 {
+		response.FailWithMessage("参数错误", c)
+		return
+	}
+	if len(req.Password) < 6 {
+		response.FailWithMessage("密码长度不能少于6位", c)
+		return
+	}
+	if err := userDeliveryService.SetRiderAccount(req); err != nil {
+		global.Log.Error("设置失败!", zap.Error(err))
+		response.FailWithMessage("设置失败, "+err.Error(), c)
+	} else {
+		response.OkWithMessage("设置成功", c)
+	}
+}
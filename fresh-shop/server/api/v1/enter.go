@@ -4,6 +4,7 @@ import (
 	"fresh-shop/server/api/v1/account"
 	"fresh-shop/server/api/v1/business"
 	"fresh-shop/server/api/v1/file"
+	"fresh-shop/server/api/v1/payment"
 	"fresh-shop/server/api/v1/shop"
 	"fresh-shop/server/api/v1/system"
 	"fresh-shop/server/api/v1/wechat"
@@ -16,6 +17,7 @@ type ApiGroup struct {
 	BusinessApiGroup business.ApiGroup
 	ShopApiGroup     shop.ApiGroup
 	WechatApiGroup   wechat.ApiGroup
+	PaymentApiGroup  payment.ApiGroup
 }
 
 var ApiGroupApp = new(ApiGroup)
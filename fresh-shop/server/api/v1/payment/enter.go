@@ -0,0 +1,11 @@
+package payment
+
+import "fresh-shop/server/service"
+
+type ApiGroup struct {
+	PaymentApi
+}
+
+var (
+	orderService = service.ServiceGroupApp.ShopServiceGroup.OrderService
+)
@@ -0,0 +1,100 @@
+package payment
+
+import (
+	"bytes"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/common/response"
+	"fresh-shop/server/service/payment"
+	"fresh-shop/server/utils"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+)
+
+type PaymentApi struct {
+}
+
+// SandboxPayReq 沙箱模拟付款请求
+type SandboxPayReq struct {
+	OrderSn string `json:"orderSn" form:"orderSn"`
+}
+
+// PayNotify 支付结果回调，channel 为 wechat、alipay、sandbox，通知原文写入 payment_notify_log
+func (p *PaymentApi) PayNotify(c *gin.Context) {
+	channel := c.Param("channel")
+	global.SugarLog.Infof("支付回调 开始 channel:%s \n", channel)
+	provider, err := payment.GetChannel(channel)
+	if err != nil {
+		c.String(http.StatusNotFound, err.Error())
+		return
+	}
+	raw, err := readNotifyBody(c)
+	if err != nil {
+		provider.NotifyAck(c.Writer, err)
+		return
+	}
+	result, err := provider.VerifyPayNotify(c.Request)
+	if err != nil {
+		global.SugarLog.Errorf("支付回调验证失败! channel:%s, err: %v \n", channel, err)
+	} else {
+		global.SugarLog.Infof("支付回调 验证通过开始执行业务逻辑 channel:%s, orderSn:%s \n", channel, result.OrderSn)
+	}
+	if err = orderService.PayNotify(channel, c.Request.Header, raw, result, err); err != nil {
+		global.SugarLog.Errorf("支付回调失败! channel:%s, err: %v \n", channel, err)
+	}
+	provider.NotifyAck(c.Writer, err)
+}
+
+// RefundNotify 退款结果回调，channel 为 wechat、sandbox，支付宝退款同步返回结果不需要回调
+func (p *PaymentApi) RefundNotify(c *gin.Context) {
+	channel := c.Param("channel")
+	global.SugarLog.Infof("退款回调 开始 channel:%s \n", channel)
+	provider, err := payment.GetChannel(channel)
+	if err != nil {
+		c.String(http.StatusNotFound, err.Error())
+		return
+	}
+	raw, err := readNotifyBody(c)
+	if err != nil {
+		provider.NotifyAck(c.Writer, err)
+		return
+	}
+	result, err := provider.VerifyRefundNotify(c.Request)
+	if err != nil {
+		global.SugarLog.Errorf("退款回调验证失败! channel:%s, err: %v \n", channel, err)
+	} else {
+		global.SugarLog.Infof("退款回调 验证通过开始执行业务逻辑 channel:%s, orderSn:%s \n", channel, result.OrderSn)
+	}
+	if err = orderService.RefundNotify(channel, c.Request.Header, raw, result, err); err != nil {
+		global.SugarLog.Errorf("退款回调失败! channel:%s, err: %v \n", channel, err)
+	}
+	provider.NotifyAck(c.Writer, err)
+}
+
+// readNotifyBody 读取通知原文后写回请求体，供支付渠道验签解析
+func readNotifyBody(c *gin.Context) ([]byte, error) {
+	raw, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+	return raw, nil
+}
+
+// SandboxPay 沙箱环境模拟用户完成付款，付款后异步回调 PayNotify
+func (p *PaymentApi) SandboxPay(c *gin.Context) {
+	var req SandboxPayReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if req.OrderSn == "" {
+		response.FailWithMessage("参数错误", c)
+		return
+	}
+	if err := orderService.SandboxPay(req.OrderSn, utils.GetUserID(c)); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithMessage("付款成功，等待支付回调", c)
+}
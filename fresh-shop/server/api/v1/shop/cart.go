@@ -208,3 +208,28 @@ func (cartApi *CartApi) GetCartList(c *gin.Context) {
 		}, "获取成功", c)
 	}
 }
+
+// GetPostageQuote 运费试算
+// @Tags Cart
+// @Summary 根据购物车已选中商品和收货地址试算运费
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query shopReq.PostageQuoteReq true "运费试算"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /cart/getPostageQuote [get]
+func (cartApi *CartApi) GetPostageQuote(c *gin.Context) {
+	var req shopReq.PostageQuoteReq
+	err := c.ShouldBindQuery(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	userId := utils.GetUserID(c)
+	if resp, err := cartService.GetPostageQuote(userId, req); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+	} else {
+		response.OkWithData(resp, c)
+	}
+}
@@ -0,0 +1,284 @@
+package shop
+
+import (
+	"fmt"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/common/request"
+	"fresh-shop/server/model/common/response"
+	"fresh-shop/server/model/shop"
+	shopReq "fresh-shop/server/model/shop/request"
+	"fresh-shop/server/service"
+	"fresh-shop/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type CouponApi struct {
+}
+
+var couponService = service.ServiceGroupApp.ShopServiceGroup.CouponService
+
+// CreateCoupon 创建Coupon
+// @Tags Coupon
+// @Summary 创建Coupon
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shop.Coupon true "创建Coupon"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /coupon/createCoupon [post]
+func (couponApi *CouponApi) CreateCoupon(c *gin.Context) {
+	var coupon shop.Coupon
+	err := c.ShouldBindJSON(&coupon)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := couponService.CreateCoupon(coupon); err != nil {
+		global.Log.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败, "+err.Error(), c)
+	} else {
+		response.OkWithMessage("创建成功", c)
+	}
+}
+
+// DeleteCoupon 删除Coupon
+// @Tags Coupon
+// @Summary 删除Coupon
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shop.Coupon true "删除Coupon"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"删除成功"}"
+// @Router /coupon/deleteCoupon [delete]
+func (couponApi *CouponApi) DeleteCoupon(c *gin.Context) {
+	var coupon shop.Coupon
+	err := c.ShouldBindJSON(&coupon)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := couponService.DeleteCoupon(coupon); err != nil {
+		global.Log.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage("删除失败", c)
+	} else {
+		response.OkWithMessage("删除成功", c)
+	}
+}
+
+// DeleteCouponByIds 批量删除Coupon
+// @Tags Coupon
+// @Summary 批量删除Coupon
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.IdsReq true "批量删除Coupon"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"批量删除成功"}"
+// @Router /coupon/deleteCouponByIds [delete]
+func (couponApi *CouponApi) DeleteCouponByIds(c *gin.Context) {
+	var IDS request.IdsReq
+	err := c.ShouldBindJSON(&IDS)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := couponService.DeleteCouponByIds(IDS); err != nil {
+		global.Log.Error("批量删除失败!", zap.Error(err))
+		response.FailWithMessage("批量删除失败", c)
+	} else {
+		response.OkWithMessage("批量删除成功", c)
+	}
+}
+
+// UpdateCoupon 更新Coupon
+// @Tags Coupon
+// @Summary 更新Coupon
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shop.Coupon true "更新Coupon"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"更新成功"}"
+// @Router /coupon/updateCoupon [put]
+func (couponApi *CouponApi) UpdateCoupon(c *gin.Context) {
+	var coupon shop.Coupon
+	err := c.ShouldBindJSON(&coupon)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := couponService.UpdateCoupon(coupon); err != nil {
+		global.Log.Error("更新失败!", zap.Error(err))
+		response.FailWithMessage("更新失败, "+err.Error(), c)
+	} else {
+		response.OkWithMessage("更新成功", c)
+	}
+}
+
+// FindCoupon 用id查询Coupon
+// @Tags Coupon
+// @Summary 用id查询Coupon
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query shop.Coupon true "用id查询Coupon"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"查询成功"}"
+// @Router /coupon/findCoupon [get]
+func (couponApi *CouponApi) FindCoupon(c *gin.Context) {
+	var coupon shop.Coupon
+	err := c.ShouldBindQuery(&coupon)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if recoupon, err := couponService.GetCoupon(coupon.ID); err != nil {
+		global.Log.Error("查询失败!", zap.Error(err))
+		response.FailWithMessage("查询失败", c)
+	} else {
+		response.OkWithData(gin.H{"recoupon": recoupon}, c)
+	}
+}
+
+// GetCouponList 分页获取Coupon列表
+// @Tags Coupon
+// @Summary 分页获取Coupon列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query shopReq.CouponSearch true "分页获取Coupon列表"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /coupon/getCouponList [get]
+func (couponApi *CouponApi) GetCouponList(c *gin.Context) {
+	var pageInfo shopReq.CouponSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if list, total, err := couponService.GetCouponInfoList(pageInfo); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithDetailed(response.PageResult{
+			List:     list,
+			Total:    total,
+			Page:     pageInfo.Page,
+			PageSize: pageInfo.PageSize,
+		}, "获取成功", c)
+	}
+}
+
+// IssueCoupon 后台发放优惠券
+// @Tags Coupon
+// @Summary 后台给指定用户发放优惠券
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shopReq.IssueCouponReq true "优惠券id, 用户id"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"发放成功"}"
+// @Router /coupon/issueCoupon [post]
+func (couponApi *CouponApi) IssueCoupon(c *gin.Context) {
+	var req shopReq.IssueCouponReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if req.CouponId == 0 || len(req.UserIds) == 0 {
+		response.FailWithMessage("参数错误", c)
+		return
+	}
+	success, err := couponService.IssueCoupon(req)
+	if err != nil {
+		global.Log.Error("部分优惠券发放失败!", zap.Error(err))
+		response.FailWithDetailed(gin.H{"success": success}, fmt.Sprintf("成功发放 %d 张, 失败: %s", success, err.Error()), c)
+		return
+	}
+	response.OkWithDetailed(gin.H{"success": success}, "发放成功", c)
+}
+
+// GetClaimableCoupons 领券中心 获取可领取的优惠券
+// @Tags Coupon
+// @Summary 领券中心 获取可领取的优惠券
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /coupon/getClaimableCoupons [get]
+func (couponApi *CouponApi) GetClaimableCoupons(c *gin.Context) {
+	if list, err := couponService.GetClaimableCoupons(utils.GetUserID(c)); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithData(list, c)
+	}
+}
+
+// ClaimCoupon 领券中心 领取优惠券
+// @Tags Coupon
+// @Summary 领券中心 领取优惠券
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shopReq.ClaimCouponReq true "优惠券id"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"领取成功"}"
+// @Router /coupon/claimCoupon [post]
+func (couponApi *CouponApi) ClaimCoupon(c *gin.Context) {
+	var req shopReq.ClaimCouponReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if userCoupon, err := couponService.ClaimCoupon(utils.GetUserID(c), req.CouponId); err != nil {
+		global.Log.Error("领取失败!", zap.Error(err))
+		response.FailWithMessage("领取失败, "+err.Error(), c)
+	} else {
+		response.OkWithDetailed(userCoupon, "领取成功", c)
+	}
+}
+
+// GetUserCouponList 我的优惠券
+// @Tags Coupon
+// @Summary 分页获取当前用户的优惠券
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query shopReq.UserCouponSearch true "状态 0未使用 1已使用 2已过期"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /coupon/getUserCouponList [get]
+func (couponApi *CouponApi) GetUserCouponList(c *gin.Context) {
+	var pageInfo shopReq.UserCouponSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if list, total, err := couponService.GetUserCouponList(utils.GetUserID(c), pageInfo); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithDetailed(response.PageResult{
+			List:     list,
+			Total:    total,
+			Page:     pageInfo.Page,
+			PageSize: pageInfo.PageSize,
+		}, "获取成功", c)
+	}
+}
+
+// GetUsableCoupons 获取购物车已选中商品可使用的优惠券
+// @Tags Coupon
+// @Summary 获取购物车已选中商品可使用的优惠券
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /coupon/getUsableCoupons [get]
+func (couponApi *CouponApi) GetUsableCoupons(c *gin.Context) {
+	if list, err := couponService.GetUsableCoupons(utils.GetUserID(c)); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithData(list, c)
+	}
+}
@@ -13,4 +13,10 @@ type ApiGroup struct {
 	FavoritesApi
 	CartApi
 	UserAddressApi
+	RiderApi
+	CouponApi
+	FlashSaleApi
+	GroupBuyApi
+	PaymentReconcileApi
+	PaymentNotifyLogApi
 }
@@ -0,0 +1,230 @@
+package shop
+
+import (
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/common/request"
+	"fresh-shop/server/model/common/response"
+	"fresh-shop/server/model/shop"
+	shopReq "fresh-shop/server/model/shop/request"
+	"fresh-shop/server/service"
+	"fresh-shop/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type FlashSaleApi struct {
+}
+
+var flashSaleService = service.ServiceGroupApp.ShopServiceGroup.FlashSaleService
+
+// CreateFlashSale 创建FlashSale
+// @Tags FlashSale
+// @Summary 创建FlashSale
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shop.FlashSale true "创建FlashSale"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /flashSale/createFlashSale [post]
+func (flashSaleApi *FlashSaleApi) CreateFlashSale(c *gin.Context) {
+	var flashSale shop.FlashSale
+	err := c.ShouldBindJSON(&flashSale)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := flashSaleService.CreateFlashSale(flashSale); err != nil {
+		global.Log.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败, "+err.Error(), c)
+	} else {
+		response.OkWithMessage("创建成功", c)
+	}
+}
+
+// DeleteFlashSale 删除FlashSale
+// @Tags FlashSale
+// @Summary 删除FlashSale
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shop.FlashSale true "删除FlashSale"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"删除成功"}"
+// @Router /flashSale/deleteFlashSale [delete]
+func (flashSaleApi *FlashSaleApi) DeleteFlashSale(c *gin.Context) {
+	var flashSale shop.FlashSale
+	err := c.ShouldBindJSON(&flashSale)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := flashSaleService.DeleteFlashSale(flashSale); err != nil {
+		global.Log.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage("删除失败", c)
+	} else {
+		response.OkWithMessage("删除成功", c)
+	}
+}
+
+// DeleteFlashSaleByIds 批量删除FlashSale
+// @Tags FlashSale
+// @Summary 批量删除FlashSale
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.IdsReq true "批量删除FlashSale"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"批量删除成功"}"
+// @Router /flashSale/deleteFlashSaleByIds [delete]
+func (flashSaleApi *FlashSaleApi) DeleteFlashSaleByIds(c *gin.Context) {
+	var IDS request.IdsReq
+	err := c.ShouldBindJSON(&IDS)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := flashSaleService.DeleteFlashSaleByIds(IDS); err != nil {
+		global.Log.Error("批量删除失败!", zap.Error(err))
+		response.FailWithMessage("批量删除失败", c)
+	} else {
+		response.OkWithMessage("批量删除成功", c)
+	}
+}
+
+// UpdateFlashSale 更新FlashSale
+// @Tags FlashSale
+// @Summary 更新FlashSale
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shop.FlashSale true "更新FlashSale"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"更新成功"}"
+// @Router /flashSale/updateFlashSale [put]
+func (flashSaleApi *FlashSaleApi) UpdateFlashSale(c *gin.Context) {
+	var flashSale shop.FlashSale
+	err := c.ShouldBindJSON(&flashSale)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := flashSaleService.UpdateFlashSale(flashSale); err != nil {
+		global.Log.Error("更新失败!", zap.Error(err))
+		response.FailWithMessage("更新失败, "+err.Error(), c)
+	} else {
+		response.OkWithMessage("更新成功", c)
+	}
+}
+
+// FindFlashSale 用id查询FlashSale
+// @Tags FlashSale
+// @Summary 用id查询FlashSale
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query shop.FlashSale true "用id查询FlashSale"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"查询成功"}"
+// @Router /flashSale/findFlashSale [get]
+func (flashSaleApi *FlashSaleApi) FindFlashSale(c *gin.Context) {
+	var flashSale shop.FlashSale
+	err := c.ShouldBindQuery(&flashSale)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if reflashSale, err := flashSaleService.GetFlashSale(flashSale.ID); err != nil {
+		global.Log.Error("查询失败!", zap.Error(err))
+		response.FailWithMessage("查询失败", c)
+	} else {
+		response.OkWithData(gin.H{"reflashSale": reflashSale}, c)
+	}
+}
+
+// GetFlashSaleList 分页获取FlashSale列表
+// @Tags FlashSale
+// @Summary 分页获取FlashSale列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query shopReq.FlashSaleSearch true "分页获取FlashSale列表"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /flashSale/getFlashSaleList [get]
+func (flashSaleApi *FlashSaleApi) GetFlashSaleList(c *gin.Context) {
+	var pageInfo shopReq.FlashSaleSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if list, total, err := flashSaleService.GetFlashSaleInfoList(pageInfo); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithDetailed(response.PageResult{
+			List:     list,
+			Total:    total,
+			Page:     pageInfo.Page,
+			PageSize: pageInfo.PageSize,
+		}, "获取成功", c)
+	}
+}
+
+// GetFlashSaleCountdown 获取秒杀活动倒计时
+// @Tags FlashSale
+// @Summary 获取进行中和即将开始的秒杀活动、秒杀商品及倒计时
+// @accept application/json
+// @Produce application/json
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /flashSale/getFlashSaleCountdown [get]
+func (flashSaleApi *FlashSaleApi) GetFlashSaleCountdown(c *gin.Context) {
+	if resp, err := flashSaleService.GetFlashSaleCountdown(); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithData(resp, c)
+	}
+}
+
+// CreateFlashOrder 秒杀下单
+// @Tags FlashSale
+// @Summary 秒杀下单，返回排队凭证，通过 getFlashOrderResult 查询下单结果
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shopReq.FlashOrderReq true "秒杀商品id, 数量, 收货地址"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"排队中"}"
+// @Router /flashSale/createFlashOrder [post]
+func (flashSaleApi *FlashSaleApi) CreateFlashOrder(c *gin.Context) {
+	var req shopReq.FlashOrderReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if token, err := flashSaleService.CreateFlashOrder(utils.GetUserID(c), req); err != nil {
+		global.Log.Error("秒杀下单失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+	} else {
+		response.OkWithDetailed(gin.H{"token": token}, "排队中", c)
+	}
+}
+
+// GetFlashOrderResult 查询秒杀下单结果
+// @Tags FlashSale
+// @Summary 查询秒杀排队下单结果
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param token query string true "排队凭证"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /flashSale/getFlashOrderResult [get]
+func (flashSaleApi *FlashSaleApi) GetFlashOrderResult(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		response.FailWithMessage("参数错误", c)
+		return
+	}
+	if result, err := flashSaleService.GetFlashOrderResult(utils.GetUserID(c), token); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+	} else {
+		response.OkWithData(result, c)
+	}
+}
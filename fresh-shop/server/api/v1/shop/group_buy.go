@@ -0,0 +1,259 @@
+package shop
+
+import (
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/common/request"
+	"fresh-shop/server/model/common/response"
+	"fresh-shop/server/model/shop"
+	shopReq "fresh-shop/server/model/shop/request"
+	"fresh-shop/server/service"
+	"fresh-shop/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type GroupBuyApi struct {
+}
+
+var groupBuyService = service.ServiceGroupApp.ShopServiceGroup.GroupBuyService
+
+// CreateGroupBuy 创建GroupBuy
+// @Tags GroupBuy
+// @Summary 创建GroupBuy
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shop.GroupBuy true "创建GroupBuy"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /groupBuy/createGroupBuy [post]
+func (groupBuyApi *GroupBuyApi) CreateGroupBuy(c *gin.Context) {
+	var groupBuy shop.GroupBuy
+	err := c.ShouldBindJSON(&groupBuy)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := groupBuyService.CreateGroupBuy(groupBuy); err != nil {
+		global.Log.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败, "+err.Error(), c)
+	} else {
+		response.OkWithMessage("创建成功", c)
+	}
+}
+
+// DeleteGroupBuy 删除GroupBuy
+// @Tags GroupBuy
+// @Summary 删除GroupBuy
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shop.GroupBuy true "删除GroupBuy"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"删除成功"}"
+// @Router /groupBuy/deleteGroupBuy [delete]
+func (groupBuyApi *GroupBuyApi) DeleteGroupBuy(c *gin.Context) {
+	var groupBuy shop.GroupBuy
+	err := c.ShouldBindJSON(&groupBuy)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := groupBuyService.DeleteGroupBuy(groupBuy); err != nil {
+		global.Log.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage("删除失败", c)
+	} else {
+		response.OkWithMessage("删除成功", c)
+	}
+}
+
+// DeleteGroupBuyByIds 批量删除GroupBuy
+// @Tags GroupBuy
+// @Summary 批量删除GroupBuy
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.IdsReq true "批量删除GroupBuy"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"批量删除成功"}"
+// @Router /groupBuy/deleteGroupBuyByIds [delete]
+func (groupBuyApi *GroupBuyApi) DeleteGroupBuyByIds(c *gin.Context) {
+	var IDS request.IdsReq
+	err := c.ShouldBindJSON(&IDS)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := groupBuyService.DeleteGroupBuyByIds(IDS); err != nil {
+		global.Log.Error("批量删除失败!", zap.Error(err))
+		response.FailWithMessage("批量删除失败", c)
+	} else {
+		response.OkWithMessage("批量删除成功", c)
+	}
+}
+
+// UpdateGroupBuy 更新GroupBuy
+// @Tags GroupBuy
+// @Summary 更新GroupBuy
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shop.GroupBuy true "更新GroupBuy"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"更新成功"}"
+// @Router /groupBuy/updateGroupBuy [put]
+func (groupBuyApi *GroupBuyApi) UpdateGroupBuy(c *gin.Context) {
+	var groupBuy shop.GroupBuy
+	err := c.ShouldBindJSON(&groupBuy)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := groupBuyService.UpdateGroupBuy(groupBuy); err != nil {
+		global.Log.Error("更新失败!", zap.Error(err))
+		response.FailWithMessage("更新失败, "+err.Error(), c)
+	} else {
+		response.OkWithMessage("更新成功", c)
+	}
+}
+
+// FindGroupBuy 用id查询GroupBuy
+// @Tags GroupBuy
+// @Summary 用id查询GroupBuy
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query shop.GroupBuy true "用id查询GroupBuy"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"查询成功"}"
+// @Router /groupBuy/findGroupBuy [get]
+func (groupBuyApi *GroupBuyApi) FindGroupBuy(c *gin.Context) {
+	var groupBuy shop.GroupBuy
+	err := c.ShouldBindQuery(&groupBuy)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if regroupBuy, err := groupBuyService.GetGroupBuy(groupBuy.ID); err != nil {
+		global.Log.Error("查询失败!", zap.Error(err))
+		response.FailWithMessage("查询失败", c)
+	} else {
+		response.OkWithData(gin.H{"regroupBuy": regroupBuy}, c)
+	}
+}
+
+// GetGroupBuyList 分页获取GroupBuy列表
+// @Tags GroupBuy
+// @Summary 分页获取GroupBuy列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query shopReq.GroupBuySearch true "分页获取GroupBuy列表"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /groupBuy/getGroupBuyList [get]
+func (groupBuyApi *GroupBuyApi) GetGroupBuyList(c *gin.Context) {
+	var pageInfo shopReq.GroupBuySearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if list, total, err := groupBuyService.GetGroupBuyInfoList(pageInfo); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithDetailed(response.PageResult{
+			List:     list,
+			Total:    total,
+			Page:     pageInfo.Page,
+			PageSize: pageInfo.PageSize,
+		}, "获取成功", c)
+	}
+}
+
+// GetGroupBuyTeamList 分页获取拼团团队
+// @Tags GroupBuy
+// @Summary 分页获取拼团团队及团员订单
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query shopReq.GroupBuyTeamSearch true "拼团活动id, 状态"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /groupBuy/getGroupBuyTeamList [get]
+func (groupBuyApi *GroupBuyApi) GetGroupBuyTeamList(c *gin.Context) {
+	var pageInfo shopReq.GroupBuyTeamSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if list, total, err := groupBuyService.GetGroupBuyTeamInfoList(pageInfo); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithDetailed(response.PageResult{
+			List:     list,
+			Total:    total,
+			Page:     pageInfo.Page,
+			PageSize: pageInfo.PageSize,
+		}, "获取成功", c)
+	}
+}
+
+// GetActiveGroupBuys 获取进行中的拼团活动
+// @Tags GroupBuy
+// @Summary 获取进行中的拼团活动
+// @accept application/json
+// @Produce application/json
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /groupBuy/getActiveGroupBuys [get]
+func (groupBuyApi *GroupBuyApi) GetActiveGroupBuys(c *gin.Context) {
+	if list, err := groupBuyService.GetActiveGroupBuys(); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithData(list, c)
+	}
+}
+
+// GetJoinableTeams 获取可参加的团
+// @Tags GroupBuy
+// @Summary 获取拼团活动可参加的团
+// @accept application/json
+// @Produce application/json
+// @Param groupBuyId query int true "拼团活动id"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /groupBuy/getJoinableTeams [get]
+func (groupBuyApi *GroupBuyApi) GetJoinableTeams(c *gin.Context) {
+	var req shopReq.GroupBuyTeamSearch
+	err := c.ShouldBindQuery(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if list, err := groupBuyService.GetJoinableTeams(req.GroupBuyId); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithData(list, c)
+	}
+}
+
+// CreateGroupOrder 拼团下单
+// @Tags GroupBuy
+// @Summary 开团或参团下单，返回微信支付所需要的参数
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shopReq.GroupOrderReq true "拼团活动id, 参加的团id(开团为0), 收货地址"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"下单成功"}"
+// @Router /groupBuy/createGroupOrder [post]
+func (groupBuyApi *GroupBuyApi) CreateGroupOrder(c *gin.Context) {
+	var req shopReq.GroupOrderReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if resp, err := groupBuyService.CreateGroupOrder(req, utils.GetUserInfo(c), c.ClientIP()); err != nil {
+		global.Log.Error("拼团下单失败!", zap.Error(err))
+		response.FailWithMessage("下单失败, "+err.Error(), c)
+	} else {
+		response.OkWithDetailed(resp, "下单成功", c)
+	}
+}
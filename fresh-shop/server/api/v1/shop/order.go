@@ -7,6 +7,7 @@ import (
 	"fresh-shop/server/model/shop"
 	shopReq "fresh-shop/server/model/shop/request"
 	"fresh-shop/server/service"
+	shopService "fresh-shop/server/service/shop"
 	"fresh-shop/server/utils"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -79,6 +80,67 @@ func (orderApi *OrderApi) OrderPay(c *gin.Context) {
 	}
 }
 
+// BalancePay 使用余额支付 Order
+// @Tags Order
+// @Summary 使用余额支付 Order，需要校验安全密码
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shopReq.BalancePayReq true "订单ID, 安全密码"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"支付成功"}"
+// @Router /order/balancePay [post]
+func (orderApi *OrderApi) BalancePay(c *gin.Context) {
+	var req shopReq.BalancePayReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if req.OrderId == 0 {
+		response.FailWithMessage("订单ID不能为空", c)
+		return
+	}
+	if order, err := orderService.BalancePay(req, utils.GetUserID(c)); err != nil {
+		global.Log.Error("余额支付失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+	} else {
+		response.OkWithDetailed(order, "支付成功", c)
+	}
+}
+
+// SyncOrderPayment 主动查询订单支付结果
+// @Tags Order
+// @Summary 向支付渠道查询待支付订单的支付结果，已支付时按支付回调处理
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shop.Order true "订单ID"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"查询成功"}"
+// @Router /order/syncPayment [post]
+func (orderApi *OrderApi) SyncOrderPayment(c *gin.Context) {
+	var order shop.Order
+	err := c.ShouldBindJSON(&order)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if order.ID == 0 {
+		response.FailWithMessage("订单ID不能为空", c)
+		return
+	}
+	paid, err := orderService.SyncOrderPayment(order.ID)
+	if err != nil {
+		global.Log.Error("查询支付结果失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if paid {
+		response.OkWithDetailed(gin.H{"paid": paid}, "订单已支付", c)
+	} else {
+		response.OkWithDetailed(gin.H{"paid": paid}, "订单未支付", c)
+	}
+}
+
 // DeleteOrder 删除Order
 // @Tags Order
 // @Summary 删除Order
@@ -119,7 +181,7 @@ func (orderApi *OrderApi) CancelOrder(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	if err := orderService.CancelOrder(order); err != nil {
+	if err := orderService.CancelOrder(order, shopService.NewOrderOperator(utils.GetUserInfo(c))); err != nil {
 		global.Log.Error("取消失败!", zap.Error(err))
 		response.FailWithMessage(err.Error(), c)
 	} else {
@@ -246,6 +308,39 @@ func (orderApi *OrderApi) OrderStatus(c *gin.Context) {
 	}
 }
 
+// GetOrderTimeline 获取订单时间线
+// @Tags Order
+// @Summary 获取订单状态流转记录
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query shop.Order true "获取订单时间线"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"查询成功"}"
+// @Router /order/getOrderTimeline [get]
+func (orderApi *OrderApi) GetOrderTimeline(c *gin.Context) {
+	var order shop.Order
+	err := c.ShouldBindQuery(&order)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if order.ID == 0 {
+		response.FailWithMessage("订单ID不能为空", c)
+		return
+	}
+	// 普通用户只能查看自己的订单
+	var userId uint
+	if op := shopService.NewOrderOperator(utils.GetUserInfo(c)); op.Source == shop.OrderSourceUser {
+		userId = op.Id
+	}
+	if list, err := orderService.GetOrderTimeline(order.ID, userId); err != nil {
+		global.Log.Error("查询失败!", zap.Error(err))
+		response.FailWithMessage("查询失败", c)
+	} else {
+		response.OkWithData(list, c)
+	}
+}
+
 // GetOrderList 分页获取Order列表
 // @Tags Order
 // @Summary 分页获取Order列表
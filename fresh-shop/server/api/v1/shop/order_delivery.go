@@ -7,6 +7,8 @@ import (
 	"fresh-shop/server/model/shop"
 	shopReq "fresh-shop/server/model/shop/request"
 	"fresh-shop/server/service"
+	shopService "fresh-shop/server/service/shop"
+	"fresh-shop/server/utils"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -37,7 +39,7 @@ func (orderDeliveryApi *OrderDeliveryApi) CreateOrderDelivery(c *gin.Context) {
 		global.SugarLog.Errorf("发货失败! 订单id参数错误 orderId: %d", *orderDelivery.OrderId)
 		response.FailWithMessage("参数错误", c)
 	}
-	if err := orderDeliveryService.CreateOrderDelivery(orderDelivery); err != nil {
+	if err := orderDeliveryService.CreateOrderDelivery(orderDelivery, shopService.NewOrderOperator(utils.GetUserInfo(c))); err != nil {
 		global.Log.Error("发货失败!", zap.Error(err))
 		response.FailWithMessage("发货失败", c)
 	} else {
@@ -45,6 +47,30 @@ func (orderDeliveryApi *OrderDeliveryApi) CreateOrderDelivery(c *gin.Context) {
 	}
 }
 
+// BatchDispatch 批量派单
+// @Tags OrderDelivery
+// @Summary 批量派单 按配送路线为多个配送员发货
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shopReq.BatchDispatchReq true "订单id, 配送员id, 出发时间"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"派单成功"}"
+// @Router /orderDelivery/batchDispatch [post]
+func (orderDeliveryApi *OrderDeliveryApi) BatchDispatch(c *gin.Context) {
+	var req shopReq.BatchDispatchReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if routes, err := orderDeliveryService.BatchDispatch(req, shopService.NewOrderOperator(utils.GetUserInfo(c))); err != nil {
+		global.Log.Error("派单失败!", zap.Error(err))
+		response.FailWithMessage("派单失败, "+err.Error(), c)
+	} else {
+		response.OkWithDetailed(routes, "派单成功", c)
+	}
+}
+
 // DeleteOrderDelivery 删除OrderDelivery
 // @Tags OrderDelivery
 // @Summary 删除OrderDelivery
@@ -109,7 +135,7 @@ func (orderDeliveryApi *OrderDeliveryApi) UpdateOrderDelivery(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	if err := orderDeliveryService.UpdateOrderDelivery(orderDelivery); err != nil {
+	if err := orderDeliveryService.UpdateOrderDelivery(orderDelivery, shopService.NewOrderOperator(utils.GetUserInfo(c))); err != nil {
 		global.Log.Error("更新失败!", zap.Error(err))
 		response.FailWithMessage("更新失败", c)
 	} else {
@@ -7,6 +7,8 @@ import (
     shopReq "fresh-shop/server/model/shop/request"
     "fresh-shop/server/model/common/response"
     "fresh-shop/server/service"
+    shopService "fresh-shop/server/service/shop"
+    "fresh-shop/server/utils"
     "github.com/gin-gonic/gin"
     "go.uber.org/zap"
 )
@@ -17,27 +19,27 @@ type OrderReturnApi struct {
 var orderReturnService = service.ServiceGroupApp.ShopServiceGroup.OrderReturnService
 
 
-// CreateOrderReturn 创建OrderReturn
+// CreateOrderReturn 申请售后
 // @Tags OrderReturn
-// @Summary 创建OrderReturn
+// @Summary 申请售后，按订单商品提交售后数量，退款金额按实付金额分摊
 // @Security ApiKeyAuth
 // @accept application/json
 // @Produce application/json
-// @Param data body shop.OrderReturn true "创建OrderReturn"
-// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Param data body shopReq.OrderReturnApply true "申请售后"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"申请成功"}"
 // @Router /orderReturn/createOrderReturn [post]
 func (orderReturnApi *OrderReturnApi) CreateOrderReturn(c *gin.Context) {
-	var orderReturn shop.OrderReturn
-	err := c.ShouldBindJSON(&orderReturn)
+	var req shopReq.OrderReturnApply
+	err := c.ShouldBindJSON(&req)
 	if err != nil {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	if err := orderReturnService.CreateOrderReturn(orderReturn); err != nil {
-        global.Log.Error("创建失败!", zap.Error(err))
-		response.FailWithMessage("创建失败", c)
+	if orderReturn, err := orderReturnService.CreateOrderReturn(req, utils.GetUserID(c), shopService.NewOrderOperator(utils.GetUserInfo(c))); err != nil {
+		global.Log.Error("申请售后失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
 	} else {
-		response.OkWithMessage("创建成功", c)
+		response.OkWithDetailed(gin.H{"reorderReturn": orderReturn}, "申请成功", c)
 	}
 }
 
@@ -89,27 +91,28 @@ func (orderReturnApi *OrderReturnApi) DeleteOrderReturnByIds(c *gin.Context) {
 	}
 }
 
-// UpdateOrderReturn 更新OrderReturn
+// ReviewOrderReturn 审核售后
 // @Tags OrderReturn
-// @Summary 更新OrderReturn
+// @Summary 审核售后，同意后按原支付方式退款
 // @Security ApiKeyAuth
 // @accept application/json
 // @Produce application/json
-// @Param data body shop.OrderReturn true "更新OrderReturn"
-// @Success 200 {string} string "{"success":true,"data":{},"msg":"更新成功"}"
+// @Param data body shopReq.OrderReturnReview true "审核售后"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"操作成功"}"
+// @Router /orderReturn/reviewOrderReturn [put]
 // @Router /orderReturn/updateOrderReturn [put]
-func (orderReturnApi *OrderReturnApi) UpdateOrderReturn(c *gin.Context) {
-	var orderReturn shop.OrderReturn
-	err := c.ShouldBindJSON(&orderReturn)
+func (orderReturnApi *OrderReturnApi) ReviewOrderReturn(c *gin.Context) {
+	var req shopReq.OrderReturnReview
+	err := c.ShouldBindJSON(&req)
 	if err != nil {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	if err := orderReturnService.UpdateOrderReturn(orderReturn); err != nil {
-        global.Log.Error("更新失败!", zap.Error(err))
-		response.FailWithMessage("更新失败", c)
+	if err := orderReturnService.ReviewOrderReturn(req, shopService.NewOrderOperator(utils.GetUserInfo(c))); err != nil {
+		global.Log.Error("审核售后失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
 	} else {
-		response.OkWithMessage("更新成功", c)
+		response.OkWithMessage("操作成功", c)
 	}
 }
 
@@ -165,3 +168,33 @@ func (orderReturnApi *OrderReturnApi) GetOrderReturnList(c *gin.Context) {
         }, "获取成功", c)
     }
 }
+
+// GetUserOrderReturnList 用户分页获取自己的售后列表
+// @Tags OrderReturn
+// @Summary 用户分页获取自己的售后列表，包含售后商品和上门取件进度
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query shopReq.OrderReturnSearch true "用户分页获取自己的售后列表"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /orderReturn/getUserOrderReturnList [get]
+func (orderReturnApi *OrderReturnApi) GetUserOrderReturnList(c *gin.Context) {
+	var pageInfo shopReq.OrderReturnSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	pageInfo.UserId = utils.Pointer(int(utils.GetUserID(c)))
+	if list, total, err := orderReturnService.GetOrderReturnInfoList(pageInfo); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithDetailed(response.PageResult{
+			List:     list,
+			Total:    total,
+			Page:     pageInfo.Page,
+			PageSize: pageInfo.PageSize,
+		}, "获取成功", c)
+	}
+}
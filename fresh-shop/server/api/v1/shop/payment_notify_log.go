@@ -0,0 +1,72 @@
+package shop
+
+import (
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/common/response"
+	shopReq "fresh-shop/server/model/shop/request"
+	"fresh-shop/server/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type PaymentNotifyLogApi struct {
+}
+
+var paymentNotifyLogService = service.ServiceGroupApp.ShopServiceGroup.PaymentNotifyLogService
+
+// GetPaymentNotifyLogList 分页获取支付通知记录
+// @Tags PaymentNotifyLog
+// @Summary 分页获取支付通知记录
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query shopReq.PaymentNotifyLogSearch true "分页获取支付通知记录"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /paymentNotifyLog/getPaymentNotifyLogList [get]
+func (paymentNotifyLogApi *PaymentNotifyLogApi) GetPaymentNotifyLogList(c *gin.Context) {
+	var pageInfo shopReq.PaymentNotifyLogSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if list, total, err := paymentNotifyLogService.GetPaymentNotifyLogInfoList(pageInfo); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithDetailed(response.PageResult{
+			List:     list,
+			Total:    total,
+			Page:     pageInfo.Page,
+			PageSize: pageInfo.PageSize,
+		}, "获取成功", c)
+	}
+}
+
+// HandlePaymentNotifyLog 标记异常通知已人工处理
+// @Tags PaymentNotifyLog
+// @Summary 标记异常通知已人工处理
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shopReq.HandleNotifyLogReq true "通知记录ID和处理说明"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"处理成功"}"
+// @Router /paymentNotifyLog/handlePaymentNotifyLog [put]
+func (paymentNotifyLogApi *PaymentNotifyLogApi) HandlePaymentNotifyLog(c *gin.Context) {
+	var req shopReq.HandleNotifyLogReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if req.ID == 0 {
+		response.FailWithMessage("记录ID不能为空", c)
+		return
+	}
+	if err := paymentNotifyLogService.HandlePaymentNotifyLog(req); err != nil {
+		global.Log.Error("处理失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+	} else {
+		response.OkWithMessage("处理成功", c)
+	}
+}
@@ -0,0 +1,136 @@
+package shop
+
+import (
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/common/request"
+	"fresh-shop/server/model/common/response"
+	shopReq "fresh-shop/server/model/shop/request"
+	"fresh-shop/server/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"io"
+)
+
+type PaymentReconcileApi struct {
+}
+
+var paymentReconcileService = service.ServiceGroupApp.ShopServiceGroup.PaymentReconcileService
+
+// Reconcile 手动对账
+// @Tags PaymentReconcile
+// @Summary 对账指定日期的微信支付账单，上传账单文件时使用文件内容，否则从微信支付下载
+// @Security ApiKeyAuth
+// @accept multipart/form-data
+// @Produce application/json
+// @Param billDate formData string true "账单日期 2006-01-02"
+// @Param file formData file false "微信支付交易账单 CSV"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"对账完成"}"
+// @Router /paymentReconcile/reconcile [post]
+func (paymentReconcileApi *PaymentReconcileApi) Reconcile(c *gin.Context) {
+	var req shopReq.ReconcileReq
+	err := c.ShouldBind(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if req.BillDate == "" {
+		response.FailWithMessage("账单日期不能为空", c)
+		return
+	}
+	var data []byte
+	if file, _, err := c.Request.FormFile("file"); err == nil {
+		defer file.Close()
+		if data, err = io.ReadAll(file); err != nil {
+			response.FailWithMessage("读取账单文件失败", c)
+			return
+		}
+	}
+	if reconcile, err := paymentReconcileService.Reconcile(req.BillDate, data); err != nil {
+		global.Log.Error("对账失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+	} else {
+		response.OkWithDetailed(reconcile, "对账完成", c)
+	}
+}
+
+// GetPaymentReconcileList 分页获取对账记录
+// @Tags PaymentReconcile
+// @Summary 分页获取对账记录
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query shopReq.PaymentReconcileSearch true "分页获取对账记录"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /paymentReconcile/getPaymentReconcileList [get]
+func (paymentReconcileApi *PaymentReconcileApi) GetPaymentReconcileList(c *gin.Context) {
+	var pageInfo shopReq.PaymentReconcileSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if list, total, err := paymentReconcileService.GetPaymentReconcileInfoList(pageInfo); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithDetailed(response.PageResult{
+			List:     list,
+			Total:    total,
+			Page:     pageInfo.Page,
+			PageSize: pageInfo.PageSize,
+		}, "获取成功", c)
+	}
+}
+
+// GetPaymentReconcileDiffList 分页获取对账差异明细
+// @Tags PaymentReconcile
+// @Summary 分页获取对账差异明细
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query shopReq.PaymentReconcileDiffSearch true "分页获取对账差异明细"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /paymentReconcile/getPaymentReconcileDiffList [get]
+func (paymentReconcileApi *PaymentReconcileApi) GetPaymentReconcileDiffList(c *gin.Context) {
+	var pageInfo shopReq.PaymentReconcileDiffSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if list, total, err := paymentReconcileService.GetPaymentReconcileDiffInfoList(pageInfo); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+	} else {
+		response.OkWithDetailed(response.PageResult{
+			List:     list,
+			Total:    total,
+			Page:     pageInfo.Page,
+			PageSize: pageInfo.PageSize,
+		}, "获取成功", c)
+	}
+}
+
+// HandlePaymentReconcileDiff 标记对账差异已处理
+// @Tags PaymentReconcile
+// @Summary 标记对账差异已处理
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.GetById true "差异记录ID"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"处理成功"}"
+// @Router /paymentReconcile/handlePaymentReconcileDiff [put]
+func (paymentReconcileApi *PaymentReconcileApi) HandlePaymentReconcileDiff(c *gin.Context) {
+	var req request.GetById
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err := paymentReconcileService.HandlePaymentReconcileDiff(uint(req.ID)); err != nil {
+		global.Log.Error("处理失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+	} else {
+		response.OkWithMessage("处理成功", c)
+	}
+}
@@ -0,0 +1,189 @@
+package shop
+
+import (
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/common/response"
+	shopReq "fresh-shop/server/model/shop/request"
+	"fresh-shop/server/service"
+	shopService "fresh-shop/server/service/shop"
+	"fresh-shop/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"strconv"
+)
+
+type RiderApi struct {
+}
+
+var riderService = service.ServiceGroupApp.ShopServiceGroup.RiderService
+
+// GetRiderOrderList 配送员获取指派的订单列表
+// @Tags Rider
+// @Summary 配送员获取指派的订单列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query shopReq.RiderOrderSearch true "配送员获取指派的订单列表"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /rider/getRiderOrderList [get]
+func (riderApi *RiderApi) GetRiderOrderList(c *gin.Context) {
+	var pageInfo shopReq.RiderOrderSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if list, total, err := riderService.GetRiderOrderList(utils.GetUserID(c), pageInfo); err != nil {
+		global.Log.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败, "+err.Error(), c)
+	} else {
+		response.OkWithDetailed(response.PageResult{
+			List:     list,
+			Total:    total,
+			Page:     pageInfo.Page,
+			PageSize: pageInfo.PageSize,
+		}, "获取成功", c)
+	}
+}
+
+// PickUpOrder 配送员取货
+// @Tags Rider
+// @Summary 配送员取货
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shopReq.RiderOrderReq true "配送员取货"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"操作成功"}"
+// @Router /rider/pickUpOrder [put]
+func (riderApi *RiderApi) PickUpOrder(c *gin.Context) {
+	var req shopReq.RiderOrderReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if req.OrderId == 0 {
+		response.FailWithMessage("参数错误", c)
+		return
+	}
+	if err := riderService.PickUpOrder(utils.GetUserID(c), req.OrderId, shopService.NewOrderOperator(utils.GetUserInfo(c))); err != nil {
+		global.Log.Error("取货失败!", zap.Error(err))
+		response.FailWithMessage("取货失败, "+err.Error(), c)
+	} else {
+		response.OkWithMessage("取货成功", c)
+	}
+}
+
+// DeliverOrder 配送员确认送达
+// @Tags Rider
+// @Summary 配送员确认送达
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shopReq.RiderOrderReq true "配送员确认送达"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"操作成功"}"
+// @Router /rider/deliverOrder [put]
+func (riderApi *RiderApi) DeliverOrder(c *gin.Context) {
+	var req shopReq.RiderOrderReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if req.OrderId == 0 {
+		response.FailWithMessage("参数错误", c)
+		return
+	}
+	if err := riderService.DeliverOrder(utils.GetUserID(c), req.OrderId, shopService.NewOrderOperator(utils.GetUserInfo(c))); err != nil {
+		global.Log.Error("确认送达失败!", zap.Error(err))
+		response.FailWithMessage("确认送达失败, "+err.Error(), c)
+	} else {
+		response.OkWithMessage("确认送达成功", c)
+	}
+}
+
+// PickUpReturn 配送员上门取回售后商品
+// @Tags Rider
+// @Summary 配送员上门取回售后商品
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shopReq.RiderReturnReq true "配送员上门取回售后商品"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"操作成功"}"
+// @Router /rider/pickUpReturn [put]
+func (riderApi *RiderApi) PickUpReturn(c *gin.Context) {
+	var req shopReq.RiderReturnReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if req.ReturnId == 0 {
+		response.FailWithMessage("参数错误", c)
+		return
+	}
+	if err := riderService.PickUpReturn(utils.GetUserID(c), req.ReturnId, shopService.NewOrderOperator(utils.GetUserInfo(c))); err != nil {
+		global.Log.Error("售后取件失败!", zap.Error(err))
+		response.FailWithMessage("售后取件失败, "+err.Error(), c)
+	} else {
+		response.OkWithMessage("取件成功", c)
+	}
+}
+
+// DeliverReturn 配送员将售后商品送回门店
+// @Tags Rider
+// @Summary 配送员将售后商品送回门店
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body shopReq.RiderReturnReq true "配送员将售后商品送回门店"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"操作成功"}"
+// @Router /rider/deliverReturn [put]
+func (riderApi *RiderApi) DeliverReturn(c *gin.Context) {
+	var req shopReq.RiderReturnReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if req.ReturnId == 0 {
+		response.FailWithMessage("参数错误", c)
+		return
+	}
+	if err := riderService.DeliverReturn(utils.GetUserID(c), req.ReturnId, shopService.NewOrderOperator(utils.GetUserInfo(c))); err != nil {
+		global.Log.Error("售后商品送回失败!", zap.Error(err))
+		response.FailWithMessage("售后商品送回失败, "+err.Error(), c)
+	} else {
+		response.OkWithMessage("送回成功", c)
+	}
+}
+
+// UploadProof 配送员上传送达凭证图片
+// @Tags Rider
+// @Summary 配送员上传送达凭证图片
+// @Security ApiKeyAuth
+// @accept multipart/form-data
+// @Produce application/json
+// @Param orderId formData int true "订单id"
+// @Param file formData file true "送达凭证图片"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"上传成功"}"
+// @Router /rider/uploadProof [post]
+func (riderApi *RiderApi) UploadProof(c *gin.Context) {
+	orderId, err := strconv.Atoi(c.PostForm("orderId"))
+	if err != nil || orderId <= 0 {
+		response.FailWithMessage("参数错误", c)
+		return
+	}
+	_, header, err := c.Request.FormFile("file")
+	if err != nil {
+		global.Log.Error("接收文件失败!", zap.Error(err))
+		response.FailWithMessage("接收文件失败", c)
+		return
+	}
+	if url, err := riderService.UploadProof(utils.GetUserID(c), uint(orderId), header); err != nil {
+		global.Log.Error("上传失败!", zap.Error(err))
+		response.FailWithMessage("上传失败, "+err.Error(), c)
+	} else {
+		response.OkWithDetailed(gin.H{"url": url}, "上传成功", c)
+	}
+}
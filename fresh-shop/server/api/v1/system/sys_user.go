@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"fresh-shop/server/global"
+	"fresh-shop/server/model/business"
 	"fresh-shop/server/model/common/request"
 	"fresh-shop/server/model/common/response"
 	"fresh-shop/server/model/system"
@@ -117,6 +118,67 @@ func (b *BaseApi) LoginWx(c *gin.Context) {
 	return
 }
 
+// RiderLogin
+// @Tags     Base
+// @Summary  配送员登录
+// @Produce   application/json
+// @Param    data  body      systemReq.Login                                             true  "用户名(手机号), 密码, 验证码"
+// @Success  200   {object}  response.Response{data=systemRes.LoginResponse,msg=string}  "返回包括用户信息,token,过期时间"
+// @Router   /base/riderLogin [post]
+func (b *BaseApi) RiderLogin(c *gin.Context) {
+	var l systemReq.Login
+	err := c.ShouldBindJSON(&l)
+	key := c.ClientIP()
+
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(l, utils.LoginVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	// 与后台登录使用相同的防爆验证码策略
+	openCaptcha := global.Config.Captcha.OpenCaptcha
+	openCaptchaTimeOut := global.Config.Captcha.OpenCaptchaTimeOut
+	v, ok := global.BlackCache.Get(key)
+	if !ok {
+		global.BlackCache.Set(key, 1, time.Second*time.Duration(openCaptchaTimeOut))
+	}
+	oc := openCaptcha == 0 || openCaptcha < interfaceToInt(v)
+	if oc && !store.Verify(l.CaptchaId, l.Captcha, true) {
+		global.BlackCache.Increment(key, 1)
+		response.FailWithMessage("验证码错误", c)
+		return
+	}
+
+	u := &system.SysUser{Username: l.Username, Password: l.Password, LoginIp: key}
+	user, err := userService.Login(u)
+	if err != nil {
+		global.Log.Error("配送员登陆失败! 用户名不存在或者密码错误!", zap.Error(err))
+		global.BlackCache.Increment(key, 1)
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if user.AuthorityId != business.RiderAuthorityId {
+		global.BlackCache.Increment(key, 1)
+		response.FailWithMessage("该账号不是配送员账号", c)
+		return
+	}
+	if user.Enable != 1 {
+		global.Log.Error("配送员登陆失败! 用户被禁止登录!")
+		response.FailWithMessage("用户被禁止登录", c)
+		return
+	}
+	if _, err = riderService.GetRider(user.ID); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	b.TokenNext(c, *user)
+}
+
 // TokenNext 登录以后签发jwt
 func (b *BaseApi) TokenNext(c *gin.Context, user system.SysUser) {
 	j := &utils.JWT{SigningKey: []byte(global.Config.JWT.SigningKey)} // 唯一签名
@@ -201,7 +263,7 @@ func (b *BaseApi) Register(c *gin.Context) {
 			AuthorityId: v,
 		})
 	}
-	user := &system.SysUser{Username: r.Username, NickName: r.NickName, Password: r.Password, HeaderImg: r.HeaderImg, AuthorityId: r.AuthorityId, Authorities: authorities, Enable: r.Enable, Phone: r.Phone, Email: r.Email}
+	user := &system.SysUser{Username: r.Username, NickName: r.NickName, Password: r.Password, HeaderImg: r.HeaderImg, AuthorityId: r.AuthorityId, Authorities: authorities, Enable: r.Enable, Phone: r.Phone, Email: r.Email, InviterId: userService.FindInviterId(r.InviteCode)}
 	userReturn, err := userService.Register(*user)
 	if err != nil {
 		global.Log.Error("注册失败!", zap.Error(err))
@@ -242,6 +304,33 @@ func (b *BaseApi) ChangePassword(c *gin.Context) {
 	response.OkWithMessage("修改成功", c)
 }
 
+// SetSafePassword
+// @Tags      SysUser
+// @Summary   设置安全密码
+// @Security  ApiKeyAuth
+// @Produce  application/json
+// @Param     data  body      systemReq.SetSafePasswordReq   true  "原安全密码, 新安全密码"
+// @Success   200   {object}  response.Response{msg=string}  "设置安全密码"
+// @Router    /user/setSafePassword [post]
+func (b *BaseApi) SetSafePassword(c *gin.Context) {
+	var req systemReq.SetSafePasswordReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err = utils.Verify(req, utils.SafePasswordVerify); err != nil {
+		response.FailWithMessage("安全密码必须为6位数字", c)
+		return
+	}
+	if err = userService.SetSafePassword(utils.GetUserID(c), req); err != nil {
+		global.Log.Error("设置失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithMessage("设置成功", c)
+}
+
 // GetUserList
 // @Tags      SysUser
 // @Summary   分页获取用户列表
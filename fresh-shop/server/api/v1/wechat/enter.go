@@ -1,6 +1,9 @@
 package wechat
 
-import "fresh-shop/server/service"
+import (
+	paymentApiGroup "fresh-shop/server/api/v1/payment"
+	"fresh-shop/server/service"
+)
 
 type ApiGroup struct {
 	WeChatApi
@@ -9,4 +12,5 @@ type ApiGroup struct {
 var (
 	wechatService = service.ServiceGroupApp.WechatServiceGroup.WechatService
 	userService   = service.ServiceGroupApp.SystemServiceGroup.UserService
+	paymentApi    = new(paymentApiGroup.PaymentApi)
 )
@@ -4,8 +4,8 @@ import (
 	"fresh-shop/server/global"
 	"fresh-shop/server/model/common/response"
 	"fresh-shop/server/model/wechat/request"
+	"fresh-shop/server/service/payment"
 	"github.com/gin-gonic/gin"
-	"github.com/silenceper/wechat/v2/pay/notify"
 	"go.uber.org/zap"
 )
 
@@ -58,38 +58,14 @@ func (w *WeChatApi) CreatePayData(c *gin.Context) {
 	}
 }
 
-// PayNotify 支付完成回调
+// PayNotify 支付完成回调，兼容原有的微信回调地址，统一由 PaymentApi 处理
 func (w *WeChatApi) PayNotify(c *gin.Context) {
-	global.SugarLog.Infof("微信支付回调 开始 \n")
-	var req notify.PaidResult
-	err := c.ShouldBindXML(&req)
-	if err != nil {
-		response.FailWithMessage(err.Error(), c)
-		return
-	}
-	n := global.WxPay.GetNotify()
-	if ok := n.PaidVerifySign(req); !ok {
-		global.SugarLog.Errorf("支付回调签名验证失败! %#v \n", req)
-		response.WxpayNotify("FAIL", "签名失败", c)
-		return
-	}
+	c.Params = append(c.Params, gin.Param{Key: "channel", Value: payment.ChannelWechat})
+	paymentApi.PayNotify(c)
+}
 
-	if *req.ReturnCode != "SUCCESS" {
-		global.SugarLog.Errorf("支付回调失败! ReturnCode:%s, ReturnMsg: %s \n", *req.ReturnCode, *req.ReturnMsg)
-		response.WxpayNotify("FAIL", "参数格式校验错误", c)
-		return
-	}
-	if *req.ResultCode != "SUCCESS" {
-		global.SugarLog.Errorf("支付回调失败! ResultCode:%s, ResultMsg: %s \n", *req.ResultCode, *req.ErrCodeDes)
-		response.WxpayNotify("FAIL", "ResultMsg: "+*req.ReturnMsg, c)
-		return
-	}
-	global.SugarLog.Infof("微信支付回调 验证通过开始执行业务逻辑\n")
-	err = wechatService.NotifyLogic(&req)
-	if err != nil {
-		global.SugarLog.Errorf("支付回调失败! err: %v \n", err)
-		response.WxpayNotify("FAIL", "参数格式校验错误", c)
-	} else {
-		response.WxpayNotify("SUCCESS", "OK", c)
-	}
+// RefundNotify 退款结果回调，兼容原有的微信回调地址，统一由 PaymentApi 处理
+func (w *WeChatApi) RefundNotify(c *gin.Context) {
+	c.Params = append(c.Params, gin.Param{Key: "channel", Value: payment.ChannelWechat})
+	paymentApi.RefundNotify(c)
 }
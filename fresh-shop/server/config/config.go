@@ -31,4 +31,8 @@ type Server struct {
 
 	Wechat    Wechat    `mapstructure:"wechat" json:"wechat" yaml:"wechat"`
 	WechatPay WechatPay `mapstructure:"wechatPay" json:"wechatPay" yaml:"wechatPay"`
+	Payment   Payment   `mapstructure:"payment" json:"payment" yaml:"payment"`
+
+	// 订单配置
+	Order Order `mapstructure:"order" json:"order" yaml:"order"`
 }
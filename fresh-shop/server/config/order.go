@@ -0,0 +1,60 @@
+package config
+
+import "time"
+
+type Order struct {
+	PayTimeout     string  `mapstructure:"pay-timeout" json:"pay-timeout" yaml:"pay-timeout"`                // 待支付订单超时时间 例: 30m
+	CancelSpec     string  `mapstructure:"cancel-spec" json:"cancel-spec" yaml:"cancel-spec"`                // 超时未支付订单取消任务 CRON 表达式
+	StockReserve   bool    `mapstructure:"stock-reserve" json:"stock-reserve" yaml:"stock-reserve"`          // 是否开启 Redis 库存预占(需开启 use-redis)
+	ReceiveSpec    string  `mapstructure:"receive-spec" json:"receive-spec" yaml:"receive-spec"`             // 自动确认收货任务 CRON 表达式，天数在系统配置 autoReceiveDays 中设置
+	PickUpStart    int     `mapstructure:"pick-up-start" json:"pick-up-start" yaml:"pick-up-start"`          // 每天第一个取餐号码
+	PickUpPrefix   string  `mapstructure:"pick-up-prefix" json:"pick-up-prefix" yaml:"pick-up-prefix"`       // 取餐码前缀 例: A
+	SlotDays       int     `mapstructure:"slot-days" json:"slot-days" yaml:"slot-days"`                      // 可预约未来几天的配送时段(含当天)
+	RiderSpeed     float64 `mapstructure:"rider-speed" json:"rider-speed" yaml:"rider-speed"`                // 批量派单估算送达时间使用的配送速度(km/h)
+	StopMinutes    int     `mapstructure:"stop-minutes" json:"stop-minutes" yaml:"stop-minutes"`             // 批量派单估算送达时间时每单停留分钟数
+	GroupSpec      string  `mapstructure:"group-spec" json:"group-spec" yaml:"group-spec"`                   // 超时未成团的拼团退款任务 CRON 表达式
+	PaySyncSpec    string  `mapstructure:"pay-sync-spec" json:"pay-sync-spec" yaml:"pay-sync-spec"`          // 未支付订单主动查询支付结果任务 CRON 表达式，用于补偿丢失的支付回调
+	RefundSyncSpec string  `mapstructure:"refund-sync-spec" json:"refund-sync-spec" yaml:"refund-sync-spec"` // 重新申请未被支付渠道受理的退款任务 CRON 表达式
+	ReconcileSpec  string  `mapstructure:"reconcile-spec" json:"reconcile-spec" yaml:"reconcile-spec"`       // 每日下载前一天微信支付账单对账任务 CRON 表达式
+}
+
+// GetPayTimeout 获取待支付订单超时时间，未配置或配置错误时默认 30 分钟
+func (o *Order) GetPayTimeout() time.Duration {
+	d, err := time.ParseDuration(o.PayTimeout)
+	if err != nil || d <= 0 {
+		return 30 * time.Minute
+	}
+	return d
+}
+
+// GetPickUpStart 获取每天第一个取餐号码，未配置时默认 101
+func (o *Order) GetPickUpStart() int {
+	if o.PickUpStart <= 0 {
+		return 101
+	}
+	return o.PickUpStart
+}
+
+// GetSlotDays 获取可预约配送时段的天数，未配置时默认 3 天
+func (o *Order) GetSlotDays() int {
+	if o.SlotDays <= 0 {
+		return 3
+	}
+	return o.SlotDays
+}
+
+// GetRiderSpeed 获取配送速度(km/h)，未配置时默认 15
+func (o *Order) GetRiderSpeed() float64 {
+	if o.RiderSpeed <= 0 {
+		return 15
+	}
+	return o.RiderSpeed
+}
+
+// GetStopDuration 获取每单停留时间，未配置时默认 5 分钟
+func (o *Order) GetStopDuration() time.Duration {
+	if o.StopMinutes <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(o.StopMinutes) * time.Minute
+}
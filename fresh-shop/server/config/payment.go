@@ -0,0 +1,33 @@
+package config
+
+// Payment 支付渠道配置
+type Payment struct {
+	Sandbox Sandbox `mapstructure:"sandbox" json:"sandbox" yaml:"sandbox"`
+	Alipay  Alipay  `mapstructure:"alipay" json:"alipay" yaml:"alipay"`
+}
+
+// Alipay 支付宝开放平台配置，签名方式为 RSA2
+type Alipay struct {
+	AppId      string `mapstructure:"app-id" json:"app-id" yaml:"app-id"`
+	PrivateKey string `mapstructure:"private-key" json:"private-key" yaml:"private-key"` // 应用私钥 PKCS1 或 PKCS8，PEM 或去掉头尾的 base64
+	PublicKey  string `mapstructure:"public-key" json:"public-key" yaml:"public-key"`    // 支付宝公钥
+	Gateway    string `mapstructure:"gateway" json:"gateway" yaml:"gateway"`             // 网关地址，沙箱环境为 https://openapi-sandbox.dl.alipaydev.com/gateway.do
+	NotifyURL  string `mapstructure:"notify-url" json:"notify-url" yaml:"notify-url"`    // 支付结果异步通知地址
+	ReturnURL  string `mapstructure:"return-url" json:"return-url" yaml:"return-url"`    // 支付完成后跳转地址
+}
+
+// Sandbox 本地沙箱支付配置，开启后微信、支付宝支付全部走本地沙箱，不需要商户号
+type Sandbox struct {
+	Enable          bool   `mapstructure:"enable" json:"enable" yaml:"enable"`
+	Key             string `mapstructure:"key" json:"key" yaml:"key"`                                           // 回调通知签名密钥
+	NotifyURL       string `mapstructure:"notify-url" json:"notify-url" yaml:"notify-url"`                      // 支付结果回调地址
+	RefundNotifyURL string `mapstructure:"refund-notify-url" json:"refund-notify-url" yaml:"refund-notify-url"` // 退款结果回调地址
+}
+
+// GetGateway 获取支付宝网关地址，未配置时使用正式环境
+func (a *Alipay) GetGateway() string {
+	if a.Gateway == "" {
+		return "https://openapi.alipay.com/gateway.do"
+	}
+	return a.Gateway
+}
@@ -7,10 +7,18 @@ type Wechat struct {
 }
 
 type WechatPay struct {
-	MchId     string `mapstructure:"mchId" json:"mchId" yaml:"mchId"`             // 商户号
-	ApiV2Key  string `mapstructure:"apiV2Key" json:"apiV2Key" yaml:"apiV2Key"`    // 商户号
-	NotifyURL string `mapstructure:"notifyUrl" json:"notifyUrl" yaml:"notifyUrl"` // 微信支付通知地址
-	Debug     bool   `mapstructure:"debug" json:"debug" yaml:"debug"`
-	CertPath  string `mapstructure:"certPath" json:"certPath" yaml:"certPath"`
-	KeyPath   string `mapstructure:"keyPath" json:"keyPath" yaml:"keyPath"`
+	MchId           string `mapstructure:"mchId" json:"mchId" yaml:"mchId"`                               // 商户号
+	ApiV2Key        string `mapstructure:"apiV2Key" json:"apiV2Key" yaml:"apiV2Key"`                      // 商户号
+	NotifyURL       string `mapstructure:"notifyUrl" json:"notifyUrl" yaml:"notifyUrl"`                   // 微信支付通知地址
+	RefundNotifyURL string `mapstructure:"refundNotifyUrl" json:"refundNotifyUrl" yaml:"refundNotifyUrl"` // 微信退款结果通知地址
+	CertPath        string `mapstructure:"certPath" json:"certPath" yaml:"certPath"`                      // 商户 API 证书 apiclient_cert.pem
+	KeyPath         string `mapstructure:"keyPath" json:"keyPath" yaml:"keyPath"`                         // 商户 API 证书私钥 apiclient_key.pem
+	ApiVersion      string `mapstructure:"apiVersion" json:"apiVersion" yaml:"apiVersion"`                // 接口版本 v2 或 v3，默认 v2
+	ApiV3Key        string `mapstructure:"apiV3Key" json:"apiV3Key" yaml:"apiV3Key"`                      // APIv3 密钥，用于解密回调通知和平台证书
+	SerialNo        string `mapstructure:"serialNo" json:"serialNo" yaml:"serialNo"`                      // 商户 API 证书序列号，为空时从 certPath 证书中读取
+}
+
+// IsV3 是否使用微信支付 APIv3
+func (w *WechatPay) IsV3() bool {
+	return w.ApiVersion == "v3"
 }
@@ -19,6 +19,8 @@ func RunWindowsServer() {
 		// 初始化redis服务
 		initialize.Redis()
 	}
+	// 秒杀下单队列
+	initialize.FlashSale()
 
 	// 从db加载jwt数据
 	if global.DB != nil {
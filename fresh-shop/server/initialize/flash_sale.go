@@ -0,0 +1,16 @@
+package initialize
+
+import (
+	"fresh-shop/server/global"
+	"fresh-shop/server/service"
+)
+
+// FlashSale 启动秒杀下单队列消费者，秒杀依赖 Redis，未开启 Redis 时不启动
+func FlashSale() {
+	if global.Redis == nil {
+		global.Log.Warn("未开启 Redis, 秒杀下单队列未启动")
+		return
+	}
+	flashSaleService := service.ServiceGroupApp.ShopServiceGroup.FlashSaleService
+	go flashSaleService.ConsumeFlashOrderQueue()
+}
@@ -56,7 +56,11 @@ func RegisterTables() {
 		shop.GoodsImage{}, shop.GoodsSpec{}, shop.GoodsSpecItem{}, shop.GoodsSpecValue{},
 		shop.Order{}, shop.OrderDetails{}, shop.OrderDelivery{}, business.UserDelivery{},
 		shop.OrderReturn{}, shop.OrderReturnDetails{}, shop.Favorites{}, shop.Cart{},
-		shop.UserAddress{}, system.SysConfig{},
+		shop.UserAddress{}, system.SysConfig{}, shop.OrderLog{}, shop.PickUpSequence{},
+		business.DeliveryZone{}, business.DeliverySlot{}, business.DeliverySlotUsage{},
+		shop.Coupon{}, shop.UserCoupon{}, shop.FlashSale{}, shop.FlashSaleGoods{},
+		shop.GroupBuy{}, shop.GroupBuyTeam{}, account.UserCommission{}, account.UserTeam{},
+		shop.PaymentReconcile{}, shop.PaymentReconcileDiff{}, shop.PaymentNotifyLog{},
 	)
 	if err != nil {
 		global.Log.Error("register table failed", zap.Error(err))
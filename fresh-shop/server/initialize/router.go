@@ -78,11 +78,14 @@ func Routers() *gin.Engine {
 		accountRouter.InitSysRechargeRouter(PrivateGroup)
 		accountRouter.InitUserFinanceTypeRouter(PrivateGroup)
 		accountRouter.InitUserFinanceCashRouter(PrivateGroup)
+		accountRouter.InitUserCommissionRouter(PrivateGroup)
 	}
 	{
 		businessRouter := router.RouterGroupApp.Business
 		businessRouter.InitBannerRouter(PrivateGroup)
 		businessRouter.InitUserDeliveryRouter(PrivateGroup)
+		businessRouter.InitDeliveryZoneRouter(PrivateGroup)
+		businessRouter.InitDeliverySlotRouter(PrivateGroup)
 
 		// 不进行路由鉴权的路由
 		{
@@ -108,10 +111,18 @@ func Routers() *gin.Engine {
 			shopRouter.InitBrandPublicRouter(PublicGroup)
 			shopRouter.InitCategoryPublicRouter(PublicGroup)
 			shopRouter.InitTagsPublicRouter(PublicGroup)
+			shopRouter.InitFlashSalePublicRouter(PublicGroup)
+			shopRouter.InitGroupBuyPublicRouter(PublicGroup)
 		}
 		shopRouter.InitFavoritesRouter(PrivateGroup)
 		shopRouter.InitCartRouter(PrivateGroup)
 		shopRouter.InitUserAddressRouter(PrivateGroup)
+		shopRouter.InitRiderRouter(PrivateGroup) // 配送员端
+		shopRouter.InitCouponRouter(PrivateGroup)
+		shopRouter.InitFlashSaleRouter(PrivateGroup)
+		shopRouter.InitGroupBuyRouter(PrivateGroup)
+		shopRouter.InitPaymentReconcileRouter(PrivateGroup)
+		shopRouter.InitPaymentNotifyLogRouter(PrivateGroup)
 	}
 	{
 		wechatRoute := router.RouterGroupApp.Wechat
@@ -121,6 +132,14 @@ func Routers() *gin.Engine {
 			wechatRoute.InitWechatPublicRouter(PublicGroup)
 		}
 	}
+	{
+		paymentRouter := router.RouterGroupApp.Payment
+		paymentRouter.InitPaymentRouter(PrivateGroup)
+		// 支付渠道回调不进行鉴权
+		{
+			paymentRouter.InitPaymentPublicRouter(PublicGroup)
+		}
+	}
 
 	global.Log.Info("router register success")
 	return Router
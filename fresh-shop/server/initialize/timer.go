@@ -7,6 +7,7 @@ import (
 
 	"fresh-shop/server/config"
 	"fresh-shop/server/global"
+	"fresh-shop/server/service"
 	"fresh-shop/server/utils"
 )
 
@@ -30,4 +31,46 @@ func Timer() {
 			}(global.Config.Timer.Detail[i])
 		}
 	}
+
+	// 订单相关定时任务
+	orderService := service.ServiceGroupApp.ShopServiceGroup.OrderService
+	if global.Config.Order.CancelSpec != "" {
+		_, err := global.Timer.AddTaskByFunc("Order", global.Config.Order.CancelSpec, orderService.CancelTimeoutOrder)
+		if err != nil {
+			fmt.Println("add order timer error:", err)
+		}
+	}
+	if global.Config.Order.GroupSpec != "" {
+		groupBuyService := service.ServiceGroupApp.ShopServiceGroup.GroupBuyService
+		_, err := global.Timer.AddTaskByFunc("Order", global.Config.Order.GroupSpec, groupBuyService.ExpireGroupTeams)
+		if err != nil {
+			fmt.Println("add order timer error:", err)
+		}
+	}
+	if global.Config.Order.PaySyncSpec != "" {
+		_, err := global.Timer.AddTaskByFunc("Order", global.Config.Order.PaySyncSpec, orderService.SyncUnpaidOrders)
+		if err != nil {
+			fmt.Println("add order timer error:", err)
+		}
+	}
+	if global.Config.Order.RefundSyncSpec != "" {
+		_, err := global.Timer.AddTaskByFunc("Order", global.Config.Order.RefundSyncSpec, orderService.SyncPendingRefunds)
+		if err != nil {
+			fmt.Println("add order timer error:", err)
+		}
+	}
+	if global.Config.Order.ReconcileSpec != "" {
+		paymentReconcileService := service.ServiceGroupApp.ShopServiceGroup.PaymentReconcileService
+		_, err := global.Timer.AddTaskByFunc("Order", global.Config.Order.ReconcileSpec, paymentReconcileService.DailyReconcile)
+		if err != nil {
+			fmt.Println("add order timer error:", err)
+		}
+	}
+	if global.Config.Order.ReceiveSpec != "" {
+		orderDeliveryService := service.ServiceGroupApp.ShopServiceGroup.OrderDeliveryService
+		_, err := global.Timer.AddTaskByFunc("Order", global.Config.Order.ReceiveSpec, orderDeliveryService.AutoReceiveOrder)
+		if err != nil {
+			fmt.Println("add order timer error:", err)
+		}
+	}
 }
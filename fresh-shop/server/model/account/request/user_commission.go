@@ -0,0 +1,23 @@
+package request
+
+import (
+	"fresh-shop/server/model/common/request"
+	"time"
+)
+
+// UserCommissionSearch 后台佣金记录查询
+type UserCommissionSearch struct {
+	request.UserSearch
+	OrderSn        string     `json:"orderSn" form:"orderSn"`
+	Level          int        `json:"level" form:"level"`
+	Status         *int       `json:"status" form:"status"` // 0冻结中 1已结算 2已撤销 3撤销失败
+	StartCreatedAt *time.Time `json:"startCreatedAt" form:"startCreatedAt"`
+	EndCreatedAt   *time.Time `json:"endCreatedAt" form:"endCreatedAt"`
+	request.PageInfo
+}
+
+// MyCommissionSearch 用户佣金明细查询
+type MyCommissionSearch struct {
+	Status *int `json:"status" form:"status"` // 0冻结中 1已结算 2已撤销 3撤销失败
+	request.PageInfo
+}
@@ -0,0 +1,39 @@
+package account
+
+import (
+	"fresh-shop/server/global"
+	sysModel "fresh-shop/server/model/system"
+	"time"
+)
+
+// 分销佣金状态
+const (
+	CommissionFrozen   = 0 // 冻结中，订单收货前
+	CommissionSettled  = 1 // 已结算到可用余额
+	CommissionReversed = 2 // 订单退款已撤销
+	// CommissionReverseFailed 订单退款时已结算佣金扣回失败(可用余额不足)，用户仍持有该笔佣金，需人工追回
+	CommissionReverseFailed = 3
+)
+
+// UserCommission 分销佣金记录，每笔订单每个上级一条
+type UserCommission struct {
+	global.DbModel
+	UserId       uint             `json:"userId" form:"userId" gorm:"index;comment:获得佣金的用户id"`
+	FromUserId   uint             `json:"fromUserId" form:"fromUserId" gorm:"comment:下单用户id"`
+	OrderId      uint             `json:"orderId" form:"orderId" gorm:"index;comment:订单id"`
+	OrderSn      string           `json:"orderSn" form:"orderSn" gorm:"size:64;comment:订单号"`
+	Level        int              `json:"level" form:"level" gorm:"comment:分销层级 1一级 2二级"`
+	Rate         float64          `json:"rate" form:"rate" gorm:"comment:佣金比例(百分比)"`
+	BaseAmount   float64          `json:"baseAmount" form:"baseAmount" gorm:"comment:计佣金额"`
+	Amount       float64          `json:"amount" form:"amount" gorm:"comment:佣金金额"`
+	RefundAmount float64          `json:"refundAmount" form:"refundAmount" gorm:"default:0;comment:售后退款已扣回的佣金金额"`
+	Status       int              `json:"status" form:"status" gorm:"default:0;comment:状态 0冻结中 1已结算 2已撤销 3撤销失败"`
+	SettleTime   *time.Time       `json:"settleTime" form:"settleTime" gorm:"comment:结算时间"`
+	User         sysModel.SysUser `json:"user"`
+	FromUser     sysModel.SysUser `json:"fromUser" gorm:"foreignKey:FromUserId"`
+}
+
+// TableName UserCommission 表名
+func (UserCommission) TableName() string {
+	return "user_commission"
+}
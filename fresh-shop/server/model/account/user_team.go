@@ -0,0 +1,20 @@
+package account
+
+import (
+	"fresh-shop/server/global"
+)
+
+// UserTeam 用户团队统计，团队为一级和二级下级
+type UserTeam struct {
+	global.DbModel
+	UserId     uint    `json:"userId" form:"userId" gorm:"uniqueIndex;comment:用户id"`
+	DirectNum  int     `json:"directNum" form:"directNum" gorm:"default:0;comment:直推人数"`
+	TeamNum    int     `json:"teamNum" form:"teamNum" gorm:"default:0;comment:团队人数"`
+	TeamAmount float64 `json:"teamAmount" form:"teamAmount" gorm:"default:0;comment:团队累计业绩"`
+	Commission float64 `json:"commission" form:"commission" gorm:"default:0;comment:累计结算佣金"`
+}
+
+// TableName UserTeam 表名
+func (UserTeam) TableName() string {
+	return "user_team"
+}
@@ -0,0 +1,35 @@
+package business
+
+import (
+	"fresh-shop/server/global"
+)
+
+// DeliverySlot 配送时段 每天按相同的时段接受预约
+type DeliverySlot struct {
+	global.DbModel
+	Name      string `json:"name" form:"name" gorm:"column:name;comment:时段名称;size:50;"`
+	StartTime string `json:"startTime" form:"startTime" gorm:"column:start_time;comment:开始时间(15:04);size:5;"`
+	EndTime   string `json:"endTime" form:"endTime" gorm:"column:end_time;comment:结束时间(15:04);size:5;"`
+	Capacity  *int   `json:"capacity" form:"capacity" gorm:"column:capacity;default:0;comment:每天可预约订单数;"`
+	ZoneId    uint   `json:"zoneId" form:"zoneId" gorm:"column:zone_id;default:0;comment:配送区域id(0不限区域);"`
+	Status    *int   `json:"status" form:"status" gorm:"column:status;default:1;comment:状态(0禁用 1启用);"`
+	Sort      int    `json:"sort" form:"sort" gorm:"column:sort;comment:排序;"`
+}
+
+// TableName DeliverySlot 表名
+func (DeliverySlot) TableName() string {
+	return "shop_delivery_slot"
+}
+
+// DeliverySlotUsage 配送时段每天已预约数量
+type DeliverySlotUsage struct {
+	global.DbModel
+	SlotId uint   `json:"slotId" form:"slotId" gorm:"column:slot_id;comment:配送时段id;uniqueIndex:idx_slot_day;"`
+	Day    string `json:"day" form:"day" gorm:"column:day;comment:配送日期(2006-01-02);size:10;uniqueIndex:idx_slot_day;"`
+	Used   int    `json:"used" form:"used" gorm:"column:used;default:0;comment:已预约数量;"`
+}
+
+// TableName DeliverySlotUsage 表名
+func (DeliverySlotUsage) TableName() string {
+	return "shop_delivery_slot_usage"
+}
@@ -0,0 +1,24 @@
+package business
+
+import (
+	"fresh-shop/server/global"
+)
+
+// DeliveryZone 配送区域
+type DeliveryZone struct {
+	global.DbModel
+	Name      string   `json:"name" form:"name" gorm:"column:name;comment:区域名称;size:50;"`
+	Type      *int     `json:"type" form:"type" gorm:"column:type;default:0;comment:区域类型(0圆形 1多边形);"`
+	Longitude *float64 `json:"longitude" form:"longitude" gorm:"column:longitude;default:0;comment:圆心经度;size:20;"`
+	Latitude  *float64 `json:"latitude" form:"latitude" gorm:"column:latitude;default:0;comment:圆心纬度;size:20;"`
+	Radius    *float64 `json:"radius" form:"radius" gorm:"column:radius;default:0;comment:配送半径(米);"`
+	Polygon   string   `json:"polygon" form:"polygon" gorm:"column:polygon;type:text;comment:多边形顶点 JSON [[经度,纬度],...];"`
+	Status    *int     `json:"status" form:"status" gorm:"column:status;default:1;comment:状态(0禁用 1启用);"`
+	Sort      int      `json:"sort" form:"sort" gorm:"column:sort;comment:排序 多个区域重叠时优先匹配;"`
+	Remark    string   `json:"remark" form:"remark" gorm:"column:remark;comment:备注;size:255;"`
+}
+
+// TableName DeliveryZone 表名
+func (DeliveryZone) TableName() string {
+	return "shop_delivery_zone"
+}
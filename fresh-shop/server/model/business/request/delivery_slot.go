@@ -0,0 +1,19 @@
+package request
+
+import (
+	"fresh-shop/server/model/business"
+	"fresh-shop/server/model/common/request"
+	"time"
+)
+
+type DeliverySlotSearch struct {
+	business.DeliverySlot
+	StartCreatedAt *time.Time `json:"startCreatedAt" form:"startCreatedAt"`
+	EndCreatedAt   *time.Time `json:"endCreatedAt" form:"endCreatedAt"`
+	request.PageInfo
+}
+
+// AvailableDeliverySlotReq 查询收货地址可预约的配送时段
+type AvailableDeliverySlotReq struct {
+	AddressId int `json:"addressId" form:"addressId"` // 收货地址id
+}
@@ -0,0 +1,20 @@
+package request
+
+import (
+	"fresh-shop/server/model/business"
+	"fresh-shop/server/model/common/request"
+	"time"
+)
+
+type DeliveryZoneSearch struct {
+	business.DeliveryZone
+	StartCreatedAt *time.Time `json:"startCreatedAt" form:"startCreatedAt"`
+	EndCreatedAt   *time.Time `json:"endCreatedAt" form:"endCreatedAt"`
+	request.PageInfo
+}
+
+// DeliveryZoneLocation 根据坐标查询配送区域
+type DeliveryZoneLocation struct {
+	Longitude float64 `json:"longitude" form:"longitude"` // 经度
+	Latitude  float64 `json:"latitude" form:"latitude"`   // 纬度
+}
@@ -0,0 +1,7 @@
+package request
+
+// RiderAccountReq 设置配送员登录账号
+type RiderAccountReq struct {
+	ID       uint   `json:"id" form:"id"`             // 配送员id
+	Password string `json:"password" form:"password"` // 登录密码
+}
@@ -0,0 +1,13 @@
+package response
+
+import "time"
+
+// AvailableDeliverySlot 可预约的配送时段
+type AvailableDeliverySlot struct {
+	SlotId    uint      `json:"slotId"`    // 配送时段id
+	Name      string    `json:"name"`      // 时段名称
+	Day       string    `json:"day"`       // 配送日期
+	Start     time.Time `json:"start"`     // 开始时间
+	End       time.Time `json:"end"`       // 结束时间
+	Remaining int       `json:"remaining"` // 剩余可预约数量
+}
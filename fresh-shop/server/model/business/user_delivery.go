@@ -5,6 +5,9 @@ import (
 	
 )
 
+// RiderAuthorityId 配送员角色，配送员使用该角色登录配送端
+const RiderAuthorityId uint = 2000
+
 // UserDelivery 结构体
 type UserDelivery struct {
       global.DbModel
@@ -12,6 +15,7 @@ type UserDelivery struct {
       Mobile  string `json:"mobile" form:"mobile" gorm:"column:mobile;comment:送货人手机号;size:11;"`
       DeliverCount  *int `json:"deliverCount" form:"deliverCount" gorm:"column:deliver_count;comment:送货单数;size:10;"`
       Status  *int `json:"status" form:"status" gorm:"column:status;comment:状态（0禁用 1启用）;"`
+      UserId  uint `json:"userId" form:"userId" gorm:"column:user_id;comment:配送员登录账号id;index;"`
 }
 
 
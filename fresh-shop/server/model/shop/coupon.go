@@ -0,0 +1,83 @@
+package shop
+
+import (
+	"fresh-shop/server/global"
+	"time"
+)
+
+// 优惠券类型
+const (
+	CouponTypeAmount  = 1 // 满减券 减免固定金额
+	CouponTypePercent = 2 // 折扣券 按百分比折扣
+)
+
+// 优惠券适用范围
+const (
+	CouponScopeAll      = 0 // 全场通用
+	CouponScopeCategory = 1 // 指定分类
+	CouponScopeBrand    = 2 // 指定品牌
+)
+
+// 优惠券发放来源
+const (
+	CouponSourceClaim    = 1 // 领券中心领取
+	CouponSourceAdmin    = 2 // 后台发放
+	CouponSourceRegister = 3 // 注册赠送
+)
+
+// Coupon 优惠券模板
+type Coupon struct {
+	global.DbModel
+	Name         string     `json:"name" form:"name" gorm:"column:name;comment:优惠券名称;size:50;"`
+	Type         int        `json:"type" form:"type" gorm:"column:type;comment:类型(1满减券 2折扣券);"`
+	Amount       float64    `json:"amount" form:"amount" gorm:"column:amount;comment:减免金额(满减券);size:14;"`
+	Percent      int        `json:"percent" form:"percent" gorm:"column:percent;comment:折扣百分比(折扣券) 例: 85 表示 85 折;"`
+	MaxDiscount  float64    `json:"maxDiscount" form:"maxDiscount" gorm:"column:max_discount;comment:最高优惠金额(折扣券) 0不限;size:14;"`
+	Threshold    float64    `json:"threshold" form:"threshold" gorm:"column:threshold;comment:使用门槛 适用商品满该金额可用 0无门槛;size:14;"`
+	ScopeType    int        `json:"scopeType" form:"scopeType" gorm:"column:scope_type;comment:适用范围(0全场 1指定分类 2指定品牌);"`
+	ScopeIds     string     `json:"scopeIds" form:"scopeIds" gorm:"column:scope_ids;comment:适用分类或品牌id 多个逗号分隔;size:500;"`
+	ValidType    int        `json:"validType" form:"validType" gorm:"column:valid_type;comment:有效期类型(0固定时间段 1领取后N天);"`
+	StartTime    *time.Time `json:"startTime" form:"startTime" gorm:"column:start_time;comment:有效期开始时间;"`
+	EndTime      *time.Time `json:"endTime" form:"endTime" gorm:"column:end_time;comment:有效期结束时间;"`
+	ValidDays    int        `json:"validDays" form:"validDays" gorm:"column:valid_days;comment:领取后有效天数;"`
+	Total        int        `json:"total" form:"total" gorm:"column:total;comment:发放总量 0不限;"`
+	Issued       int        `json:"issued" form:"issued" gorm:"column:issued;default:0;comment:已发放数量;"`
+	PerUserLimit int        `json:"perUserLimit" form:"perUserLimit" gorm:"column:per_user_limit;comment:每人限领数量 0不限;"`
+	Claimable    *int       `json:"claimable" form:"claimable" gorm:"column:claimable;default:0;comment:是否在领券中心展示(0否 1是);"`
+	RegisterGift *int       `json:"registerGift" form:"registerGift" gorm:"column:register_gift;default:0;comment:是否注册赠送(0否 1是);"`
+	Status       *int       `json:"status" form:"status" gorm:"column:status;default:1;comment:状态(0停用 1启用);"`
+	Remark       string     `json:"remark" form:"remark" gorm:"column:remark;comment:使用说明;size:255;"`
+	Claimed      int        `json:"claimed" gorm:"-"` // 当前用户已领取数量 领券中心用
+}
+
+// TableName Coupon 表名
+func (Coupon) TableName() string {
+	return "shop_coupon"
+}
+
+// UserCoupon 用户优惠券 发放时保存优惠券规则快照，修改模板不影响已发放的优惠券
+type UserCoupon struct {
+	global.DbModel
+	UserId      uint       `json:"userId" form:"userId" gorm:"column:user_id;comment:用户id;index;"`
+	CouponId    uint       `json:"couponId" form:"couponId" gorm:"column:coupon_id;comment:优惠券模板id;index;"`
+	Name        string     `json:"name" form:"name" gorm:"column:name;comment:优惠券名称;size:50;"`
+	Type        int        `json:"type" form:"type" gorm:"column:type;comment:类型(1满减券 2折扣券);"`
+	Amount      float64    `json:"amount" form:"amount" gorm:"column:amount;comment:减免金额;size:14;"`
+	Percent     int        `json:"percent" form:"percent" gorm:"column:percent;comment:折扣百分比;"`
+	MaxDiscount float64    `json:"maxDiscount" form:"maxDiscount" gorm:"column:max_discount;comment:最高优惠金额 0不限;size:14;"`
+	Threshold   float64    `json:"threshold" form:"threshold" gorm:"column:threshold;comment:使用门槛;size:14;"`
+	ScopeType   int        `json:"scopeType" form:"scopeType" gorm:"column:scope_type;comment:适用范围(0全场 1指定分类 2指定品牌);"`
+	ScopeIds    string     `json:"scopeIds" form:"scopeIds" gorm:"column:scope_ids;comment:适用分类或品牌id;size:500;"`
+	StartTime   time.Time  `json:"startTime" form:"startTime" gorm:"column:start_time;comment:有效期开始时间;"`
+	EndTime     time.Time  `json:"endTime" form:"endTime" gorm:"column:end_time;comment:有效期结束时间;index;"`
+	Source      int        `json:"source" form:"source" gorm:"column:source;comment:来源(1领券中心 2后台发放 3注册赠送);"`
+	Status      int        `json:"status" form:"status" gorm:"column:status;default:0;comment:状态(0未使用 1已使用);"`
+	OrderId     uint       `json:"orderId" form:"orderId" gorm:"column:order_id;comment:使用的订单id;"`
+	UsedTime    *time.Time `json:"usedTime" form:"usedTime" gorm:"column:used_time;comment:使用时间;"`
+	Discount    float64    `json:"discount" gorm:"-"` // 可用优惠券列表中当前购物车可优惠的金额
+}
+
+// TableName UserCoupon 表名
+func (UserCoupon) TableName() string {
+	return "shop_user_coupon"
+}
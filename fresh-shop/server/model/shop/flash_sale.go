@@ -0,0 +1,45 @@
+package shop
+
+import (
+	"fresh-shop/server/global"
+	"time"
+)
+
+// FlashSale 秒杀活动
+type FlashSale struct {
+	global.DbModel
+	Name      string           `json:"name" form:"name" gorm:"column:name;comment:活动名称;size:50;"`
+	StartTime *time.Time       `json:"startTime" form:"startTime" gorm:"column:start_time;comment:开始时间;index;"`
+	EndTime   *time.Time       `json:"endTime" form:"endTime" gorm:"column:end_time;comment:结束时间;index;"`
+	Status    *int             `json:"status" form:"status" gorm:"column:status;default:1;comment:状态(0停用 1启用);"`
+	Remark    string           `json:"remark" form:"remark" gorm:"column:remark;comment:活动说明;size:255;"`
+	Goods     []FlashSaleGoods `json:"goods"`
+	StartIn   int64            `json:"startIn" gorm:"-"` // 距离开始的秒数 已开始为 0 倒计时用
+	EndIn     int64            `json:"endIn" gorm:"-"`   // 距离结束的秒数 倒计时用
+}
+
+// TableName FlashSale 表名
+func (FlashSale) TableName() string {
+	return "shop_flash_sale"
+}
+
+// FlashSaleGoods 秒杀商品 每个商品(规格)单独的秒杀价和秒杀库存，秒杀库存从商品库存中划出
+type FlashSaleGoods struct {
+	global.DbModel
+	FlashSaleId  uint           `json:"flashSaleId" form:"flashSaleId" gorm:"column:flash_sale_id;comment:秒杀活动id;index;"`
+	GoodsId      uint           `json:"goodsId" form:"goodsId" gorm:"column:goods_id;comment:商品id;"`
+	SpecId       int            `json:"specId" form:"specId" gorm:"column:spec_id;comment:规格值id(shop_goods_spec_value.id) 单规格为0;"`
+	Price        float64        `json:"price" form:"price" gorm:"column:price;comment:秒杀价;size:14;"`
+	Stock        int            `json:"stock" form:"stock" gorm:"column:stock;comment:秒杀库存;"`
+	Sold         int            `json:"sold" form:"sold" gorm:"column:sold;default:0;comment:已售数量;"`
+	LimitPerUser int            `json:"limitPerUser" form:"limitPerUser" gorm:"column:limit_per_user;comment:每人限购数量 0不限;"`
+	Sort         int            `json:"sort" form:"sort" gorm:"column:sort;default:50;comment:排序;"`
+	Goods        Goods          `json:"goods"`
+	SpecValue    GoodsSpecValue `json:"specValue" gorm:"foreignKey:SpecId"` // 多规格商品参与秒杀的规格
+	Remain       int            `json:"remain" gorm:"-"`                    // 剩余秒杀库存
+}
+
+// TableName FlashSaleGoods 表名
+func (FlashSaleGoods) TableName() string {
+	return "shop_flash_sale_goods"
+}
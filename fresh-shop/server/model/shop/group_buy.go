@@ -0,0 +1,55 @@
+package shop
+
+import (
+	"fresh-shop/server/global"
+	"time"
+)
+
+// 拼团团队状态
+const (
+	GroupTeamOngoing = 0 // 拼团中
+	GroupTeamSuccess = 1 // 拼团成功
+	GroupTeamFailed  = 2 // 拼团失败
+)
+
+// GroupBuy 拼团活动
+type GroupBuy struct {
+	global.DbModel
+	Name      string         `json:"name" form:"name" gorm:"column:name;comment:活动名称;size:50;"`
+	GoodsId   uint           `json:"goodsId" form:"goodsId" gorm:"column:goods_id;comment:商品id;index;"`
+	SpecId    int            `json:"specId" form:"specId" gorm:"column:spec_id;comment:规格值id(shop_goods_spec_value.id) 单规格为0;"`
+	Price     float64        `json:"price" form:"price" gorm:"column:price;comment:拼团价;size:14;"`
+	GroupSize int            `json:"groupSize" form:"groupSize" gorm:"column:group_size;comment:成团人数;"`
+	TimeLimit int            `json:"timeLimit" form:"timeLimit" gorm:"column:time_limit;comment:成团时限(分钟) 开团后超时未成团自动退款;"`
+	StartTime *time.Time     `json:"startTime" form:"startTime" gorm:"column:start_time;comment:开始时间;"`
+	EndTime   *time.Time     `json:"endTime" form:"endTime" gorm:"column:end_time;comment:结束时间 结束后不能开团，已开的团在时限内仍可参团;"`
+	Status    *int           `json:"status" form:"status" gorm:"column:status;default:1;comment:状态(0停用 1启用);"`
+	Sort      int            `json:"sort" form:"sort" gorm:"column:sort;default:50;comment:排序;"`
+	Goods     Goods          `json:"goods"`
+	SpecValue GoodsSpecValue `json:"specValue" gorm:"foreignKey:SpecId"` // 多规格商品参与拼团的规格
+}
+
+// TableName GroupBuy 表名
+func (GroupBuy) TableName() string {
+	return "shop_group_buy"
+}
+
+// GroupBuyTeam 拼团团队 团长开团后其他用户参团，已支付人数达到成团人数即拼团成功
+type GroupBuyTeam struct {
+	global.DbModel
+	GroupBuyId uint       `json:"groupBuyId" form:"groupBuyId" gorm:"column:group_buy_id;comment:拼团活动id;index;"`
+	LeaderId   uint       `json:"leaderId" form:"leaderId" gorm:"column:leader_id;comment:团长用户id;"`
+	GroupSize  int        `json:"groupSize" form:"groupSize" gorm:"column:group_size;comment:成团人数;"`
+	JoinedNum  int        `json:"joinedNum" form:"joinedNum" gorm:"column:joined_num;default:0;comment:已参团人数(含未支付);"`
+	PaidNum    int        `json:"paidNum" form:"paidNum" gorm:"column:paid_num;default:0;comment:已支付人数;"`
+	Status     int        `json:"status" form:"status" gorm:"column:status;default:0;comment:状态(0拼团中 1拼团成功 2拼团失败);index;"`
+	ExpireTime time.Time  `json:"expireTime" form:"expireTime" gorm:"column:expire_time;comment:成团截止时间;index;"`
+	FinishTime *time.Time `json:"finishTime" form:"finishTime" gorm:"column:finish_time;comment:成团或失败时间;"`
+	GroupBuy   GroupBuy   `json:"groupBuy"`
+	Orders     []Order    `json:"orders" gorm:"foreignKey:GroupTeamId"` // 团员订单
+}
+
+// TableName GroupBuyTeam 表名
+func (GroupBuyTeam) TableName() string {
+	return "shop_group_buy_team"
+}
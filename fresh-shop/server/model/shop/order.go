@@ -14,25 +14,41 @@ type Order struct {
 	ShipmentName    string         `json:"shipmentName" form:"shipmentName" gorm:"column:shipment_name;comment:收货人姓名;size:20;"`
 	ShipmentMobile  string         `json:"shipmentMobile" form:"shipmentMobile" gorm:"column:shipment_mobile;comment:收货人手机号;size:11;"`
 	ShipmentAddress string         `json:"shipmentAddress" form:"shipmentAddress" gorm:"column:shipment_address;comment:收货人地址;size:255;"`
+	ShipmentLng     *float64       `json:"shipmentLng" form:"shipmentLng" gorm:"column:shipment_lng;comment:收货地址经度;"`
+	ShipmentLat     *float64       `json:"shipmentLat" form:"shipmentLat" gorm:"column:shipment_lat;comment:收货地址纬度;"`
 	ShipmentType    *int           `json:"shipmentType" form:"shipmentType" gorm:"column:shipment_type;comment:收货方式 0配送 1自提;default:0;size:1;"`
 	Num             int            `json:"num" form:"num" gorm:"column:num;comment:商品总数量;size:10;"`
 	Total           float64        `json:"total" form:"total" gorm:"column:total;comment:订单商品总金额;size:14;"`
+	CouponId        uint           `json:"couponId" form:"couponId" gorm:"column:coupon_id;comment:使用的用户优惠券id;"`
+	CouponAmount    float64        `json:"couponAmount" form:"couponAmount" gorm:"column:coupon_amount;comment:优惠券抵扣金额;size:14;"`
+	FlashGoodsId    uint           `json:"flashGoodsId" form:"flashGoodsId" gorm:"column:flash_goods_id;comment:秒杀商品id 普通订单为0;index;"`
+	GroupTeamId     uint           `json:"groupTeamId" form:"groupTeamId" gorm:"column:group_team_id;comment:拼团团队id 普通订单为0;index;"`
+	SourceOrderId   uint           `json:"sourceOrderId" form:"sourceOrderId" gorm:"column:source_order_id;comment:换货补发订单的原订单id 普通订单为0;index;"`
+	PointOffset     float64        `json:"pointOffset" form:"pointOffset" gorm:"column:point_offset;comment:抵扣使用的积分数量;size:14;"`
+	PointAmount     float64        `json:"pointAmount" form:"pointAmount" gorm:"column:point_amount;comment:积分抵扣金额;size:14;"`
 	Postage         float64        `json:"postage" form:"postage" gorm:"column:postage;comment:邮费;size:14;"`
 	Finish          float64        `json:"finish" form:"finish" gorm:"column:finish;comment:实付金额;size:14;"`
 	Payment         *int           `json:"payment" form:"payment" gorm:"column:payment;comment:支付方式(1余额 2微信 3支付宝 4积分);"`
 	PickUpNumber    int            `json:"pickUpNumber" form:"pickUpNumber" gorm:"column:pick_up_number;comment:取餐号码;size:11;"`
+	PickUpCode      string         `json:"pickUpCode" form:"pickUpCode" gorm:"column:pick_up_code;comment:取餐码(前缀+取餐号码);size:20;"`
 	PaymentInfo     string         `json:"paymentInfo" form:"paymentInfo" gorm:"column:payment_info;comment:支付详情信息;size:255;"`
 	PaymentOpenid   string         `json:"paymentOpenid" form:"paymentOpenid" gorm:"column:payment_openid;comment:支付openId;size:255;"`
 	TransationId    string         `json:"transationId" form:"transationId" gorm:"column:transation_id;comment:支付流水订单号;size:255;"`
 	Remarks         string         `json:"remarks" form:"remarks" gorm:"column:remarks;comment:留言;size:255;"`
-	Status          *int           `json:"status" form:"status" gorm:"column:status;comment:订单状态(0未付款 1已付款待发货 2 已发货 已收货);"`
+	Status          *int           `json:"status" form:"status" gorm:"column:status;comment:订单状态(0未付款 1已付款待发货 2 已发货 3已收货 4已付款待成团);"`
 	StatusCancel    *int           `json:"statusCancel" form:"statusCancel" gorm:"column:status_cancel;comment:取消状态(0未取消 1用户取消 2后台取消 3超时取消);"`
 	StatusRefund    *int           `json:"statusRefund" form:"statusRefund" gorm:"column:status_refund;comment:退款状态(0未退款 1退款中 2已退款 3退款失败);"`
+	RefundSn        string         `json:"refundSn" form:"refundSn" gorm:"column:refund_sn;comment:当前退款单号，退款结果按此单号匹配;size:64;"`
+	RefundApplied   *int           `json:"refundApplied" form:"refundApplied" gorm:"column:refund_applied;comment:当前退款是否已被支付渠道受理(0未受理 1已受理);default:0;"`
 	PayTime         *time.Time     `json:"payTime" form:"payTime" gorm:"column:pay_time;comment:支付时间;"`
 	ShipmentTime    *time.Time     `json:"shipmentTime" form:"shipmentTime" gorm:"column:shipment_time;comment:发货时间;"`
 	ReceiveTime     *time.Time     `json:"receiveTime" form:"receiveTime" gorm:"column:receive_time;comment:收货时间;"`
 	CancelTime      *time.Time     `json:"cancelTime" form:"cancelTime" gorm:"column:cancel_time;comment:取消时间;"`
 	GiftPoints      float64        `json:"giftPoints" form:"giftPoints" gorm:"column:gift_points;comment:赠送积分数量;size:10;"`
+	SlotId          uint           `json:"slotId" form:"slotId" gorm:"column:slot_id;comment:预约配送时段id;"`
+	SlotDay         string         `json:"slotDay" form:"slotDay" gorm:"column:slot_day;comment:预约配送日期(2006-01-02);size:10;"`
+	SlotStart       *time.Time     `json:"slotStart" form:"slotStart" gorm:"column:slot_start;comment:预约配送开始时间;"`
+	SlotEnd         *time.Time     `json:"slotEnd" form:"slotEnd" gorm:"column:slot_end;comment:预约配送结束时间;"`
 	AddressId       int            `json:"addressId" form:"addressId" gorm:"-"`       // 收货地址id
 	OrderDetails    []OrderDetails `json:"details"`                                   // 订单详情
 	OrderReturn     OrderReturn    `json:"return"`                                    // 订单售后
@@ -10,14 +10,25 @@ import (
 type OrderDelivery struct {
 	global.DbModel
 	OrderId       *int                  `json:"orderId" form:"orderId" gorm:"column:order_id;comment:订单Id;size:20;"`
+	Type          *int                  `json:"type" form:"type" gorm:"column:type;comment:配送类型(0订单配送 1售后取回);default:0;"`
+	ReturnId      uint                  `json:"returnId" form:"returnId" gorm:"column:return_id;comment:售后取回关联的售后id;index;"`
 	ScheduledTime time.Time             `json:"scheduledTime" form:"scheduledTime" gorm:"column:scheduled_time;comment:预计到达时间;"`
 	DeliverName   string                `json:"deliverName" form:"deliverName" gorm:"column:deliver_name;comment:送货人姓名;"`
 	DeliveryId    *int                  `json:"deliveryId" form:"deliveryId" gorm:"column:delivery_id;comment:送货人ID;size:11;"`
 	DeliverMobile string                `json:"deliverMobile" form:"deliverMobile" gorm:"column:deliver_mobile;comment:送货人联系电话;size:11;"`
-	ReceiptTime   *time.Time            `json:"receiptTime" form:"receiptTime" gorm:"column:receipt_time;comment:收货时间;"`
+	ReceiptTime   *time.Time            `json:"receiptTime" form:"receiptTime" gorm:"column:receipt_time;comment:收货时间(售后取回为送回门店时间);"`
+	RouteSeq      int                   `json:"routeSeq" form:"routeSeq" gorm:"column:route_seq;comment:配送路线顺序(批量派单生成);"`
+	PickUpTime    *time.Time            `json:"pickUpTime" form:"pickUpTime" gorm:"column:pick_up_time;comment:配送员取货时间(售后取回为上门取件时间);"`
+	ProofImages   string                `json:"proofImages" form:"proofImages" gorm:"column:proof_images;comment:送达凭证图片(多张逗号分隔);size:1000;"`
 	UserDelivery  business.UserDelivery `json:"user" gorm:"foreignKey:id;references:delivery_id"`
 }
 
+// 配送类型
+const (
+	DeliveryTypeShip   = 0 // 订单配送
+	DeliveryTypeReturn = 1 // 售后商品上门取回
+)
+
 // TableName OrderDelivery 表名
 func (OrderDelivery) TableName() string {
 	return "shop_order_delivery"
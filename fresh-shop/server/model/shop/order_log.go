@@ -0,0 +1,34 @@
+package shop
+
+import (
+	"fresh-shop/server/global"
+)
+
+// 订单操作来源
+const (
+	OrderSourceUser     = 1 // 用户
+	OrderSourceAdmin    = 2 // 后台
+	OrderSourceTimer    = 3 // 定时任务
+	OrderSourceCallback = 4 // 支付回调
+	OrderSourceRider    = 5 // 配送员
+)
+
+// OrderLog 订单状态流转记录
+type OrderLog struct {
+	global.DbModel
+	OrderId      uint   `json:"orderId" form:"orderId" gorm:"column:order_id;comment:订单Id;index;"`
+	Event        string `json:"event" form:"event" gorm:"column:event;comment:操作事件;size:30;"`
+	Title        string `json:"title" form:"title" gorm:"column:title;comment:操作说明;size:50;"`
+	Status       int    `json:"status" form:"status" gorm:"column:status;comment:操作后订单状态;"`
+	StatusCancel int    `json:"statusCancel" form:"statusCancel" gorm:"column:status_cancel;comment:操作后取消状态;"`
+	StatusRefund int    `json:"statusRefund" form:"statusRefund" gorm:"column:status_refund;comment:操作后退款状态;"`
+	Source       int    `json:"source" form:"source" gorm:"column:source;comment:操作来源(1用户 2后台 3定时任务 4支付回调 5配送员);"`
+	OperatorId   uint   `json:"operatorId" form:"operatorId" gorm:"column:operator_id;comment:操作人id;"`
+	Operator     string `json:"operator" form:"operator" gorm:"column:operator;comment:操作人;size:50;"`
+	Remark       string `json:"remark" form:"remark" gorm:"column:remark;comment:备注;size:255;"`
+}
+
+// TableName OrderLog 表名
+func (OrderLog) TableName() string {
+	return "shop_order_log"
+}
@@ -8,17 +8,32 @@ import (
 // OrderReturn 结构体
 type OrderReturn struct {
 	global.DbModel
-	UserId       *int               `json:"userId" form:"userId" gorm:"column:user_id;comment:用户id;size:20;"`
-	OrderId      *int               `json:"orderId" form:"orderId" gorm:"column:order_id;comment:订单Id;size:20;"`
-	Reason       string             `json:"reason" form:"reason" gorm:"column:reason;comment:申请原因;size:255;"`
-	Amount       *float64           `json:"amount" form:"amount" gorm:"column:amount;comment:退款金额;size:14;"`
-	Status       *int               `json:"status" form:"status" gorm:"column:status;comment:售后状态(-1 拒绝售后 0未处理 1已退款);"`
-	RefundStatus *int               `json:"refundStatus" form:"refundStatus" gorm:"column:refund_status;comment:退款状态;"`
-	Reply        string             `json:"reply" form:"reply" gorm:"column:reply;comment:售后说明;size:255;"`
-	ProcessTime  *time.Time         `json:"processTime" form:"processTime" gorm:"column:process_time;comment:售后处理时间;"`
-	Details      OrderReturnDetails `json:"details" gorm:"foreignKey:return_id"`
+	UserId          *int                 `json:"userId" form:"userId" gorm:"column:user_id;comment:用户id;size:20;"`
+	OrderId         *int                 `json:"orderId" form:"orderId" gorm:"column:order_id;comment:订单Id;size:20;"`
+	Type            *int                 `json:"type" form:"type" gorm:"column:type;comment:售后类型(0退货退款 1换货);default:0;"`
+	Reason          string               `json:"reason" form:"reason" gorm:"column:reason;comment:申请原因;size:255;"`
+	Images          string               `json:"images" form:"images" gorm:"column:images;comment:凭证图片(多张逗号分隔);size:1000;"`
+	Amount          *float64             `json:"amount" form:"amount" gorm:"column:amount;comment:退款金额;size:14;"`
+	Points          float64              `json:"points" form:"points" gorm:"column:points;comment:退回的抵扣积分;size:14;"`
+	GiftPoints      float64              `json:"giftPoints" form:"giftPoints" gorm:"column:gift_points;comment:扣回的赠送积分;size:14;"`
+	PickUp          int                  `json:"pickUp" form:"pickUp" gorm:"column:pick_up;comment:是否由配送员上门取回(0否 1是);"`
+	Restock         int                  `json:"restock" form:"restock" gorm:"column:restock;comment:是否退回库存(0否 1是);"`
+	Status          *int                 `json:"status" form:"status" gorm:"column:status;comment:售后状态(-1 拒绝售后 0未处理 1已完成);"`
+	RefundStatus    *int                 `json:"refundStatus" form:"refundStatus" gorm:"column:refund_status;comment:退款状态(0未退款 1退款中 2已退款 3退款失败);"`
+	RefundSn        string               `json:"refundSn" form:"refundSn" gorm:"column:refund_sn;comment:退款单号;size:50;"`
+	Reply           string               `json:"reply" form:"reply" gorm:"column:reply;comment:售后说明;size:255;"`
+	ProcessTime     *time.Time           `json:"processTime" form:"processTime" gorm:"column:process_time;comment:售后处理时间;"`
+	ExchangeOrderId uint                 `json:"exchangeOrderId" form:"exchangeOrderId" gorm:"column:exchange_order_id;comment:换货补发订单id;"`
+	Details         []OrderReturnDetails `json:"details" gorm:"foreignKey:return_id"`
+	Delivery        OrderDelivery        `json:"delivery" gorm:"foreignKey:return_id"` // 上门取回的配送信息
 }
 
+// 售后类型
+const (
+	ReturnTypeRefund   = 0 // 退货退款
+	ReturnTypeExchange = 1 // 换货 同意后生成零元补发订单
+)
+
 // TableName OrderReturn 表名
 func (OrderReturn) TableName() string {
 	return "shop_order_return"
@@ -0,0 +1,44 @@
+package shop
+
+import (
+	"fresh-shop/server/global"
+)
+
+// 通知类型
+const (
+	NotifyTypePay    = 1 // 支付结果通知
+	NotifyTypeRefund = 2 // 退款结果通知
+	NotifyTypeQuery  = 3 // 主动查询到的支付结果
+)
+
+// 通知处理状态
+const (
+	NotifyStatusReceived  = 0 // 已接收
+	NotifyStatusProcessed = 1 // 处理成功
+	NotifyStatusFailed    = 2 // 处理失败，等待渠道重新通知
+	NotifyStatusDuplicate = 3 // 重复通知，未重复处理
+	NotifyStatusAnomaly   = 4 // 异常待人工审核，订单未标记为已支付
+	NotifyStatusIgnored   = 5 // 非支付成功的通知，无需处理
+)
+
+// PaymentNotifyLog 支付渠道回调通知原文记录
+type PaymentNotifyLog struct {
+	global.DbModel
+	Channel       string  `json:"channel" form:"channel" gorm:"size:20;comment:支付渠道 wechat alipay sandbox"`
+	NotifyType    int     `json:"notifyType" form:"notifyType" gorm:"comment:通知类型 1支付 2退款 3主动查询"`
+	OrderSn       string  `json:"orderSn" form:"orderSn" gorm:"size:50;index;comment:订单号"`
+	TransactionId string  `json:"transactionId" form:"transactionId" gorm:"size:64;index;comment:渠道交易号"`
+	RefundSn      string  `json:"refundSn" form:"refundSn" gorm:"size:64;comment:退款单号"`
+	Amount        float64 `json:"amount" form:"amount" gorm:"comment:通知金额"`
+	Headers       string  `json:"headers" form:"headers" gorm:"type:text;comment:签名相关请求头"`
+	RawBody       string  `json:"rawBody" form:"rawBody" gorm:"type:text;comment:通知原文"`
+	Status        int     `json:"status" form:"status" gorm:"index;default:0;comment:处理状态 0已接收 1处理成功 2处理失败 3重复通知 4异常待审核 5无需处理"`
+	Remark        string  `json:"remark" form:"remark" gorm:"size:255;comment:处理结果说明"`
+	Handled       int     `json:"handled" form:"handled" gorm:"default:0;comment:异常是否已人工处理 0未处理 1已处理"`
+	HandleRemark  string  `json:"handleRemark" form:"handleRemark" gorm:"size:255;comment:人工处理说明"`
+}
+
+// TableName PaymentNotifyLog 表名
+func (PaymentNotifyLog) TableName() string {
+	return "payment_notify_log"
+}
@@ -0,0 +1,50 @@
+package shop
+
+import (
+	"fresh-shop/server/global"
+)
+
+// 对账差异类型
+const (
+	ReconcileDiffAmount       = 1 // 金额不一致
+	ReconcileDiffStatus       = 2 // 状态不一致，渠道已支付或已退款而平台订单未同步
+	ReconcileDiffMissingOrder = 3 // 渠道有交易，平台没有对应订单
+	ReconcileDiffMissingBill  = 4 // 平台订单已支付，渠道账单中没有交易
+)
+
+// PaymentReconcile 支付渠道账单对账记录，每个渠道每天一条
+type PaymentReconcile struct {
+	global.DbModel
+	BillDate    string  `json:"billDate" form:"billDate" gorm:"size:10;uniqueIndex:idx_bill_date_payment;comment:账单日期(2006-01-02)"`
+	Payment     int     `json:"payment" form:"payment" gorm:"uniqueIndex:idx_bill_date_payment;comment:支付方式(2微信 3支付宝)"`
+	BillCount   int     `json:"billCount" form:"billCount" gorm:"comment:账单交易笔数"`
+	BillAmount  float64 `json:"billAmount" form:"billAmount" gorm:"comment:账单支付总金额"`
+	OrderCount  int     `json:"orderCount" form:"orderCount" gorm:"comment:平台支付订单笔数"`
+	OrderAmount float64 `json:"orderAmount" form:"orderAmount" gorm:"comment:平台支付订单总金额"`
+	DiffCount   int     `json:"diffCount" form:"diffCount" gorm:"comment:差异笔数"`
+}
+
+// TableName PaymentReconcile 表名
+func (PaymentReconcile) TableName() string {
+	return "payment_reconcile"
+}
+
+// PaymentReconcileDiff 对账差异明细
+type PaymentReconcileDiff struct {
+	global.DbModel
+	ReconcileId   uint    `json:"reconcileId" form:"reconcileId" gorm:"index;comment:对账记录id"`
+	DiffType      int     `json:"diffType" form:"diffType" gorm:"comment:差异类型 1金额不一致 2状态不一致 3平台缺单 4渠道缺单"`
+	OrderId       uint    `json:"orderId" form:"orderId" gorm:"comment:订单id"`
+	OrderSn       string  `json:"orderSn" form:"orderSn" gorm:"size:50;index;comment:订单号"`
+	TransactionId string  `json:"transactionId" form:"transactionId" gorm:"size:64;comment:渠道交易号"`
+	BillState     string  `json:"billState" form:"billState" gorm:"size:20;comment:账单交易状态"`
+	BillAmount    float64 `json:"billAmount" form:"billAmount" gorm:"comment:账单金额"`
+	OrderAmount   float64 `json:"orderAmount" form:"orderAmount" gorm:"comment:订单金额"`
+	Remark        string  `json:"remark" form:"remark" gorm:"size:255;comment:差异说明"`
+	Handled       int     `json:"handled" form:"handled" gorm:"default:0;comment:是否已处理 0未处理 1已处理"`
+}
+
+// TableName PaymentReconcileDiff 表名
+func (PaymentReconcileDiff) TableName() string {
+	return "payment_reconcile_diff"
+}
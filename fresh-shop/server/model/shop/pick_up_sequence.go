@@ -0,0 +1,17 @@
+package shop
+
+import (
+	"fresh-shop/server/global"
+)
+
+// PickUpSequence 自提订单取餐号码序列，每天一行
+type PickUpSequence struct {
+	global.DbModel
+	Day   string `json:"day" form:"day" gorm:"column:day;comment:日期(20060102);size:8;uniqueIndex;"`
+	Value int    `json:"value" form:"value" gorm:"column:value;comment:当日最后一个取餐号码;"`
+}
+
+// TableName PickUpSequence 表名
+func (PickUpSequence) TableName() string {
+	return "shop_pick_up_sequence"
+}
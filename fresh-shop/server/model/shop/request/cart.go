@@ -12,3 +12,11 @@ type CartSearch struct {
 	EndCreatedAt   *time.Time `json:"endCreatedAt" form:"endCreatedAt"`
 	request.PageInfo
 }
+
+// PostageQuoteReq 运费试算
+type PostageQuoteReq struct {
+	AddressId    int     `json:"addressId" form:"addressId"`       // 收货地址id
+	ShipmentType int     `json:"shipmentType" form:"shipmentType"` // 收货方式 0配送 1自提
+	CouponId     uint    `json:"couponId" form:"couponId"`         // 使用的用户优惠券id
+	PointOffset  float64 `json:"pointOffset" form:"pointOffset"`   // 抵扣使用的积分数量
+}
@@ -0,0 +1,31 @@
+package request
+
+import (
+	"fresh-shop/server/model/common/request"
+	"fresh-shop/server/model/shop"
+	"time"
+)
+
+type CouponSearch struct {
+	shop.Coupon
+	StartCreatedAt *time.Time `json:"startCreatedAt" form:"startCreatedAt"`
+	EndCreatedAt   *time.Time `json:"endCreatedAt" form:"endCreatedAt"`
+	request.PageInfo
+}
+
+// IssueCouponReq 后台发放优惠券
+type IssueCouponReq struct {
+	CouponId uint   `json:"couponId" form:"couponId"` // 优惠券模板id
+	UserIds  []uint `json:"userIds" form:"userIds"`   // 用户id
+}
+
+// ClaimCouponReq 领取优惠券
+type ClaimCouponReq struct {
+	CouponId uint `json:"couponId" form:"couponId"` // 优惠券模板id
+}
+
+// UserCouponSearch 用户优惠券查询
+type UserCouponSearch struct {
+	Status int `json:"status" form:"status"` // 0未使用 1已使用 2已过期
+	request.PageInfo
+}
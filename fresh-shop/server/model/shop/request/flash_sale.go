@@ -0,0 +1,31 @@
+package request
+
+import (
+	"fresh-shop/server/model/common/request"
+	"fresh-shop/server/model/shop"
+	"time"
+)
+
+type FlashSaleSearch struct {
+	shop.FlashSale
+	StartCreatedAt *time.Time `json:"startCreatedAt" form:"startCreatedAt"`
+	EndCreatedAt   *time.Time `json:"endCreatedAt" form:"endCreatedAt"`
+	request.PageInfo
+}
+
+// GoodsOrderReq 单个商品的活动订单(秒杀、拼团)下单参数
+type GoodsOrderReq struct {
+	Num          int    `json:"num" form:"num"`                   // 购买数量
+	AddressId    int    `json:"addressId" form:"addressId"`       // 收货地址id
+	ShipmentType int    `json:"shipmentType" form:"shipmentType"` // 收货方式 0配送 1自提
+	SlotId       uint   `json:"slotId" form:"slotId"`             // 预约配送时段id
+	SlotDay      string `json:"slotDay" form:"slotDay"`           // 预约配送日期
+	Remarks      string `json:"remarks" form:"remarks"`           // 留言
+	Payment      *int   `json:"payment" form:"payment"`           // 支付方式 1余额 2微信 3支付宝 不传为微信支付
+}
+
+// FlashOrderReq 秒杀下单
+type FlashOrderReq struct {
+	FlashGoodsId uint `json:"flashGoodsId" form:"flashGoodsId"` // 秒杀商品id
+	GoodsOrderReq
+}
@@ -0,0 +1,28 @@
+package request
+
+import (
+	"fresh-shop/server/model/common/request"
+	"fresh-shop/server/model/shop"
+	"time"
+)
+
+type GroupBuySearch struct {
+	shop.GroupBuy
+	StartCreatedAt *time.Time `json:"startCreatedAt" form:"startCreatedAt"`
+	EndCreatedAt   *time.Time `json:"endCreatedAt" form:"endCreatedAt"`
+	request.PageInfo
+}
+
+// GroupBuyTeamSearch 拼团团队查询
+type GroupBuyTeamSearch struct {
+	GroupBuyId uint `json:"groupBuyId" form:"groupBuyId"` // 拼团活动id
+	Status     *int `json:"status" form:"status"`         // 0拼团中 1拼团成功 2拼团失败
+	request.PageInfo
+}
+
+// GroupOrderReq 拼团下单 TeamId 为 0 时开团，否则参加该团
+type GroupOrderReq struct {
+	GroupBuyId uint `json:"groupBuyId" form:"groupBuyId"` // 拼团活动id
+	TeamId     uint `json:"teamId" form:"teamId"`         // 参加的拼团团队id
+	GoodsOrderReq
+}
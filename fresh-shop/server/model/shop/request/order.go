@@ -18,3 +18,9 @@ type OrderSearch struct{
     EndCancelTime  *time.Time  `json:"endCancelTime" form:"endCancelTime"`
     request.PageInfo
 }
+
+// BalancePayReq 余额支付订单
+type BalancePayReq struct {
+	OrderId      uint   `json:"orderId" form:"orderId"`
+	SafePassword string `json:"safePassword" form:"safePassword"` // 安全密码
+}
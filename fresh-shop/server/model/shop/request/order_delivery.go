@@ -16,3 +16,10 @@ type OrderDeliverySearch struct{
     EndReceiptTime  *time.Time  `json:"endReceiptTime" form:"endReceiptTime"`
     request.PageInfo
 }
+
+// BatchDispatchReq 批量派单
+type BatchDispatchReq struct {
+	OrderIds  []uint     `json:"orderIds" form:"orderIds"`   // 待发货的配送订单id
+	RiderIds  []uint     `json:"riderIds" form:"riderIds"`   // 配送员id
+	StartTime *time.Time `json:"startTime" form:"startTime"` // 出发时间 不传为当前时间
+}
@@ -14,3 +14,29 @@ type OrderReturnSearch struct{
     EndProcessTime  *time.Time  `json:"endProcessTime" form:"endProcessTime"`
     request.PageInfo
 }
+
+// OrderReturnApply 用户申请售后
+type OrderReturnApply struct {
+	OrderId uint                     `json:"orderId" form:"orderId"`
+	Type    int                      `json:"type" form:"type"` // 售后类型 0退货退款 1换货
+	Reason  string                   `json:"reason" form:"reason"`
+	Images  string                   `json:"images" form:"images"` // 凭证图片(多张逗号分隔)
+	PickUp  bool                     `json:"pickUp" form:"pickUp"` // 是否由配送员上门取回售后商品
+	Details []OrderReturnApplyDetail `json:"details" form:"details"`
+}
+
+// OrderReturnApplyDetail 申请售后的商品及数量
+type OrderReturnApplyDetail struct {
+	OrderDetailId uint `json:"orderDetailId" form:"orderDetailId"`
+	Num           int  `json:"num" form:"num"`
+}
+
+// OrderReturnReview 审核售后 Status 1 同意 -1 拒绝，退货退款同意后退款，换货同意后生成补发订单
+type OrderReturnReview struct {
+	ID            uint       `json:"ID" form:"ID"`
+	Status        int        `json:"status" form:"status"`
+	Reply         string     `json:"reply" form:"reply"`
+	Restock       bool       `json:"restock" form:"restock"`             // 同意售后时是否退回库存
+	RiderId       uint       `json:"riderId" form:"riderId"`             // 上门取回的配送员id
+	ScheduledTime *time.Time `json:"scheduledTime" form:"scheduledTime"` // 预计上门取件时间 不传为当前时间
+}
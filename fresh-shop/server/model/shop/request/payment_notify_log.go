@@ -0,0 +1,22 @@
+package request
+
+import (
+	"fresh-shop/server/model/common/request"
+)
+
+// PaymentNotifyLogSearch 支付通知记录查询
+type PaymentNotifyLogSearch struct {
+	Channel       string `json:"channel" form:"channel"`
+	NotifyType    int    `json:"notifyType" form:"notifyType"`
+	OrderSn       string `json:"orderSn" form:"orderSn"`
+	TransactionId string `json:"transactionId" form:"transactionId"`
+	Status        *int   `json:"status" form:"status"`
+	Handled       *int   `json:"handled" form:"handled"`
+	request.PageInfo
+}
+
+// HandleNotifyLogReq 人工处理异常通知
+type HandleNotifyLogReq struct {
+	ID           uint   `json:"id" form:"id"`
+	HandleRemark string `json:"handleRemark" form:"handleRemark"`
+}
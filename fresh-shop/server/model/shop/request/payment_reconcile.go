@@ -0,0 +1,28 @@
+package request
+
+import (
+	"fresh-shop/server/model/common/request"
+)
+
+// PaymentReconcileSearch 对账记录查询
+type PaymentReconcileSearch struct {
+	Payment        int    `json:"payment" form:"payment"`
+	StartBillDate  string `json:"startBillDate" form:"startBillDate"`
+	EndBillDate    string `json:"endBillDate" form:"endBillDate"`
+	OnlyDifference bool   `json:"onlyDifference" form:"onlyDifference"` // 只看有差异的记录
+	request.PageInfo
+}
+
+// PaymentReconcileDiffSearch 对账差异明细查询
+type PaymentReconcileDiffSearch struct {
+	ReconcileId uint   `json:"reconcileId" form:"reconcileId"`
+	OrderSn     string `json:"orderSn" form:"orderSn"`
+	DiffType    int    `json:"diffType" form:"diffType"`
+	Handled     *int   `json:"handled" form:"handled"`
+	request.PageInfo
+}
+
+// ReconcileReq 手动对账，上传账单文件时使用文件内容，否则从支付渠道下载
+type ReconcileReq struct {
+	BillDate string `json:"billDate" form:"billDate"` // 账单日期 2006-01-02
+}
@@ -0,0 +1,21 @@
+package request
+
+import (
+	"fresh-shop/server/model/common/request"
+)
+
+// RiderOrderSearch 配送员查询配送订单
+type RiderOrderSearch struct {
+	Delivered *int `json:"delivered" form:"delivered"` // 是否已送达 0待配送 1已送达 不传查询全部
+	request.PageInfo
+}
+
+// RiderOrderReq 配送员操作订单
+type RiderOrderReq struct {
+	OrderId uint `json:"orderId" form:"orderId"` // 订单id
+}
+
+// RiderReturnReq 配送员操作售后取回
+type RiderReturnReq struct {
+	ReturnId uint `json:"returnId" form:"returnId"` // 售后id
+}
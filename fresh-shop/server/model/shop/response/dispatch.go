@@ -0,0 +1,23 @@
+package response
+
+import (
+	"time"
+)
+
+// DispatchRoute 配送员的配送路线
+type DispatchRoute struct {
+	RiderId   uint           `json:"riderId"`   // 配送员id
+	RiderName string         `json:"riderName"` // 配送员姓名
+	Distance  float64        `json:"distance"`  // 路线总距离(km) 不含无坐标的订单
+	Stops     []DispatchStop `json:"stops"`     // 按配送顺序排列的订单
+}
+
+// DispatchStop 配送路线中的订单
+type DispatchStop struct {
+	Seq           int       `json:"seq"`           // 配送顺序 从 1 开始
+	OrderId       uint      `json:"orderId"`       // 订单id
+	OrderSn       string    `json:"orderSn"`       // 订单编号
+	Address       string    `json:"address"`       // 收货地址
+	Located       bool      `json:"located"`       // 收货地址是否有坐标 无坐标的订单排在路线最后
+	ScheduledTime time.Time `json:"scheduledTime"` // 预计到达时间
+}
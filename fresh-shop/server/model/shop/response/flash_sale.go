@@ -0,0 +1,21 @@
+package response
+
+import (
+	"fresh-shop/server/model/shop"
+	"time"
+)
+
+// FlashSaleCountdown 秒杀活动倒计时
+type FlashSaleCountdown struct {
+	Now        time.Time        `json:"now"`        // 服务器当前时间 前端用于校准本地时间
+	FlashSales []shop.FlashSale `json:"flashSales"` // 进行中和即将开始的活动
+}
+
+// FlashOrderResult 秒杀排队下单结果
+type FlashOrderResult struct {
+	Token   string `json:"token"`   // 排队凭证
+	Status  int    `json:"status"`  // 0排队中 1下单成功 2下单失败
+	OrderId uint   `json:"orderId"` // 下单成功的订单id 使用 order/orderPay 发起支付
+	OrderSn string `json:"orderSn"` // 订单编号
+	Msg     string `json:"msg"`     // 失败原因
+}
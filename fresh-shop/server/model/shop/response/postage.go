@@ -0,0 +1,13 @@
+package response
+
+// PostageQuoteResp 运费试算结果
+type PostageQuoteResp struct {
+	Total       float64 `json:"total"`       // 商品总金额
+	Coupon      float64 `json:"coupon"`      // 优惠券抵扣金额
+	Weight      int     `json:"weight"`      // 商品总重量(g)
+	Distance    float64 `json:"distance"`    // 配送距离(km) -1表示未知
+	Postage     float64 `json:"postage"`     // 运费
+	PointOffset float64 `json:"pointOffset"` // 实际使用的抵扣积分
+	PointAmount float64 `json:"pointAmount"` // 积分抵扣金额
+	PayAmount   float64 `json:"payAmount"`   // 应付金额
+}
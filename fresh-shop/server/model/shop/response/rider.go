@@ -0,0 +1,11 @@
+package response
+
+import (
+	"fresh-shop/server/model/shop"
+)
+
+// RiderOrder 配送员配送订单
+type RiderOrder struct {
+	Delivery shop.OrderDelivery `json:"delivery"` // 发货信息
+	Order    shop.Order         `json:"order"`    // 订单信息(含订单详情)
+}
@@ -16,6 +16,7 @@ type Register struct {
 	AuthorityIds []uint `json:"authorityIds" swaggertype:"string" file:"[]uint 角色id"`
 	Phone        string `json:"phone" file:"电话号码"`
 	Email        string `json:"email" file:"电子邮箱"`
+	InviteCode   string `json:"inviteCode" file:"邀请码"`
 }
 
 // User wechat structure
@@ -33,6 +34,12 @@ type ChangePasswordReq struct {
 	NewPassword string `json:"newPassword"` // 新密码
 }
 
+// SetSafePasswordReq 设置安全密码，未设置过安全密码时不需要原安全密码
+type SetSafePasswordReq struct {
+	SafePassword    string `json:"safePassword"`    // 原安全密码
+	NewSafePassword string `json:"newSafePassword"` // 新安全密码
+}
+
 // Modify  user's auth structure
 type SetUserAuth struct {
 	AuthorityId uint `json:"authorityId"` // 角色ID
@@ -12,6 +12,7 @@ type SysUser struct {
 	OpenId         string         `json:"openId" gorm:"index;comment:OpenId"`                                                              // OpenId
 	Username       string         `json:"userName" gorm:"index;comment:用户登录名"`                                                        // 用户登录名
 	InvitationCode string         `json:"invitationCode" gorm:"index;comment:推荐码"`                                                      // 推荐码
+	InviterId      uint           `json:"inviterId" gorm:"index;comment:邀请人id"`                                                         // 邀请人id
 	Password       string         `json:"-"  gorm:"comment:用户登录密码"`                                                                  // 用户登录密码
 	SafePassword   string         `json:"-"  gorm:"comment:用户安全密码"`                                                                  // 用户安全密码
 	NickName       string         `json:"nickName" gorm:"default:系统用户;comment:用户昵称"`                                               // 用户昵称
@@ -11,6 +11,7 @@ type LoginReq struct {
 	EncryptedData string `json:"encryptedData" form:"encryptedData"`
 	Iv            string `json:"iv" form:"iv"`
 	OpenId        string `json:"openid" form:"openid"`
+	InviteCode    string `json:"inviteCode" form:"inviteCode"` // 邀请码
 }
 
 type WechatPayReq struct {
@@ -2,11 +2,10 @@ package response
 
 import (
 	"fresh-shop/server/model/shop"
-	orderPay "github.com/silenceper/wechat/v2/pay/order"
 )
 
 // CreateOrderResp 创建订单响应
 type CreateOrderResp struct {
-	Pay   orderPay.Config `json:"pay" form:"pay"`
-	Order shop.Order      `json:"order" form:"order"`
+	Pay   interface{} `json:"pay" form:"pay"` // 客户端调起支付所需参数，随支付方式不同而不同
+	Order shop.Order  `json:"order" form:"order"`
 }
@@ -6,4 +6,5 @@ type RouterGroup struct {
 	SysRechargeRouter
 	UserFinanceTypeRouter
 	UserFinanceCashRouter
+	UserCommissionRouter
 }
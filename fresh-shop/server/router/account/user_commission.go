@@ -0,0 +1,21 @@
+package account
+
+import (
+	"fresh-shop/server/api/v1"
+	"github.com/gin-gonic/gin"
+)
+
+type UserCommissionRouter struct {
+}
+
+// InitUserCommissionRouter 初始化 UserCommission 路由信息
+func (s *UserCommissionRouter) InitUserCommissionRouter(Router *gin.RouterGroup) {
+	userCommissionRouterWithoutRecord := Router.Group("userCommission")
+	var userCommissionApi = v1.ApiGroupApp.AccountApiGroup.UserCommissionApi
+	{
+		userCommissionRouterWithoutRecord.GET("getUserCommissionList", userCommissionApi.GetUserCommissionList) // 获取分销佣金列表
+		userCommissionRouterWithoutRecord.GET("getMyCommissionList", userCommissionApi.GetMyCommissionList)     // 我的佣金明细
+		userCommissionRouterWithoutRecord.GET("getMyTeam", userCommissionApi.GetMyTeam)                         // 我的团队统计
+		userCommissionRouterWithoutRecord.GET("getMyTeamMembers", userCommissionApi.GetMyTeamMembers)           // 我的直推成员
+	}
+}
@@ -0,0 +1,28 @@
+package business
+
+import (
+	"fresh-shop/server/api/v1"
+	"fresh-shop/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type DeliverySlotRouter struct {
+}
+
+// InitDeliverySlotRouter 初始化 DeliverySlot 路由信息
+func (s *DeliverySlotRouter) InitDeliverySlotRouter(Router *gin.RouterGroup) {
+	deliverySlotRouter := Router.Group("deliverySlot").Use(middleware.OperationRecord())
+	deliverySlotRouterWithoutRecord := Router.Group("deliverySlot")
+	var deliverySlotApi = v1.ApiGroupApp.BusinessApiGroup.DeliverySlotApi
+	{
+		deliverySlotRouter.POST("createDeliverySlot", deliverySlotApi.CreateDeliverySlot)             // 新建DeliverySlot
+		deliverySlotRouter.DELETE("deleteDeliverySlot", deliverySlotApi.DeleteDeliverySlot)           // 删除DeliverySlot
+		deliverySlotRouter.DELETE("deleteDeliverySlotByIds", deliverySlotApi.DeleteDeliverySlotByIds) // 批量删除DeliverySlot
+		deliverySlotRouter.PUT("updateDeliverySlot", deliverySlotApi.UpdateDeliverySlot)              // 更新DeliverySlot
+	}
+	{
+		deliverySlotRouterWithoutRecord.GET("findDeliverySlot", deliverySlotApi.FindDeliverySlot)                   // 根据ID获取DeliverySlot
+		deliverySlotRouterWithoutRecord.GET("getDeliverySlotList", deliverySlotApi.GetDeliverySlotList)             // 获取DeliverySlot列表
+		deliverySlotRouterWithoutRecord.GET("getAvailableDeliverySlots", deliverySlotApi.GetAvailableDeliverySlots) // 获取收货地址可预约的配送时段
+	}
+}
@@ -3,4 +3,6 @@ package business
 type RouterGroup struct {
 	BannerRouter
 	UserDeliveryRouter
+	DeliveryZoneRouter
+	DeliverySlotRouter
 }
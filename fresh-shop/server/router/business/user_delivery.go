@@ -24,5 +24,6 @@ func (s *UserDeliveryRouter) InitUserDeliveryRouter(Router *gin.RouterGroup) {
 		userDeliveryRouterWithoutRecord.GET("findUserDelivery", userDeliveryApi.FindUserDelivery)             // 根据ID获取UserDelivery
 		userDeliveryRouterWithoutRecord.GET("getUserDeliveryList", userDeliveryApi.GetUserDeliveryList)       // 获取UserDelivery列表
 		userDeliveryRouterWithoutRecord.GET("getUserDeliveryAllList", userDeliveryApi.GetUserDeliveryAllList) // 获取所有 UserDelivery列表
+		userDeliveryRouterWithoutRecord.PUT("setRiderAccount", userDeliveryApi.SetRiderAccount)               // 设置配送员登录账号 包含密码不记录操作日志
 	}
 }
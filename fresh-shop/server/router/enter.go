@@ -4,6 +4,7 @@ import (
 	"fresh-shop/server/router/account"
 	"fresh-shop/server/router/business"
 	"fresh-shop/server/router/file"
+	"fresh-shop/server/router/payment"
 	"fresh-shop/server/router/shop"
 	"fresh-shop/server/router/system"
 	"fresh-shop/server/router/wechat"
@@ -16,6 +17,7 @@ type RouterGroup struct {
 	Shop     shop.RouterGroup
 	Business business.RouterGroup
 	Wechat   wechat.RouterGroup
+	Payment  payment.RouterGroup
 }
 
 var RouterGroupApp = new(RouterGroup)
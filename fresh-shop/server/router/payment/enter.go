@@ -0,0 +1,5 @@
+package payment
+
+type RouterGroup struct {
+	PaymentRouter
+}
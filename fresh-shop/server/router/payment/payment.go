@@ -0,0 +1,28 @@
+package payment
+
+import (
+	v1 "fresh-shop/server/api/v1"
+	"github.com/gin-gonic/gin"
+)
+
+type PaymentRouter struct {
+}
+
+// InitPaymentRouter 初始化 PaymentRouter 路由信息
+func (s *PaymentRouter) InitPaymentRouter(Router *gin.RouterGroup) {
+	paymentRouterWithoutRecord := Router.Group("payment")
+	var paymentApi = v1.ApiGroupApp.PaymentApiGroup.PaymentApi
+	{
+		paymentRouterWithoutRecord.POST("sandboxPay", paymentApi.SandboxPay) // 沙箱模拟付款
+	}
+}
+
+// InitPaymentPublicRouter 初始化公共的 PaymentRouter 路由信息，支付渠道回调不做鉴权
+func (s *PaymentRouter) InitPaymentPublicRouter(Router *gin.RouterGroup) {
+	paymentRouterWithoutRecord := Router.Group("payment")
+	var paymentApi = v1.ApiGroupApp.PaymentApiGroup.PaymentApi
+	{
+		paymentRouterWithoutRecord.POST("notify/:channel", paymentApi.PayNotify)          // 支付结果回调
+		paymentRouterWithoutRecord.POST("refundNotify/:channel", paymentApi.RefundNotify) // 退款结果回调
+	}
+}
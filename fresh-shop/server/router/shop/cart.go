@@ -24,5 +24,6 @@ func (s *CartRouter) InitCartRouter(Router *gin.RouterGroup) {
 		cartRouterWithoutRecord.POST("selectAllChecked", cartApi.SelectAllChecked) // 全选 Cart
 		cartRouterWithoutRecord.POST("clearAllChecked", cartApi.ClearAllChecked)   // 取消全选 Cart
 		cartRouterWithoutRecord.GET("getCartList", cartApi.GetCartList)            // 获取Cart列表
+		cartRouterWithoutRecord.GET("getPostageQuote", cartApi.GetPostageQuote)    // 运费试算
 	}
 }
@@ -0,0 +1,32 @@
+package shop
+
+import (
+	"fresh-shop/server/api/v1"
+	"fresh-shop/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type CouponRouter struct {
+}
+
+// InitCouponRouter 初始化 Coupon 路由信息
+func (s *CouponRouter) InitCouponRouter(Router *gin.RouterGroup) {
+	couponRouter := Router.Group("coupon").Use(middleware.OperationRecord())
+	couponRouterWithoutRecord := Router.Group("coupon")
+	var couponApi = v1.ApiGroupApp.ShopApiGroup.CouponApi
+	{
+		couponRouter.POST("createCoupon", couponApi.CreateCoupon)             // 新建Coupon
+		couponRouter.DELETE("deleteCoupon", couponApi.DeleteCoupon)           // 删除Coupon
+		couponRouter.DELETE("deleteCouponByIds", couponApi.DeleteCouponByIds) // 批量删除Coupon
+		couponRouter.PUT("updateCoupon", couponApi.UpdateCoupon)              // 更新Coupon
+		couponRouter.POST("issueCoupon", couponApi.IssueCoupon)               // 后台发放优惠券
+		couponRouter.POST("claimCoupon", couponApi.ClaimCoupon)               // 领券中心领取优惠券
+	}
+	{
+		couponRouterWithoutRecord.GET("findCoupon", couponApi.FindCoupon)                   // 根据ID获取Coupon
+		couponRouterWithoutRecord.GET("getCouponList", couponApi.GetCouponList)             // 获取Coupon列表
+		couponRouterWithoutRecord.GET("getClaimableCoupons", couponApi.GetClaimableCoupons) // 领券中心 可领取的优惠券
+		couponRouterWithoutRecord.GET("getUserCouponList", couponApi.GetUserCouponList)     // 我的优惠券
+		couponRouterWithoutRecord.GET("getUsableCoupons", couponApi.GetUsableCoupons)       // 购物车可用的优惠券
+	}
+}
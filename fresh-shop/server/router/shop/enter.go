@@ -13,4 +13,10 @@ type RouterGroup struct {
 	FavoritesRouter
 	CartRouter
 	UserAddressRouter
+	RiderRouter
+	CouponRouter
+	FlashSaleRouter
+	GroupBuyRouter
+	PaymentReconcileRouter
+	PaymentNotifyLogRouter
 }
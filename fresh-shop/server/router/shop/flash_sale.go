@@ -0,0 +1,38 @@
+package shop
+
+import (
+	"fresh-shop/server/api/v1"
+	"fresh-shop/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type FlashSaleRouter struct {
+}
+
+// InitFlashSaleRouter 初始化 FlashSale 路由信息
+func (s *FlashSaleRouter) InitFlashSaleRouter(Router *gin.RouterGroup) {
+	flashSaleRouter := Router.Group("flashSale").Use(middleware.OperationRecord())
+	flashSaleRouterWithoutRecord := Router.Group("flashSale")
+	var flashSaleApi = v1.ApiGroupApp.ShopApiGroup.FlashSaleApi
+	{
+		flashSaleRouter.POST("createFlashSale", flashSaleApi.CreateFlashSale)             // 新建FlashSale
+		flashSaleRouter.DELETE("deleteFlashSale", flashSaleApi.DeleteFlashSale)           // 删除FlashSale
+		flashSaleRouter.DELETE("deleteFlashSaleByIds", flashSaleApi.DeleteFlashSaleByIds) // 批量删除FlashSale
+		flashSaleRouter.PUT("updateFlashSale", flashSaleApi.UpdateFlashSale)              // 更新FlashSale
+	}
+	{
+		flashSaleRouterWithoutRecord.GET("findFlashSale", flashSaleApi.FindFlashSale)             // 根据ID获取FlashSale
+		flashSaleRouterWithoutRecord.GET("getFlashSaleList", flashSaleApi.GetFlashSaleList)       // 获取FlashSale列表
+		flashSaleRouterWithoutRecord.POST("createFlashOrder", flashSaleApi.CreateFlashOrder)      // 秒杀下单
+		flashSaleRouterWithoutRecord.GET("getFlashOrderResult", flashSaleApi.GetFlashOrderResult) // 秒杀下单结果
+	}
+}
+
+// InitFlashSalePublicRouter 初始化公开的 FlashSale 路由信息
+func (s *FlashSaleRouter) InitFlashSalePublicRouter(Router *gin.RouterGroup) {
+	flashSaleRouterWithoutRecord := Router.Group("flashSale")
+	var flashSaleApi = v1.ApiGroupApp.ShopApiGroup.FlashSaleApi
+	{
+		flashSaleRouterWithoutRecord.GET("getFlashSaleCountdown", flashSaleApi.GetFlashSaleCountdown) // 秒杀活动倒计时
+	}
+}
@@ -0,0 +1,39 @@
+package shop
+
+import (
+	"fresh-shop/server/api/v1"
+	"fresh-shop/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type GroupBuyRouter struct {
+}
+
+// InitGroupBuyRouter 初始化 GroupBuy 路由信息
+func (s *GroupBuyRouter) InitGroupBuyRouter(Router *gin.RouterGroup) {
+	groupBuyRouter := Router.Group("groupBuy").Use(middleware.OperationRecord())
+	groupBuyRouterWithoutRecord := Router.Group("groupBuy")
+	var groupBuyApi = v1.ApiGroupApp.ShopApiGroup.GroupBuyApi
+	{
+		groupBuyRouter.POST("createGroupBuy", groupBuyApi.CreateGroupBuy)             // 新建GroupBuy
+		groupBuyRouter.DELETE("deleteGroupBuy", groupBuyApi.DeleteGroupBuy)           // 删除GroupBuy
+		groupBuyRouter.DELETE("deleteGroupBuyByIds", groupBuyApi.DeleteGroupBuyByIds) // 批量删除GroupBuy
+		groupBuyRouter.PUT("updateGroupBuy", groupBuyApi.UpdateGroupBuy)              // 更新GroupBuy
+		groupBuyRouter.POST("createGroupOrder", groupBuyApi.CreateGroupOrder)         // 拼团下单
+	}
+	{
+		groupBuyRouterWithoutRecord.GET("findGroupBuy", groupBuyApi.FindGroupBuy)               // 根据ID获取GroupBuy
+		groupBuyRouterWithoutRecord.GET("getGroupBuyList", groupBuyApi.GetGroupBuyList)         // 获取GroupBuy列表
+		groupBuyRouterWithoutRecord.GET("getGroupBuyTeamList", groupBuyApi.GetGroupBuyTeamList) // 获取拼团团队列表
+	}
+}
+
+// InitGroupBuyPublicRouter 初始化公开的 GroupBuy 路由信息
+func (s *GroupBuyRouter) InitGroupBuyPublicRouter(Router *gin.RouterGroup) {
+	groupBuyRouterWithoutRecord := Router.Group("groupBuy")
+	var groupBuyApi = v1.ApiGroupApp.ShopApiGroup.GroupBuyApi
+	{
+		groupBuyRouterWithoutRecord.GET("getActiveGroupBuys", groupBuyApi.GetActiveGroupBuys) // 进行中的拼团活动
+		groupBuyRouterWithoutRecord.GET("getJoinableTeams", groupBuyApi.GetJoinableTeams)     // 可参加的团
+	}
+}
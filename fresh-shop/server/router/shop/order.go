@@ -18,6 +18,7 @@ func (s *OrderRouter) InitOrderRouter(Router *gin.RouterGroup) {
 		orderRouter.POST("createOrder", orderApi.CreateOrder)             // 创建待支付 Order
 		orderRouter.POST("orderPay", orderApi.OrderPay)                   // 支付 Order, 返回微信支付所需要的参数
 		orderRouter.POST("cancelOrder", orderApi.CancelOrder)             // 取消订单
+		orderRouter.POST("syncPayment", orderApi.SyncOrderPayment)        // 主动查询订单支付结果
 		orderRouter.DELETE("deleteOrder", orderApi.DeleteOrder)           // 删除 Order
 		orderRouter.DELETE("deleteOrderByIds", orderApi.DeleteOrderByIds) // 批量删除 Order
 		orderRouter.PUT("updateOrder", orderApi.UpdateOrder)              // 更新 Order
@@ -28,5 +29,8 @@ func (s *OrderRouter) InitOrderRouter(Router *gin.RouterGroup) {
 		orderRouterWithoutRecord.GET("getOrderList", orderApi.GetOrderList)               // 获取Order列表
 		orderRouterWithoutRecord.GET("getUserOrderList", orderApi.GetUserOrderList)       // 根据登录用户获取Order列表
 		orderRouterWithoutRecord.GET("orderStatus", orderApi.OrderStatus)                 // 获取订单状态 Order
+		orderRouterWithoutRecord.GET("getOrderTimeline", orderApi.GetOrderTimeline)       // 获取订单时间线
+		// 请求体包含安全密码，不写入操作记录
+		orderRouterWithoutRecord.POST("balancePay", orderApi.BalancePay) // 余额支付 Order
 	}
 }
@@ -18,10 +18,12 @@ func (s *OrderReturnRouter) InitOrderReturnRouter(Router *gin.RouterGroup) {
 		orderReturnRouter.POST("createOrderReturn", orderReturnApi.CreateOrderReturn)   // 新建OrderReturn
 		orderReturnRouter.DELETE("deleteOrderReturn", orderReturnApi.DeleteOrderReturn) // 删除OrderReturn
 		orderReturnRouter.DELETE("deleteOrderReturnByIds", orderReturnApi.DeleteOrderReturnByIds) // 批量删除OrderReturn
-		orderReturnRouter.PUT("updateOrderReturn", orderReturnApi.UpdateOrderReturn)    // 更新OrderReturn
+		orderReturnRouter.PUT("reviewOrderReturn", orderReturnApi.ReviewOrderReturn)    // 审核售后
+		orderReturnRouter.PUT("updateOrderReturn", orderReturnApi.ReviewOrderReturn)    // 审核售后 兼容已授权的原更新接口
 	}
 	{
 		orderReturnRouterWithoutRecord.GET("findOrderReturn", orderReturnApi.FindOrderReturn)        // 根据ID获取OrderReturn
 		orderReturnRouterWithoutRecord.GET("getOrderReturnList", orderReturnApi.GetOrderReturnList)  // 获取OrderReturn列表
+		orderReturnRouterWithoutRecord.GET("getUserOrderReturnList", orderReturnApi.GetUserOrderReturnList) // 用户获取自己的售后列表
 	}
 }
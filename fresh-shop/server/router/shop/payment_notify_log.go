@@ -0,0 +1,23 @@
+package shop
+
+import (
+	"fresh-shop/server/api/v1"
+	"fresh-shop/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type PaymentNotifyLogRouter struct {
+}
+
+// InitPaymentNotifyLogRouter 初始化 PaymentNotifyLog 路由信息
+func (s *PaymentNotifyLogRouter) InitPaymentNotifyLogRouter(Router *gin.RouterGroup) {
+	paymentNotifyLogRouter := Router.Group("paymentNotifyLog").Use(middleware.OperationRecord())
+	paymentNotifyLogRouterWithoutRecord := Router.Group("paymentNotifyLog")
+	var paymentNotifyLogApi = v1.ApiGroupApp.ShopApiGroup.PaymentNotifyLogApi
+	{
+		paymentNotifyLogRouter.PUT("handlePaymentNotifyLog", paymentNotifyLogApi.HandlePaymentNotifyLog) // 标记异常通知已人工处理
+	}
+	{
+		paymentNotifyLogRouterWithoutRecord.GET("getPaymentNotifyLogList", paymentNotifyLogApi.GetPaymentNotifyLogList) // 获取支付通知记录列表
+	}
+}
@@ -0,0 +1,26 @@
+package shop
+
+import (
+	"fresh-shop/server/api/v1"
+	"fresh-shop/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type PaymentReconcileRouter struct {
+}
+
+// InitPaymentReconcileRouter 初始化 PaymentReconcile 路由信息
+func (s *PaymentReconcileRouter) InitPaymentReconcileRouter(Router *gin.RouterGroup) {
+	paymentReconcileRouter := Router.Group("paymentReconcile").Use(middleware.OperationRecord())
+	paymentReconcileRouterWithoutRecord := Router.Group("paymentReconcile")
+	var paymentReconcileApi = v1.ApiGroupApp.ShopApiGroup.PaymentReconcileApi
+	{
+		paymentReconcileRouter.PUT("handlePaymentReconcileDiff", paymentReconcileApi.HandlePaymentReconcileDiff) // 标记对账差异已处理
+	}
+	{
+		// 请求体为账单文件，不写入操作记录
+		paymentReconcileRouterWithoutRecord.POST("reconcile", paymentReconcileApi.Reconcile)                                    // 手动对账
+		paymentReconcileRouterWithoutRecord.GET("getPaymentReconcileList", paymentReconcileApi.GetPaymentReconcileList)         // 获取对账记录列表
+		paymentReconcileRouterWithoutRecord.GET("getPaymentReconcileDiffList", paymentReconcileApi.GetPaymentReconcileDiffList) // 获取对账差异明细
+	}
+}
@@ -0,0 +1,27 @@
+package shop
+
+import (
+	"fresh-shop/server/api/v1"
+	"fresh-shop/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type RiderRouter struct {
+}
+
+// InitRiderRouter 初始化 配送员端 路由信息
+func (s *RiderRouter) InitRiderRouter(Router *gin.RouterGroup) {
+	riderRouter := Router.Group("rider").Use(middleware.OperationRecord())
+	riderRouterWithoutRecord := Router.Group("rider")
+	var riderApi = v1.ApiGroupApp.ShopApiGroup.RiderApi
+	{
+		riderRouter.PUT("pickUpOrder", riderApi.PickUpOrder)     // 配送员取货
+		riderRouter.PUT("deliverOrder", riderApi.DeliverOrder)   // 配送员确认送达
+		riderRouter.PUT("pickUpReturn", riderApi.PickUpReturn)   // 配送员上门取回售后商品
+		riderRouter.PUT("deliverReturn", riderApi.DeliverReturn) // 配送员将售后商品送回门店
+	}
+	{
+		riderRouterWithoutRecord.GET("getRiderOrderList", riderApi.GetRiderOrderList) // 配送员获取指派的订单列表
+		riderRouterWithoutRecord.POST("uploadProof", riderApi.UploadProof)            // 上传送达凭证图片 文件不记录操作日志
+	}
+}
@@ -13,6 +13,7 @@ func (s *BaseRouter) InitBaseRouter(Router *gin.RouterGroup) (R gin.IRoutes) {
 	{
 		baseRouter.POST("login", baseApi.Login)
 		baseRouter.POST("loginWx", baseApi.LoginWx)
+		baseRouter.POST("riderLogin", baseApi.RiderLogin)
 		baseRouter.POST("captcha", baseApi.Captcha)
 	}
 	return baseRouter
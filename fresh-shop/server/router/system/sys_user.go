@@ -25,5 +25,7 @@ func (s *UserRouter) InitUserRouter(Router *gin.RouterGroup) {
 	{
 		userRouterWithoutRecord.POST("getUserList", baseApi.GetUserList) // 分页获取用户列表
 		userRouterWithoutRecord.GET("getUserInfo", baseApi.GetUserInfo)  // 获取自身信息
+		// 请求体包含安全密码，不写入操作记录
+		userRouterWithoutRecord.POST("setSafePassword", baseApi.SetSafePassword) // 设置安全密码
 	}
 }
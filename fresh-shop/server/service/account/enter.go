@@ -6,4 +6,5 @@ type ServiceGroup struct {
 	SysRechargeService
 	UserFinanceTypeService
 	UserFinanceCashService
+	UserCommissionService
 }
@@ -0,0 +1,82 @@
+package account
+
+import (
+	"errors"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/account"
+	accountReq "fresh-shop/server/model/account/request"
+	"fresh-shop/server/model/common/request"
+	sysModel "fresh-shop/server/model/system"
+	"gorm.io/gorm"
+)
+
+type UserCommissionService struct {
+}
+
+// GetUserCommissionInfoList 后台分页获取分销佣金记录
+func (userCommissionService *UserCommissionService) GetUserCommissionInfoList(info accountReq.UserCommissionSearch) (list []account.UserCommission, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.DB.Model(&account.UserCommission{}).Joins("User")
+	if info.StartCreatedAt != nil && info.EndCreatedAt != nil {
+		db = db.Where("user_commission.created_at BETWEEN ? AND ?", info.StartCreatedAt, info.EndCreatedAt)
+	}
+	if info.Username != "" {
+		db = db.Where("User.username like ?", "%"+info.Username+"%")
+	}
+	if info.Phone != "" {
+		db = db.Where("User.phone like ?", "%"+info.Phone+"%")
+	}
+	if info.OrderSn != "" {
+		db = db.Where("user_commission.order_sn = ?", info.OrderSn)
+	}
+	if info.Level != 0 {
+		db = db.Where("user_commission.level = ?", info.Level)
+	}
+	if info.Status != nil {
+		db = db.Where("user_commission.status = ?", *info.Status)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Preload("FromUser").Limit(limit).Offset(offset).Order("user_commission.id desc").Find(&list).Error
+	return
+}
+
+// GetMyCommissionList 分页获取用户自己的佣金明细
+func (userCommissionService *UserCommissionService) GetMyCommissionList(userId uint, info accountReq.MyCommissionSearch) (list []account.UserCommission, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.DB.Model(&account.UserCommission{}).Where("user_id = ?", userId)
+	if info.Status != nil {
+		db = db.Where("status = ?", *info.Status)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Preload("FromUser", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "nick_name", "header_img")
+	}).Limit(limit).Offset(offset).Order("id desc").Find(&list).Error
+	return
+}
+
+// GetMyTeam 获取用户团队统计，没有下级时返回空统计
+func (userCommissionService *UserCommissionService) GetMyTeam(userId uint) (team account.UserTeam, err error) {
+	err = global.DB.Where("user_id = ?", userId).First(&team).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return account.UserTeam{UserId: userId}, nil
+	}
+	return
+}
+
+// GetMyTeamMembers 分页获取用户的直推成员
+func (userCommissionService *UserCommissionService) GetMyTeamMembers(userId uint, info request.PageInfo) (list []sysModel.SysUser, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.DB.Model(&sysModel.SysUser{}).Where("inviter_id = ?", userId)
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Select("id", "created_at", "nick_name", "header_img").Limit(limit).Offset(offset).Order("id desc").Find(&list).Error
+	return
+}
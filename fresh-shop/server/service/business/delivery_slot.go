@@ -0,0 +1,152 @@
+package business
+
+import (
+	"errors"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/business"
+	businessReq "fresh-shop/server/model/business/request"
+	businessResp "fresh-shop/server/model/business/response"
+	"fresh-shop/server/model/common/request"
+	"fresh-shop/server/model/shop"
+	"fresh-shop/server/service/common"
+	"gorm.io/gorm"
+	"time"
+)
+
+type DeliverySlotService struct {
+}
+
+// CreateDeliverySlot 创建DeliverySlot记录
+// Author [likfees](https://github.com/likfees)
+func (deliverySlotService *DeliverySlotService) CreateDeliverySlot(deliverySlot business.DeliverySlot) (err error) {
+	if err = checkDeliverySlot(deliverySlot); err != nil {
+		return err
+	}
+	err = global.DB.Create(&deliverySlot).Error
+	return err
+}
+
+// DeleteDeliverySlot 删除DeliverySlot记录
+// Author [likfees](https://github.com/likfees)
+func (deliverySlotService *DeliverySlotService) DeleteDeliverySlot(deliverySlot business.DeliverySlot) (err error) {
+	err = global.DB.Delete(&deliverySlot).Error
+	return err
+}
+
+// DeleteDeliverySlotByIds 批量删除DeliverySlot记录
+// Author [likfees](https://github.com/likfees)
+func (deliverySlotService *DeliverySlotService) DeleteDeliverySlotByIds(ids request.IdsReq) (err error) {
+	err = global.DB.Delete(&[]business.DeliverySlot{}, "id in ?", ids.Ids).Error
+	return err
+}
+
+// UpdateDeliverySlot 更新DeliverySlot记录
+// Author [likfees](https://github.com/likfees)
+func (deliverySlotService *DeliverySlotService) UpdateDeliverySlot(deliverySlot business.DeliverySlot) (err error) {
+	if err = checkDeliverySlot(deliverySlot); err != nil {
+		return err
+	}
+	err = global.DB.Save(&deliverySlot).Error
+	return err
+}
+
+// GetDeliverySlot 根据id获取DeliverySlot记录
+// Author [likfees](https://github.com/likfees)
+func (deliverySlotService *DeliverySlotService) GetDeliverySlot(id uint) (deliverySlot business.DeliverySlot, err error) {
+	err = global.DB.Where("id = ?", id).First(&deliverySlot).Error
+	return
+}
+
+// GetDeliverySlotInfoList 分页获取DeliverySlot记录
+// Author [likfees](https://github.com/likfees)
+func (deliverySlotService *DeliverySlotService) GetDeliverySlotInfoList(info businessReq.DeliverySlotSearch) (list []business.DeliverySlot, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	// 创建db
+	db := global.DB.Model(&business.DeliverySlot{})
+	var deliverySlots []business.DeliverySlot
+	// 如果有条件搜索 下方会自动创建搜索语句
+	if info.StartCreatedAt != nil && info.EndCreatedAt != nil {
+		db = db.Where("created_at BETWEEN ? AND ?", info.StartCreatedAt, info.EndCreatedAt)
+	}
+	if info.ZoneId != 0 {
+		db = db.Where("zone_id = ?", info.ZoneId)
+	}
+	if info.Status != nil {
+		db = db.Where("status = ?", info.Status)
+	}
+	err = db.Count(&total).Error
+	if err != nil {
+		return
+	}
+
+	err = db.Limit(limit).Offset(offset).Order("sort asc, start_time asc").Find(&deliverySlots).Error
+	return deliverySlots, total, err
+}
+
+// GetAvailableDeliverySlots 获取收货地址未来几天可预约的配送时段
+// 只返回地址所属配送区域(或不限区域)的时段，已开始和已约满的时段不返回
+func (deliverySlotService *DeliverySlotService) GetAvailableDeliverySlots(userId uint, addressId int) (list []businessResp.AvailableDeliverySlot, err error) {
+	var address shop.UserAddress
+	if errors.Is(global.DB.Where("id = ? and user_id = ?", addressId, userId).First(&address).Error, gorm.ErrRecordNotFound) {
+		return nil, errors.New("收货地址不存在")
+	}
+	if address.Deliverable != nil && *address.Deliverable == 0 {
+		return nil, errors.New("收货地址超出配送范围")
+	}
+	var slots []business.DeliverySlot
+	err = global.DB.Where("status = 1 and zone_id in ?", []uint{0, address.ZoneId}).Order("sort asc, start_time asc").Find(&slots).Error
+	if err != nil || len(slots) == 0 {
+		return
+	}
+	now := time.Now()
+	for i := 0; i < global.Config.Order.GetSlotDays(); i++ {
+		day := now.AddDate(0, 0, i).Format("2006-01-02")
+		var usages []business.DeliverySlotUsage
+		if err = global.DB.Where("day = ?", day).Find(&usages).Error; err != nil {
+			return
+		}
+		used := make(map[uint]int, len(usages))
+		for _, u := range usages {
+			used[u.SlotId] = u.Used
+		}
+		for _, slot := range slots {
+			start, end, parseErr := common.DeliverySlotWindow(slot, day)
+			if parseErr != nil || !start.After(now) {
+				continue
+			}
+			remaining := *slot.Capacity - used[slot.ID]
+			if remaining <= 0 {
+				continue
+			}
+			list = append(list, businessResp.AvailableDeliverySlot{
+				SlotId:    slot.ID,
+				Name:      slot.Name,
+				Day:       day,
+				Start:     start,
+				End:       end,
+				Remaining: remaining,
+			})
+		}
+	}
+	return
+}
+
+// checkDeliverySlot 校验配送时段参数
+func checkDeliverySlot(slot business.DeliverySlot) error {
+	start, err := time.Parse("15:04", slot.StartTime)
+	if err != nil {
+		return errors.New("开始时间格式错误，例: 09:00")
+	}
+	end, err := time.Parse("15:04", slot.EndTime)
+	if err != nil {
+		return errors.New("结束时间格式错误，例: 11:00")
+	}
+	if !end.After(start) {
+		return errors.New("结束时间必须大于开始时间")
+	}
+	if slot.Capacity == nil || *slot.Capacity <= 0 {
+		return errors.New("可预约订单数必须大于 0")
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package business
+
+import (
+	"errors"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/business"
+	businessReq "fresh-shop/server/model/business/request"
+	"fresh-shop/server/model/common/request"
+	"fresh-shop/server/service/common"
+)
+
+type DeliveryZoneService struct {
+}
+
+// CreateDeliveryZone 创建DeliveryZone记录
+// Author [likfees](https://github.com/likfees)
+func (deliveryZoneService *DeliveryZoneService) CreateDeliveryZone(deliveryZone business.DeliveryZone) (err error) {
+	if err = checkDeliveryZone(deliveryZone); err != nil {
+		return err
+	}
+	err = global.DB.Create(&deliveryZone).Error
+	return err
+}
+
+// DeleteDeliveryZone 删除DeliveryZone记录
+// Author [likfees](https://github.com/likfees)
+func (deliveryZoneService *DeliveryZoneService) DeleteDeliveryZone(deliveryZone business.DeliveryZone) (err error) {
+	err = global.DB.Delete(&deliveryZone).Error
+	return err
+}
+
+// DeleteDeliveryZoneByIds 批量删除DeliveryZone记录
+// Author [likfees](https://github.com/likfees)
+func (deliveryZoneService *DeliveryZoneService) DeleteDeliveryZoneByIds(ids request.IdsReq) (err error) {
+	err = global.DB.Delete(&[]business.DeliveryZone{}, "id in ?", ids.Ids).Error
+	return err
+}
+
+// UpdateDeliveryZone 更新DeliveryZone记录
+// Author [likfees](https://github.com/likfees)
+func (deliveryZoneService *DeliveryZoneService) UpdateDeliveryZone(deliveryZone business.DeliveryZone) (err error) {
+	if err = checkDeliveryZone(deliveryZone); err != nil {
+		return err
+	}
+	err = global.DB.Save(&deliveryZone).Error
+	return err
+}
+
+// GetDeliveryZone 根据id获取DeliveryZone记录
+// Author [likfees](https://github.com/likfees)
+func (deliveryZoneService *DeliveryZoneService) GetDeliveryZone(id uint) (deliveryZone business.DeliveryZone, err error) {
+	err = global.DB.Where("id = ?", id).First(&deliveryZone).Error
+	return
+}
+
+// GetDeliveryZoneInfoList 分页获取DeliveryZone记录
+// Author [likfees](https://github.com/likfees)
+func (deliveryZoneService *DeliveryZoneService) GetDeliveryZoneInfoList(info businessReq.DeliveryZoneSearch) (list []business.DeliveryZone, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	// 创建db
+	db := global.DB.Model(&business.DeliveryZone{})
+	var deliveryZones []business.DeliveryZone
+	// 如果有条件搜索 下方会自动创建搜索语句
+	if info.StartCreatedAt != nil && info.EndCreatedAt != nil {
+		db = db.Where("created_at BETWEEN ? AND ?", info.StartCreatedAt, info.EndCreatedAt)
+	}
+	if info.Name != "" {
+		db = db.Where("name LIKE ?", "%"+info.Name+"%")
+	}
+	if info.Status != nil {
+		db = db.Where("status = ?", info.Status)
+	}
+	err = db.Count(&total).Error
+	if err != nil {
+		return
+	}
+
+	err = db.Limit(limit).Offset(offset).Order("sort asc").Find(&deliveryZones).Error
+	return deliveryZones, total, err
+}
+
+// FindDeliveryZoneByLocation 根据坐标获取所在的配送区域
+// 未配置配送区域时不限制配送范围，返回的 zone 为 nil
+func (deliveryZoneService *DeliveryZoneService) FindDeliveryZoneByLocation(lng, lat float64) (zone *business.DeliveryZone, deliverable bool, err error) {
+	return common.MatchDeliveryZone(lng, lat)
+}
+
+// checkDeliveryZone 校验配送区域参数
+func checkDeliveryZone(zone business.DeliveryZone) error {
+	if zone.Type != nil && *zone.Type == 1 {
+		_, err := common.ParseZonePolygon(zone.Polygon)
+		return err
+	}
+	if zone.Longitude == nil || zone.Latitude == nil || (*zone.Longitude == 0 && *zone.Latitude == 0) {
+		return errors.New("请设置配送区域圆心坐标")
+	}
+	if zone.Radius == nil || *zone.Radius <= 0 {
+		return errors.New("配送半径必须大于 0")
+	}
+	return nil
+}
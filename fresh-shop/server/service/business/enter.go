@@ -3,4 +3,6 @@ package business
 type ServiceGroup struct {
 	BannerService
 	UserDeliveryService
+	DeliveryZoneService
+	DeliverySlotService
 }
@@ -1,12 +1,19 @@
 package business
 
 import (
+	"errors"
 	"fresh-shop/server/global"
 	"fresh-shop/server/model/business"
 	businessReq "fresh-shop/server/model/business/request"
 	"fresh-shop/server/model/common/request"
+	sysModel "fresh-shop/server/model/system"
+	"fresh-shop/server/service/system"
+	"fresh-shop/server/utils"
+	"gorm.io/gorm"
 )
 
+var userService = system.UserService{}
+
 type UserDeliveryService struct {
 }
 
@@ -34,7 +41,8 @@ func (userDeliveryService *UserDeliveryService) DeleteUserDeliveryByIds(ids requ
 // UpdateUserDelivery 更新UserDelivery记录
 // Author [likfees](https://github.com/likfees)
 func (userDeliveryService *UserDeliveryService) UpdateUserDelivery(userDelivery business.UserDelivery) (err error) {
-	err = global.DB.Save(&userDelivery).Error
+	// 登录账号只能通过 SetRiderAccount 绑定
+	err = global.DB.Omit("user_id").Save(&userDelivery).Error
 	return err
 }
 
@@ -111,3 +119,36 @@ func (userDeliveryService *UserDeliveryService) GetUserDeliveryAllList(info busi
 	err = db.Find(&userDeliverys).Error
 	return userDeliverys, err
 }
+
+// SetRiderAccount 设置配送员登录账号
+// 未绑定账号时以配送员手机号为用户名创建配送员角色账号，已绑定时重置登录密码
+func (userDeliveryService *UserDeliveryService) SetRiderAccount(req businessReq.RiderAccountReq) (err error) {
+	var rider business.UserDelivery
+	if errors.Is(global.DB.Where("id = ?", req.ID).First(&rider).Error, gorm.ErrRecordNotFound) {
+		return errors.New("配送员不存在")
+	}
+	if rider.UserId != 0 {
+		var user sysModel.SysUser
+		if err = global.DB.Where("id = ?", rider.UserId).First(&user).Error; err == nil {
+			return global.DB.Model(&user).Update("password", utils.BcryptHash(req.Password)).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		// 账号已被删除时重新创建
+	}
+	user, err := userService.Register(sysModel.SysUser{
+		Username:    rider.Mobile,
+		NickName:    rider.Name,
+		Password:    req.Password,
+		AuthorityId: business.RiderAuthorityId,
+		Authorities: []sysModel.SysAuthority{{AuthorityId: business.RiderAuthorityId}},
+		Enable:      1,
+		Phone:       rider.Mobile,
+	})
+	if err != nil {
+		global.SugarLog.Errorf("创建配送员账号失败 riderId:%d, mobile:%s, err:%v \n", rider.ID, rider.Mobile, err)
+		return err
+	}
+	return global.DB.Model(&business.UserDelivery{}).Where("id = ?", rider.ID).Update("user_id", user.ID).Error
+}
@@ -8,6 +8,7 @@ import (
 	sysModel "fresh-shop/server/model/system"
 	"fresh-shop/server/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"math"
 )
 
@@ -17,12 +18,28 @@ const (
 	POINT = 2 // 积分
 )
 
+// 流水类型ID 对应 user_finance_type 表，如果数据库中的ID发生改变这里也需要修改
+const (
+	FinanceTypePointGoods = 1 // 购买积分商品
+	FinanceTypeGiftPoint  = 6 // 确认收货发放积分
+	FinanceTypeRefund     = 7 // 订单退款
+
+	FinanceTypeCommission        = 8  // 分销佣金入账(冻结)
+	FinanceTypeCommissionSettle  = 9  // 分销佣金解冻
+	FinanceTypeCommissionRefund  = 10 // 订单退款扣回佣金
+	FinanceTypeBalancePay        = 11 // 余额支付订单
+	FinanceTypePointOffset       = 12 // 订单积分抵扣
+	FinanceTypePointOffsetReturn = 13 // 订单积分抵扣退回
+	FinanceTypeGiftPointReturn   = 14 // 售后退款扣回赠送积分
+)
+
 // 限定操作类型
 type optionType int
 
 var (
-	OptionTypeCASH = optionType(0) // 操作余额
-	OptionTypeLock = optionType(1) // 操作锁仓
+	OptionTypeCASH   = optionType(0) // 操作余额
+	OptionTypeFreeze = optionType(1) // 操作冻结
+	OptionTypeLock   = optionType(2) // 操作锁仓
 )
 
 // NewFinance 构造函数
@@ -54,6 +71,12 @@ func NewFinance(optionType optionType, typeId int, userId uint, username string,
 // groupId 账户类型
 // finance 入账数据，需要填写完整
 func AccountUnifyDeduction(groupId int, finance account.UserFinance) error {
+	return AccountUnifyDeductionTx(global.DB, groupId, finance)
+}
+
+// AccountUnifyDeductionTx 在指定事务中进行账户统一扣减，用于和业务数据一起提交或回滚
+// tx 业务事务，传入 global.DB 时单独开启事务
+func AccountUnifyDeductionTx(tx *gorm.DB, groupId int, finance account.UserFinance) error {
 
 	if finance.FeeAmount == nil {
 		finance.FeeAmount = utils.Pointer(0.0)
@@ -64,7 +87,7 @@ func AccountUnifyDeduction(groupId int, finance account.UserFinance) error {
 	log := fmt.Sprintf("账户统一扣减 --- userId: %d, group: %d, typeId: %d, amount: %f, feeAmount: %f, optionType: %d",
 		finance.UserId, groupId, finance.TypeId, *finance.Amount, *finance.FeeAmount, finance.OptionType)
 	var user sysModel.SysUser
-	if errors.Is(global.DB.Where("username = ?", finance.Username).First(&user).Error, gorm.ErrRecordNotFound) {
+	if errors.Is(tx.Where("username = ?", finance.Username).First(&user).Error, gorm.ErrRecordNotFound) {
 		global.SugarLog.Errorf(log + " 用户不存在")
 		return errors.New("用户不存在")
 	}
@@ -82,83 +105,87 @@ func AccountUnifyDeduction(groupId int, finance account.UserFinance) error {
 	}
 	// 获取账户配置
 	var group account.AccountGroup
-	if errors.Is(global.DB.Where("id = ?", groupId).First(&group).Error, gorm.ErrRecordNotFound) {
+	if errors.Is(tx.Where("id = ?", groupId).First(&group).Error, gorm.ErrRecordNotFound) {
 		global.SugarLog.Errorf(log + " 账户配置不存在")
 		return errors.New("账户配置不存在")
 	}
-	// 获取该用户的账户信息
-	accountInfo, err := GetUserAccountInfo(*finance.UserId, groupId)
-	// 如果账户不存在，应该创建
-	if err != nil {
-		global.SugarLog.Errorf(log + " 获取账户信息失败")
-		return err
-	}
-	if *accountInfo.Status != 1 {
-		global.SugarLog.Errorf(log+" 账户异常，account.status: %d", *accountInfo.Status)
-		return errors.New("账户异常")
-	}
 	// 手续费
 	if *finance.FeeAmount > 0 {
 		finance.IsFee = utils.Pointer(1)
 	} else {
 		finance.IsFee = utils.Pointer(0)
 	}
-
+	column, name := "amount", group.NameCn
 	switch *finance.OptionType {
-	case 0: // 操作余额
-		if *finance.Amount < 0 && *accountInfo.Amount < math.Abs(*finance.Amount)+*finance.FeeAmount { //  减操作且余额不足
-			global.SugarLog.Errorf(log+" %s不足，当前%s为：%f", group.NameCn, group.NameCn, *accountInfo.Amount)
-			return errors.New(group.NameCn + "不足")
-		}
-		*accountInfo.Amount += *finance.Amount
-		*finance.Balance = *accountInfo.Amount
 	case 1: // 操作冻结
-		if *finance.Amount < 0 && *accountInfo.FreezeAmount < *finance.Amount+*finance.FeeAmount { //  减操作且余额不足
-			global.SugarLog.Errorf(log+"冻结%s不足，当前%s为：%f", group.NameCn, group.NameCn, *accountInfo.Amount)
-			return errors.New("冻结" + group.NameCn + "不足")
-		}
-		*accountInfo.FreezeAmount += *finance.Amount
-		*finance.Balance = *accountInfo.FreezeAmount
+		column, name = "freeze_amount", "冻结"+group.NameCn
 	case 2: // 操作锁仓
-		if *finance.Amount < 0 && *accountInfo.LockAmount < *finance.Amount+*finance.FeeAmount { //  减操作且余额不足
-			global.SugarLog.Errorf(log+"锁仓%s不足，当前%s为：%f", group.NameCn, group.NameCn, *accountInfo.Amount)
-			return errors.New("锁仓" + group.NameCn + "不足")
+		column, name = "lock_amount", "锁仓"+group.NameCn
+	}
+	// 开始事务，tx 已经在事务中时使用保存点；账户行加锁后再校验余额，并发扣减时排队执行
+	return tx.Transaction(func(subTx *gorm.DB) error {
+		accountInfo, err := getUserAccountInfo(subTx.Clauses(clause.Locking{Strength: "UPDATE"}), *finance.UserId, groupId)
+		// 如果账户不存在，应该创建
+		if err != nil {
+			global.SugarLog.Errorf(log + " 获取账户信息失败")
+			return err
 		}
-		*accountInfo.LockAmount += *finance.Amount
-		*finance.Balance = *accountInfo.LockAmount
-	}
-	// 增加累计
-	if *finance.Amount > 0 {
-		*accountInfo.InAmount += *finance.Amount
-	} else {
-		*accountInfo.OutAmount += math.Abs(*finance.Amount)
-	}
-	// 开始事务
-	subTx := global.DB.Begin()
-	// 保存用户账户数据
-	err = subTx.Save(accountInfo).Error
-	if err != nil {
-		subTx.Rollback()
-		global.SugarLog.Errorf(log+" 更新用户账户失败, accountInfo: %#v, err: %s", accountInfo, err.Error())
-		return errors.New("更新用户账户失败")
-	}
-	// 创建流水记录
-	err = subTx.Table("user_finance_" + group.NameEn).Create(&finance).Error
-	if err != nil {
-		subTx.Rollback()
-		global.SugarLog.Errorf(log+" 创建账户流水失败, finance: %#v, err: %s", finance, err.Error())
-		return errors.New("创建账户流水失败")
-	}
-	// TODO 增加团队累计金额
-	subTx.Commit() // 提交事务
-	return nil
+		if *accountInfo.Status != 1 {
+			global.SugarLog.Errorf(log+" 账户异常，account.status: %d", *accountInfo.Status)
+			return errors.New("账户异常")
+		}
+		balance := *accountInfo.Amount
+		switch *finance.OptionType {
+		case 1:
+			balance = *accountInfo.FreezeAmount
+		case 2:
+			balance = *accountInfo.LockAmount
+		}
+		need := math.Abs(*finance.Amount) + *finance.FeeAmount
+		if *finance.Amount < 0 && balance < need { //  减操作且余额不足
+			global.SugarLog.Errorf(log+" %s不足，当前%s为：%f", name, name, balance)
+			return errors.New(name + "不足")
+		}
+		*finance.Balance = balance + *finance.Amount
+		// 只按增量更新变动的字段，不回写整行数据
+		values := map[string]interface{}{column: gorm.Expr(column+" + ?", *finance.Amount)}
+		if *finance.Amount > 0 {
+			values["in_amount"] = gorm.Expr("in_amount + ?", *finance.Amount)
+		} else {
+			values["out_amount"] = gorm.Expr("out_amount + ?", math.Abs(*finance.Amount))
+		}
+		db := subTx.Model(&account.Account{}).Where("id = ?", accountInfo.ID)
+		if *finance.Amount < 0 {
+			db = db.Where(column+" >= ?", need)
+		}
+		result := db.Updates(values)
+		if result.Error != nil {
+			global.SugarLog.Errorf(log+" 更新用户账户失败, accountId: %d, err: %s", accountInfo.ID, result.Error.Error())
+			return errors.New("更新用户账户失败")
+		}
+		if result.RowsAffected == 0 {
+			global.SugarLog.Errorf(log+" %s不足，更新用户账户未生效 accountId: %d", name, accountInfo.ID)
+			return errors.New(name + "不足")
+		}
+		// 创建流水记录
+		err = subTx.Table("user_finance_" + group.NameEn).Create(&finance).Error
+		if err != nil {
+			global.SugarLog.Errorf(log+" 创建账户流水失败, finance: %#v, err: %s", finance, err.Error())
+			return errors.New("创建账户流水失败")
+		}
+		return nil
+	})
 }
 
 // GetUserAccountInfo 获取用户币种信息
 func GetUserAccountInfo(userId, groupId int) (*account.Account, error) {
+	return getUserAccountInfo(global.DB, userId, groupId)
+}
+
+func getUserAccountInfo(db *gorm.DB, userId, groupId int) (*account.Account, error) {
 	// 获取该用户的账户信息
 	var userAcount account.Account
-	if errors.Is(global.DB.Where("user_id = ? and group_id = ?", userId, groupId).Preload("Group").First(&userAcount).Error, gorm.ErrRecordNotFound) {
+	if errors.Is(db.Where("user_id = ? and group_id = ?", userId, groupId).Preload("Group").First(&userAcount).Error, gorm.ErrRecordNotFound) {
 		return nil, errors.New("账户配置不存在")
 	}
 	if *userAcount.Status == 0 {
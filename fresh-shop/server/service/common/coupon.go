@@ -0,0 +1,89 @@
+package common
+
+import (
+	"errors"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/shop"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var (
+	ErrCouponNotAvailable = errors.New("优惠券不存在或已停止发放")
+	ErrCouponSoldOut      = errors.New("优惠券已发放完")
+	ErrCouponLimit        = errors.New("已达到该优惠券的领取上限")
+)
+
+// IssueCoupon 在事务中给用户发放一张优惠券
+// 发放总量使用 issued < total 条件更新，并发领取不会超发；每人限领按用户已有的该券数量判断，
+// 查询优惠券时锁定该行，同一张券的并发领取串行执行，避免同一用户并发领取时都通过限领判断
+func IssueCoupon(tx *gorm.DB, couponId uint, userId uint, source int) (userCoupon shop.UserCoupon, err error) {
+	var coupon shop.Coupon
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? and status = 1", couponId).First(&coupon).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return userCoupon, ErrCouponNotAvailable
+	}
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	start, end := now, now.AddDate(0, 0, coupon.ValidDays)
+	if coupon.ValidType == 0 {
+		if coupon.StartTime == nil || coupon.EndTime == nil || !coupon.EndTime.After(now) {
+			return userCoupon, ErrCouponNotAvailable
+		}
+		start, end = *coupon.StartTime, *coupon.EndTime
+	}
+	if coupon.PerUserLimit > 0 {
+		var count int64
+		if err = tx.Model(&shop.UserCoupon{}).Where("user_id = ? and coupon_id = ?", userId, coupon.ID).Count(&count).Error; err != nil {
+			return
+		}
+		if count >= int64(coupon.PerUserLimit) {
+			return userCoupon, ErrCouponLimit
+		}
+	}
+	db := tx.Model(&shop.Coupon{}).Where("id = ?", coupon.ID)
+	if coupon.Total > 0 {
+		db = db.Where("issued < total")
+	}
+	result := db.Update("issued", gorm.Expr("issued + 1"))
+	if result.Error != nil {
+		return userCoupon, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return userCoupon, ErrCouponSoldOut
+	}
+	userCoupon = shop.UserCoupon{
+		UserId:      userId,
+		CouponId:    coupon.ID,
+		Name:        coupon.Name,
+		Type:        coupon.Type,
+		Amount:      coupon.Amount,
+		Percent:     coupon.Percent,
+		MaxDiscount: coupon.MaxDiscount,
+		Threshold:   coupon.Threshold,
+		ScopeType:   coupon.ScopeType,
+		ScopeIds:    coupon.ScopeIds,
+		StartTime:   start,
+		EndTime:     end,
+		Source:      source,
+	}
+	err = tx.Create(&userCoupon).Error
+	return
+}
+
+// IssueRegisterCoupons 给新注册用户发放注册赠送的优惠券，单张优惠券发放失败不影响注册
+func IssueRegisterCoupons(tx *gorm.DB, userId uint) error {
+	var coupons []shop.Coupon
+	if err := tx.Where("status = 1 and register_gift = 1").Find(&coupons).Error; err != nil {
+		return err
+	}
+	for _, c := range coupons {
+		if _, err := IssueCoupon(tx, c.ID, userId, shop.CouponSourceRegister); err != nil {
+			global.SugarLog.Warnf("注册赠送优惠券失败 userId:%d, couponId:%d, err:%v \n", userId, c.ID, err)
+		}
+	}
+	return nil
+}
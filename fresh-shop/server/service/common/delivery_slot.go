@@ -0,0 +1,53 @@
+package common
+
+import (
+	"errors"
+	"fresh-shop/server/model/business"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var ErrDeliverySlotFull = errors.New("该配送时段已约满，请选择其他时段")
+
+// DeliverySlotWindow 获取配送时段在指定日期的开始、结束时间
+func DeliverySlotWindow(slot business.DeliverySlot, day string) (start, end time.Time, err error) {
+	if start, err = time.ParseInLocation("2006-01-02 15:04", day+" "+slot.StartTime, time.Local); err != nil {
+		return
+	}
+	end, err = time.ParseInLocation("2006-01-02 15:04", day+" "+slot.EndTime, time.Local)
+	return
+}
+
+// ReserveDeliverySlot 在事务中占用配送时段容量，约满时返回 ErrDeliverySlotFull
+// 先保证当天的计数行存在，再使用 used < capacity 条件更新，并发预约不会超出容量
+func ReserveDeliverySlot(tx *gorm.DB, slot business.DeliverySlot, day string) error {
+	usage := business.DeliverySlotUsage{SlotId: slot.ID, Day: day}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&usage).Error; err != nil {
+		return err
+	}
+	capacity := 0
+	if slot.Capacity != nil {
+		capacity = *slot.Capacity
+	}
+	result := tx.Model(&business.DeliverySlotUsage{}).
+		Where("slot_id = ? and day = ? and used < ?", slot.ID, day, capacity).
+		Update("used", gorm.Expr("used + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeliverySlotFull
+	}
+	return nil
+}
+
+// ReleaseDeliverySlot 在事务中归还配送时段容量，订单取消时调用
+func ReleaseDeliverySlot(tx *gorm.DB, slotId uint, day string) error {
+	if slotId == 0 || day == "" {
+		return nil
+	}
+	return tx.Model(&business.DeliverySlotUsage{}).
+		Where("slot_id = ? and day = ? and used > 0", slotId, day).
+		Update("used", gorm.Expr("used - 1")).Error
+}
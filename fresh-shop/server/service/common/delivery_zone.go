@@ -0,0 +1,59 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/business"
+	"fresh-shop/server/utils"
+)
+
+// ParseZonePolygon 解析配送区域多边形顶点
+func ParseZonePolygon(polygon string) ([][2]float64, error) {
+	var points [][2]float64
+	if err := json.Unmarshal([]byte(polygon), &points); err != nil {
+		return nil, errors.New("多边形坐标格式错误")
+	}
+	if len(points) < 3 {
+		return nil, errors.New("多边形至少需要 3 个顶点")
+	}
+	return points, nil
+}
+
+// ZoneContains 判断坐标是否在配送区域内
+func ZoneContains(zone business.DeliveryZone, lng, lat float64) bool {
+	if zone.Type != nil && *zone.Type == 1 {
+		points, err := ParseZonePolygon(zone.Polygon)
+		if err != nil {
+			global.SugarLog.Errorf("配送区域多边形解析失败 zoneId:%d, err:%v \n", zone.ID, err)
+			return false
+		}
+		return utils.InPolygon(lng, lat, points)
+	}
+	if zone.Longitude == nil || zone.Latitude == nil || zone.Radius == nil {
+		return false
+	}
+	return utils.Distance(*zone.Longitude, *zone.Latitude, lng, lat) <= *zone.Radius
+}
+
+// MatchDeliveryZone 查找坐标所在的配送区域
+// 未配置任何启用的配送区域时不限制配送范围，deliverable 返回 true，zone 为 nil
+func MatchDeliveryZone(lng, lat float64) (zone *business.DeliveryZone, deliverable bool, err error) {
+	var zones []business.DeliveryZone
+	if err = global.DB.Where("status = 1").Order("sort asc, id asc").Find(&zones).Error; err != nil {
+		return nil, false, err
+	}
+	if len(zones) == 0 {
+		return nil, true, nil
+	}
+	// 没有坐标的地址无法判断是否可配送
+	if lng == 0 && lat == 0 {
+		return nil, false, nil
+	}
+	for i := range zones {
+		if ZoneContains(zones[i], lng, lat) {
+			return &zones[i], true, nil
+		}
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,69 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"fresh-shop/server/utils"
+	"github.com/go-redis/redis/v8"
+	"time"
+)
+
+// 安全密码错误锁定
+// 每个用户连续输错安全密码达到 safePasswordMaxFail 次后锁定 safePasswordLockTTL，锁定期间不再校验密码，
+// 校验成功后清除错误次数；未开启 Redis 时不做限制
+
+const (
+	safePasswordMaxFail int64 = 5
+	safePasswordLockTTL       = 30 * time.Minute
+)
+
+var ErrSafePasswordLocked = errors.New("安全密码错误次数过多，请稍后再试")
+
+func safePasswordFailKey(userId uint) string {
+	return fmt.Sprintf("safe_password:fail:%d", userId)
+}
+
+// CheckSafePassword 校验用户安全密码，先检查是否处于锁定期，校验失败累计错误次数
+// 余额支付和修改安全密码共用同一个错误计数
+func CheckSafePassword(userId uint, password, hash string) error {
+	if global.Redis == nil {
+		if !utils.BcryptCheck(password, hash) {
+			return errors.New("安全密码错误")
+		}
+		return nil
+	}
+	ctx := context.Background()
+	key := safePasswordFailKey(userId)
+	times, err := global.Redis.Get(ctx, key).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		global.SugarLog.Errorf("Redis 查询安全密码错误次数异常 userId:%d, err:%v \n", userId, err)
+	}
+	if times >= safePasswordMaxFail {
+		return ErrSafePasswordLocked
+	}
+	if utils.BcryptCheck(password, hash) {
+		if times > 0 {
+			if err = global.Redis.Del(ctx, key).Err(); err != nil {
+				global.SugarLog.Errorf("Redis 清除安全密码错误次数异常 userId:%d, err:%v \n", userId, err)
+			}
+		}
+		return nil
+	}
+	times, err = global.Redis.Incr(ctx, key).Result()
+	if err != nil {
+		global.SugarLog.Errorf("Redis 记录安全密码错误次数异常 userId:%d, err:%v \n", userId, err)
+		return errors.New("安全密码错误")
+	}
+	// 首次错误时开始计数窗口，达到上限时重新计时锁定
+	if times == 1 || times >= safePasswordMaxFail {
+		if err = global.Redis.Expire(ctx, key, safePasswordLockTTL).Err(); err != nil {
+			global.SugarLog.Errorf("Redis 设置安全密码锁定时间异常 userId:%d, err:%v \n", userId, err)
+		}
+	}
+	if left := safePasswordMaxFail - times; left > 0 {
+		return fmt.Errorf("安全密码错误，还可尝试 %d 次", left)
+	}
+	return ErrSafePasswordLocked
+}
@@ -0,0 +1,70 @@
+package common
+
+import (
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/account"
+	sysModel "fresh-shop/server/model/system"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// CommissionLevels 分销层级数，团队统计和佣金都只计算到二级
+const CommissionLevels = 2
+
+// IncTeamMember 在注册事务中给一级、二级上级增加团队人数，用户的 inviter_id 需要已经写入
+func IncTeamMember(tx *gorm.DB, userId, inviterId uint) error {
+	if inviterId == 0 || inviterId == userId {
+		return nil
+	}
+	if err := upsertUserTeam(tx, inviterId, map[string]interface{}{
+		"direct_num": gorm.Expr("direct_num + 1"),
+		"team_num":   gorm.Expr("team_num + 1"),
+	}); err != nil {
+		return err
+	}
+	var parentId uint
+	if err := tx.Model(&sysModel.SysUser{}).Where("id = ?", inviterId).Pluck("inviter_id", &parentId).Error; err != nil {
+		return err
+	}
+	if parentId == 0 || parentId == userId {
+		return nil
+	}
+	return upsertUserTeam(tx, parentId, map[string]interface{}{"team_num": gorm.Expr("team_num + 1")})
+}
+
+// GetInviterChain 获取用户的上级链，下标 0 为一级上级，最多 CommissionLevels 级
+func GetInviterChain(tx *gorm.DB, userId uint) (chain []uint, err error) {
+	current := userId
+	for i := 0; i < CommissionLevels; i++ {
+		var inviterId uint
+		if err = tx.Model(&sysModel.SysUser{}).Where("id = ?", current).Pluck("inviter_id", &inviterId).Error; err != nil {
+			return
+		}
+		if inviterId == 0 || inviterId == userId {
+			return
+		}
+		chain = append(chain, inviterId)
+		current = inviterId
+	}
+	return
+}
+
+// AddTeamAmount 给用户累加团队业绩和已结算佣金，amount 为负数时扣减
+func AddTeamAmount(tx *gorm.DB, userId uint, teamAmount, commission float64) error {
+	return upsertUserTeam(tx, userId, map[string]interface{}{
+		"team_amount": gorm.Expr("team_amount + ?", teamAmount),
+		"commission":  gorm.Expr("commission + ?", commission),
+	})
+}
+
+// upsertUserTeam 团队统计不存在时先创建再更新，并发下依赖 user_id 唯一索引
+func upsertUserTeam(tx *gorm.DB, userId uint, updates map[string]interface{}) error {
+	team := account.UserTeam{UserId: userId}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&team).Error; err != nil {
+		global.SugarLog.Errorf("创建团队统计失败 userId:%d, err:%s", userId, err.Error())
+		return err
+	}
+	updates["updated_at"] = time.Now()
+	return tx.Model(&account.UserTeam{}).Where("user_id = ?", userId).Updates(updates).Error
+}
@@ -0,0 +1,349 @@
+package payment
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AlipayProvider 支付宝手机网站支付，接口签名方式为 RSA2
+type AlipayProvider struct {
+}
+
+// alipayResponse 支付宝接口公共响应参数
+type alipayResponse struct {
+	Code    string `json:"code"`
+	Msg     string `json:"msg"`
+	SubCode string `json:"sub_code"`
+	SubMsg  string `json:"sub_msg"`
+}
+
+// Create 返回拼接好签名的支付跳转地址
+func (p *AlipayProvider) Create(req CreateReq) (interface{}, error) {
+	cfg := global.Config.Payment.Alipay
+	params, err := alipayParams("alipay.trade.wap.pay", map[string]interface{}{
+		"out_trade_no":    req.OrderSn,
+		"total_amount":    fmt.Sprintf("%.2f", req.Amount),
+		"subject":         req.Subject,
+		"product_code":    "QUICK_WAP_WAY",
+		"time_expire":     req.ExpireAt.Format("2006-01-02 15:04:05"),
+		"passback_params": url.QueryEscape(strconv.Itoa(int(req.OrderId))),
+	})
+	if err != nil {
+		return nil, err
+	}
+	params.Set("notify_url", cfg.NotifyURL)
+	if cfg.ReturnURL != "" {
+		params.Set("return_url", cfg.ReturnURL)
+	}
+	if err = alipaySign(params); err != nil {
+		return nil, err
+	}
+	return map[string]string{"payUrl": cfg.GetGateway() + "?" + params.Encode()}, nil
+}
+
+func (p *AlipayProvider) Query(orderSn string) (result TradeResult, err error) {
+	result = TradeResult{Payment: Alipay, OrderSn: orderSn}
+	var rsp struct {
+		alipayResponse
+		TradeNo      string `json:"trade_no"`
+		TradeStatus  string `json:"trade_status"`
+		TotalAmount  string `json:"total_amount"`
+		SendPayDate  string `json:"send_pay_date"`
+		BuyerUserId  string `json:"buyer_user_id"`
+		PassbackData string `json:"passback_params"`
+	}
+	if err = alipayCall("alipay.trade.query", map[string]interface{}{"out_trade_no": orderSn}, &rsp); err != nil {
+		return
+	}
+	if rsp.Code != "10000" {
+		if rsp.SubCode == "ACQ.TRADE_NOT_EXIST" {
+			result.State = "WAIT_BUYER_PAY"
+			return result, nil
+		}
+		return result, fmt.Errorf("%s %s", rsp.SubCode, rsp.SubMsg)
+	}
+	result.State = rsp.TradeStatus
+	result.TransactionId = rsp.TradeNo
+	result.Buyer = rsp.BuyerUserId
+	result.Attach = rsp.PassbackData
+	if rsp.TradeStatus != "TRADE_SUCCESS" && rsp.TradeStatus != "TRADE_FINISHED" {
+		return result, nil
+	}
+	result.Paid = true
+	result.Amount, _ = strconv.ParseFloat(rsp.TotalAmount, 64)
+	result.PayTime, _ = time.ParseInLocation("2006-01-02 15:04:05", rsp.SendPayDate, time.Local)
+	return result, nil
+}
+
+func (p *AlipayProvider) Close(orderSn string) error {
+	var rsp alipayResponse
+	if err := alipayCall("alipay.trade.close", map[string]interface{}{"out_trade_no": orderSn}, &rsp); err != nil {
+		return err
+	}
+	switch {
+	case rsp.Code == "10000", rsp.SubCode == "ACQ.TRADE_NOT_EXIST":
+		return nil
+	case rsp.SubCode == "ACQ.TRADE_STATUS_ERROR":
+		// 交易已支付或已关闭，查询确认
+		result, err := p.Query(orderSn)
+		if err != nil {
+			return err
+		}
+		if result.Paid {
+			return ErrOrderPaid
+		}
+		return nil
+	}
+	global.SugarLog.Errorf("支付宝 - 关闭订单发生错误 orderSn:%s, subCode:%s, subMsg:%s", orderSn, rsp.SubCode, rsp.SubMsg)
+	return errors.New(rsp.SubMsg)
+}
+
+// Refund 支付宝退款同步返回结果，成功即到账
+func (p *AlipayProvider) Refund(req RefundReq) (result RefundResult, err error) {
+	log := fmt.Sprintf("支付宝 - 申请退款 orderSn:%s, refundSn:%s, amount:%.2f, ", req.OrderSn, req.RefundSn, req.Amount)
+	result = RefundResult{OrderSn: req.OrderSn, RefundSn: req.RefundSn}
+	var rsp struct {
+		alipayResponse
+		FundChange string `json:"fund_change"`
+	}
+	err = alipayCall("alipay.trade.refund", map[string]interface{}{
+		"out_trade_no":   req.OrderSn,
+		"out_request_no": req.RefundSn,
+		"refund_amount":  fmt.Sprintf("%.2f", req.Amount),
+		"refund_reason":  req.Reason,
+	}, &rsp)
+	if err != nil {
+		global.SugarLog.Errorf(log+"请求失败, err:%s", err.Error())
+		return
+	}
+	if rsp.Code != "10000" {
+		global.SugarLog.Errorf(log+"业务失败, subCode:%s, subMsg:%s", rsp.SubCode, rsp.SubMsg)
+		return result, errors.New(rsp.SubMsg)
+	}
+	result.Status = RefundSuccess
+	result.State = rsp.FundChange
+	global.SugarLog.Infof(log+"退款成功, fundChange:%s", rsp.FundChange)
+	return result, nil
+}
+
+func (p *AlipayProvider) VerifyPayNotify(r *http.Request) (result TradeResult, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	form := r.PostForm
+	if err = alipayVerify(form); err != nil {
+		global.SugarLog.Errorf("支付宝回调签名验证失败! %v, form: %v \n", err, form)
+		return result, errors.New("签名失败")
+	}
+	result = TradeResult{
+		Payment:       Alipay,
+		AppId:         form.Get("app_id"),
+		OrderSn:       form.Get("out_trade_no"),
+		TransactionId: form.Get("trade_no"),
+		Buyer:         form.Get("buyer_id"),
+		State:         form.Get("trade_status"),
+	}
+	result.Attach, _ = url.QueryUnescape(form.Get("passback_params"))
+	if result.State != "TRADE_SUCCESS" && result.State != "TRADE_FINISHED" {
+		return result, nil
+	}
+	result.Paid = true
+	if result.Amount, err = strconv.ParseFloat(form.Get("total_amount"), 64); err != nil {
+		return result, fmt.Errorf("支付金额格式错误 total_amount:%s", form.Get("total_amount"))
+	}
+	if result.PayTime, err = time.ParseInLocation("2006-01-02 15:04:05", form.Get("gmt_payment"), time.Local); err != nil {
+		return result, fmt.Errorf("支付时间格式错误 gmt_payment:%s", form.Get("gmt_payment"))
+	}
+	return result, nil
+}
+
+// VerifyRefundNotify 支付宝退款结果在申请退款时同步返回，没有退款通知
+func (p *AlipayProvider) VerifyRefundNotify(r *http.Request) (RefundResult, error) {
+	return RefundResult{}, ErrUnsupported
+}
+
+func (p *AlipayProvider) NotifyAck(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err != nil {
+		_, _ = w.Write([]byte("fail"))
+		return
+	}
+	_, _ = w.Write([]byte("success"))
+}
+
+// alipayParams 组装公共请求参数
+func alipayParams(method string, bizContent map[string]interface{}) (url.Values, error) {
+	biz, err := json.Marshal(bizContent)
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	params.Set("app_id", global.Config.Payment.Alipay.AppId)
+	params.Set("method", method)
+	params.Set("format", "JSON")
+	params.Set("charset", "utf-8")
+	params.Set("sign_type", "RSA2")
+	params.Set("timestamp", time.Now().Format("2006-01-02 15:04:05"))
+	params.Set("version", "1.0")
+	params.Set("biz_content", string(biz))
+	return params, nil
+}
+
+// alipayCall 调用支付宝接口并校验响应签名，rsp 为 {method}_response 节点的内容
+func alipayCall(method string, bizContent map[string]interface{}, rsp interface{}) error {
+	params, err := alipayParams(method, bizContent)
+	if err != nil {
+		return err
+	}
+	if err = alipaySign(params); err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.PostForm(global.Config.Payment.Alipay.GetGateway(), params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var body map[string]json.RawMessage
+	if err = json.Unmarshal(raw, &body); err != nil {
+		return fmt.Errorf("解析响应失败 raw:%s, err:%v", string(raw), err)
+	}
+	node, ok := body[strings.ReplaceAll(method, ".", "_")+"_response"]
+	if !ok {
+		return fmt.Errorf("响应格式错误 raw:%s", string(raw))
+	}
+	var sign string
+	_ = json.Unmarshal(body["sign"], &sign)
+	// 接口异常时支付宝可能不返回签名，只有签名存在时校验
+	if sign != "" {
+		if err = alipayVerifyContent(string(node), sign); err != nil {
+			return fmt.Errorf("响应验签失败 %v", err)
+		}
+	}
+	return json.Unmarshal(node, rsp)
+}
+
+// alipaySign 对请求参数签名，并写入 sign 参数
+func alipaySign(params url.Values) error {
+	key, err := alipayPrivateKey()
+	if err != nil {
+		return err
+	}
+	hashed := sha256.Sum256([]byte(alipaySignContent(params)))
+	sign, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return err
+	}
+	params.Set("sign", base64.StdEncoding.EncodeToString(sign))
+	return nil
+}
+
+// alipayVerify 校验异步通知签名，sign 和 sign_type 不参与签名
+func alipayVerify(form url.Values) error {
+	sign := form.Get("sign")
+	if sign == "" {
+		return errors.New("缺少签名")
+	}
+	params := url.Values{}
+	for k, v := range form {
+		if k != "sign" && k != "sign_type" {
+			params[k] = v
+		}
+	}
+	return alipayVerifyContent(alipaySignContent(params), sign)
+}
+
+func alipayVerifyContent(content, sign string) error {
+	key, err := alipayPublicKey()
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(sign)
+	if err != nil {
+		return err
+	}
+	hashed := sha256.Sum256([]byte(content))
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], sig)
+}
+
+// alipaySignContent 参数按 key 升序排列，去掉 sign 和空值后用 & 拼接
+func alipaySignContent(params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if k != "sign" && params.Get(k) != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+params.Get(k))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// alipayPrivateKey 解析应用私钥，支持 PKCS1 和 PKCS8
+func alipayPrivateKey() (*rsa.PrivateKey, error) {
+	der, err := pemBytes(global.Config.Payment.Alipay.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("应用私钥格式错误 %v", err)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("应用私钥格式错误 %v", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("应用私钥不是 RSA 密钥")
+	}
+	return rsaKey, nil
+}
+
+// alipayPublicKey 解析支付宝公钥
+func alipayPublicKey() (*rsa.PublicKey, error) {
+	der, err := pemBytes(global.Config.Payment.Alipay.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("支付宝公钥格式错误 %v", err)
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("支付宝公钥格式错误 %v", err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("支付宝公钥不是 RSA 密钥")
+	}
+	return rsaKey, nil
+}
+
+// pemBytes 密钥可以是 PEM 格式，也可以是支付宝后台复制的去掉头尾的 base64
+func pemBytes(key string) ([]byte, error) {
+	if block, _ := pem.Decode([]byte(key)); block != nil {
+		return block.Bytes, nil
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+}
@@ -0,0 +1,171 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"net/http"
+	"time"
+)
+
+// 支付方式，对应 Order.Payment
+const (
+	Balance = 1 // 余额
+	Wechat  = 2 // 微信
+	Alipay  = 3 // 支付宝
+	Point   = 4 // 积分
+)
+
+// 支付渠道名称，用于区分回调地址
+const (
+	ChannelWechat  = "wechat"
+	ChannelAlipay  = "alipay"
+	ChannelSandbox = "sandbox"
+)
+
+// 退款状态，与 Order.StatusRefund 保持一致
+const (
+	RefundProcessing = 1 // 退款中
+	RefundSuccess    = 2 // 退款成功
+	RefundFailed     = 3 // 退款失败
+)
+
+var (
+	ErrOrderPaid   = errors.New("订单已支付")
+	ErrUnsupported = errors.New("不支持的支付方式")
+)
+
+// CreateReq 发起支付参数
+type CreateReq struct {
+	OrderId  uint
+	OrderSn  string
+	Amount   float64 // 支付金额(元)
+	Subject  string  // 订单标题
+	OpenId   string  // 微信 JSAPI 支付的用户 openid
+	ClientIP string
+	ExpireAt time.Time // 支付过期时间，与订单超时取消时间保持一致
+}
+
+// TradeResult 交易结果，查询订单和支付通知共用
+type TradeResult struct {
+	Payment       int       // 支付方式
+	OrderSn       string    // 商户订单号
+	TransactionId string    // 渠道交易号
+	Paid          bool      // 是否已支付
+	Amount        float64   // 实付金额(元)
+	PayTime       time.Time // 支付完成时间
+	Buyer         string    // 付款用户标识 微信为 openid 支付宝为 buyer_id
+	Attach        string    // 发起支付时的附加数据
+	State         string    // 渠道原始交易状态
+	AppId         string    // 收款应用 id，微信为公众号/小程序 appid，支付宝为 app_id
+	MchId         string    // 收款商户号，支付宝为空
+}
+
+// RefundReq 退款参数
+type RefundReq struct {
+	OrderSn  string
+	RefundSn string
+	Total    float64 // 订单实付金额(元)
+	Amount   float64 // 退款金额(元)
+	Reason   string
+}
+
+// RefundResult 退款结果，申请退款和退款通知共用
+type RefundResult struct {
+	OrderSn  string
+	RefundSn string
+	Status   int    // RefundProcessing RefundSuccess RefundFailed
+	State    string // 渠道原始退款状态
+}
+
+// PaymentProvider 支付渠道
+type PaymentProvider interface {
+	// Create 发起支付，返回客户端调起支付所需的参数
+	Create(req CreateReq) (interface{}, error)
+	// Query 查询交易结果
+	Query(orderSn string) (TradeResult, error)
+	// Close 关闭未支付的交易，交易不存在视为成功，已支付返回 ErrOrderPaid
+	Close(orderSn string) error
+	// Refund 申请退款，同步到账的渠道直接返回退款成功
+	Refund(req RefundReq) (RefundResult, error)
+	// VerifyPayNotify 校验并解析支付结果通知
+	VerifyPayNotify(r *http.Request) (TradeResult, error)
+	// VerifyRefundNotify 校验并解析退款结果通知
+	VerifyRefundNotify(r *http.Request) (RefundResult, error)
+	// NotifyAck 按渠道要求的格式应答通知，err 不为空时渠道会重试
+	NotifyAck(w http.ResponseWriter, err error)
+}
+
+// BillDownloader 支持下载交易账单的支付渠道，用于每日对账
+type BillDownloader interface {
+	// DownloadBill 下载指定日期的全部交易账单，返回微信账单格式的 CSV 内容
+	DownloadBill(date time.Time) ([]byte, error)
+}
+
+// GetProvider 根据订单支付方式获取支付渠道，开启沙箱后微信、支付宝都使用沙箱渠道
+func GetProvider(payment int) (PaymentProvider, error) {
+	if payment != Wechat && payment != Alipay {
+		return nil, ErrUnsupported
+	}
+	if global.Config.Payment.Sandbox.Enable {
+		return &SandboxProvider{payment: payment}, nil
+	}
+	if payment == Wechat {
+		return wechatProvider(), nil
+	}
+	return &AlipayProvider{}, nil
+}
+
+// GetChannel 根据回调地址中的渠道名称获取支付渠道
+func GetChannel(channel string) (PaymentProvider, error) {
+	switch channel {
+	case ChannelWechat:
+		return wechatProvider(), nil
+	case ChannelAlipay:
+		return &AlipayProvider{}, nil
+	case ChannelSandbox:
+		if global.Config.Payment.Sandbox.Enable {
+			return &SandboxProvider{}, nil
+		}
+	}
+	return nil, ErrUnsupported
+}
+
+// wechatProvider 根据配置的接口版本选择微信支付 APIv2 或 APIv3
+func wechatProvider() PaymentProvider {
+	if global.Config.WechatPay.IsV3() {
+		return &WechatV3Provider{}
+	}
+	return &WechatProvider{}
+}
+
+// ChannelOf 订单支付方式当前使用的支付渠道名称
+func ChannelOf(payment int) string {
+	if global.Config.Payment.Sandbox.Enable {
+		return ChannelSandbox
+	}
+	if payment == Alipay {
+		return ChannelAlipay
+	}
+	return ChannelWechat
+}
+
+// CheckMerchant 校验回调通知中的收款应用和商户号与配置一致，防止其他商户的通知被当作本商户的支付
+func CheckMerchant(channel string, result TradeResult) error {
+	switch channel {
+	case ChannelWechat:
+		if result.AppId != global.Config.Wechat.Appid || result.MchId != global.Config.WechatPay.MchId {
+			return fmt.Errorf("appid 或商户号不匹配 appid:%s, mch_id:%s", result.AppId, result.MchId)
+		}
+	case ChannelAlipay:
+		if result.AppId != global.Config.Payment.Alipay.AppId {
+			return fmt.Errorf("app_id 不匹配 app_id:%s", result.AppId)
+		}
+	}
+	return nil
+}
+
+// IsOnline 是否为需要第三方渠道的支付方式
+func IsOnline(payment int) bool {
+	return payment == Wechat || payment == Alipay
+}
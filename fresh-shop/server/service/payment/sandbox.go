@@ -0,0 +1,243 @@
+package payment
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"fresh-shop/server/utils"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SandboxProvider 本地沙箱支付，模拟用户付款和渠道回调，用于没有商户号时跑通完整下单流程
+// 交易数据只保存在内存中，服务重启后丢失
+type SandboxProvider struct {
+	payment int // 被模拟的支付方式
+}
+
+// 沙箱交易状态
+const (
+	sandboxWaitPay = iota
+	sandboxPaid
+	sandboxClosed
+)
+
+type sandboxTrade struct {
+	payment       int
+	orderSn       string
+	amount        float64
+	attach        string
+	status        int
+	transactionId string
+	payTime       time.Time
+}
+
+// SandboxNotify 沙箱回调通知内容，支付通知和退款通知共用
+type SandboxNotify struct {
+	Payment       int     `json:"payment"`
+	OrderSn       string  `json:"orderSn"`
+	RefundSn      string  `json:"refundSn,omitempty"`
+	TransactionId string  `json:"transactionId,omitempty"`
+	Amount        float64 `json:"amount"`
+	PayTime       int64   `json:"payTime,omitempty"`
+	Attach        string  `json:"attach,omitempty"`
+	Status        string  `json:"status"`
+	Nonce         string  `json:"nonce"`
+	Sign          string  `json:"sign"`
+}
+
+var sandboxTrades = struct {
+	sync.Mutex
+	m map[string]*sandboxTrade
+}{m: make(map[string]*sandboxTrade)}
+
+func (p *SandboxProvider) Create(req CreateReq) (interface{}, error) {
+	sandboxTrades.Lock()
+	defer sandboxTrades.Unlock()
+	if t, ok := sandboxTrades.m[req.OrderSn]; ok && t.status == sandboxPaid {
+		return nil, ErrOrderPaid
+	}
+	sandboxTrades.m[req.OrderSn] = &sandboxTrade{
+		payment: p.payment,
+		orderSn: req.OrderSn,
+		amount:  req.Amount,
+		attach:  strconv.Itoa(int(req.OrderId)),
+	}
+	return map[string]interface{}{"sandbox": true, "orderSn": req.OrderSn, "amount": req.Amount}, nil
+}
+
+func (p *SandboxProvider) Query(orderSn string) (TradeResult, error) {
+	sandboxTrades.Lock()
+	defer sandboxTrades.Unlock()
+	result := TradeResult{Payment: p.payment, OrderSn: orderSn, State: "NOTPAY"}
+	t, ok := sandboxTrades.m[orderSn]
+	if !ok {
+		return result, nil
+	}
+	return t.result(), nil
+}
+
+func (p *SandboxProvider) Close(orderSn string) error {
+	sandboxTrades.Lock()
+	defer sandboxTrades.Unlock()
+	t, ok := sandboxTrades.m[orderSn]
+	if !ok {
+		return nil
+	}
+	if t.status == sandboxPaid {
+		return ErrOrderPaid
+	}
+	t.status = sandboxClosed
+	return nil
+}
+
+// Refund 沙箱退款异步通知退款成功，与微信退款流程一致
+func (p *SandboxProvider) Refund(req RefundReq) (RefundResult, error) {
+	result := RefundResult{OrderSn: req.OrderSn, RefundSn: req.RefundSn, Status: RefundProcessing}
+	sandboxTrades.Lock()
+	t, ok := sandboxTrades.m[req.OrderSn]
+	sandboxTrades.Unlock()
+	payment := p.payment
+	if ok {
+		payment = t.payment
+	}
+	n := SandboxNotify{Payment: payment, OrderSn: req.OrderSn, RefundSn: req.RefundSn, Amount: req.Amount, Status: "SUCCESS"}
+	go sendSandboxNotify(global.Config.Payment.Sandbox.RefundNotifyURL, n)
+	return result, nil
+}
+
+func (p *SandboxProvider) VerifyPayNotify(r *http.Request) (result TradeResult, err error) {
+	var n SandboxNotify
+	if err = decodeSandboxNotify(r, &n); err != nil {
+		return
+	}
+	return TradeResult{
+		Payment:       n.Payment,
+		OrderSn:       n.OrderSn,
+		TransactionId: n.TransactionId,
+		Paid:          n.Status == "SUCCESS",
+		Amount:        n.Amount,
+		PayTime:       time.Unix(n.PayTime, 0),
+		Attach:        n.Attach,
+		State:         n.Status,
+	}, nil
+}
+
+func (p *SandboxProvider) VerifyRefundNotify(r *http.Request) (result RefundResult, err error) {
+	var n SandboxNotify
+	if err = decodeSandboxNotify(r, &n); err != nil {
+		return
+	}
+	result = RefundResult{OrderSn: n.OrderSn, RefundSn: n.RefundSn, State: n.Status, Status: RefundFailed}
+	if n.Status == "SUCCESS" {
+		result.Status = RefundSuccess
+	}
+	return
+}
+
+func (p *SandboxProvider) NotifyAck(w http.ResponseWriter, err error) {
+	resp := map[string]string{"code": "SUCCESS"}
+	if err != nil {
+		resp = map[string]string{"code": "FAIL", "message": err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// SandboxPay 模拟用户完成付款，随后异步发送支付成功通知
+func SandboxPay(orderSn string) error {
+	if !global.Config.Payment.Sandbox.Enable {
+		return errors.New("沙箱支付未开启")
+	}
+	sandboxTrades.Lock()
+	t, ok := sandboxTrades.m[orderSn]
+	if !ok || t.status == sandboxClosed {
+		sandboxTrades.Unlock()
+		return errors.New("交易不存在或已关闭")
+	}
+	if t.status == sandboxWaitPay {
+		t.status = sandboxPaid
+		t.transactionId = utils.GenerateOrderNumber("SB")
+		t.payTime = time.Now()
+	}
+	n := SandboxNotify{
+		Payment:       t.payment,
+		OrderSn:       t.orderSn,
+		TransactionId: t.transactionId,
+		Amount:        t.amount,
+		PayTime:       t.payTime.Unix(),
+		Attach:        t.attach,
+		Status:        "SUCCESS",
+	}
+	sandboxTrades.Unlock()
+	go sendSandboxNotify(global.Config.Payment.Sandbox.NotifyURL, n)
+	return nil
+}
+
+func (t *sandboxTrade) result() TradeResult {
+	result := TradeResult{Payment: t.payment, OrderSn: t.orderSn, Attach: t.attach, State: "NOTPAY"}
+	switch t.status {
+	case sandboxPaid:
+		result.Paid = true
+		result.State = "SUCCESS"
+		result.Amount = t.amount
+		result.PayTime = t.payTime
+		result.TransactionId = t.transactionId
+	case sandboxClosed:
+		result.State = "CLOSED"
+	}
+	return result
+}
+
+// sendSandboxNotify 签名后发送回调通知，失败时最多重试 3 次
+func sendSandboxNotify(url string, n SandboxNotify) {
+	if url == "" {
+		global.SugarLog.Errorf("沙箱支付 - 未配置回调地址, orderSn:%s", n.OrderSn)
+		return
+	}
+	n.Nonce = utils.GenerateInviteCode(16)
+	n.Sign = sandboxSign(n)
+	body, _ := json.Marshal(n)
+	client := &http.Client{Timeout: 10 * time.Second}
+	for i := 0; i < 3; i++ {
+		time.Sleep(time.Duration(i+1) * time.Second)
+		resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			global.SugarLog.Errorf("沙箱支付 - 发送回调失败 url:%s, orderSn:%s, err:%s", url, n.OrderSn, err.Error())
+			continue
+		}
+		var ack map[string]string
+		_ = json.NewDecoder(resp.Body).Decode(&ack)
+		resp.Body.Close()
+		if ack["code"] == "SUCCESS" {
+			return
+		}
+		global.SugarLog.Errorf("沙箱支付 - 回调处理失败 url:%s, orderSn:%s, ack:%v", url, n.OrderSn, ack)
+	}
+}
+
+func decodeSandboxNotify(r *http.Request, n *SandboxNotify) error {
+	if err := json.NewDecoder(r.Body).Decode(n); err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(n.Sign), []byte(sandboxSign(*n))) {
+		return errors.New("签名失败")
+	}
+	return nil
+}
+
+// sandboxSign 使用配置的密钥对通知内容做 HMAC-SHA256 签名
+func sandboxSign(n SandboxNotify) string {
+	content := fmt.Sprintf("%d|%s|%s|%s|%.2f|%d|%s|%s|%s", n.Payment, n.OrderSn, n.RefundSn, n.TransactionId, n.Amount, n.PayTime, n.Attach, n.Status, n.Nonce)
+	mac := hmac.New(sha256.New, []byte(global.Config.Payment.Sandbox.Key))
+	mac.Write([]byte(content))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,189 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"fresh-shop/server/service/wechat"
+	"fresh-shop/server/utils"
+	"github.com/silenceper/wechat/v2/pay/notify"
+	orderPay "github.com/silenceper/wechat/v2/pay/order"
+	"github.com/silenceper/wechat/v2/util"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WechatProvider 微信支付 APIv2 JSAPI
+type WechatProvider struct {
+}
+
+func (p *WechatProvider) Create(req CreateReq) (interface{}, error) {
+	err, config := wechat.JSAPIPay(req.OpenId, req.OrderSn, req.OrderId, req.Amount, req.ClientIP)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (p *WechatProvider) Query(orderSn string) (result TradeResult, err error) {
+	res, err := global.WxPay.GetOrder().QueryOrder(&orderPay.QueryParams{OutTradeNo: orderSn})
+	if err != nil {
+		// 订单不存在说明用户没有发起过微信支付
+		if res.ErrCode != nil && *res.ErrCode == "ORDERNOTEXIST" {
+			return TradeResult{Payment: Wechat, OrderSn: orderSn, State: "NOTPAY"}, nil
+		}
+		global.SugarLog.Errorf("微信支付 - 查询订单发生错误 orderSn:%s, err:%s", orderSn, err.Error())
+		return
+	}
+	return wechatTradeResult(res)
+}
+
+func (p *WechatProvider) Close(orderSn string) error {
+	err := wechat.CloseOrder(orderSn)
+	if errors.Is(err, wechat.ErrOrderPaid) {
+		return ErrOrderPaid
+	}
+	return err
+}
+
+func (p *WechatProvider) Refund(req RefundReq) (RefundResult, error) {
+	result := RefundResult{OrderSn: req.OrderSn, RefundSn: req.RefundSn, Status: RefundProcessing}
+	if err := wechat.Refund(req.OrderSn, req.RefundSn, req.Total, req.Amount, req.Reason); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+func (p *WechatProvider) VerifyPayNotify(r *http.Request) (result TradeResult, err error) {
+	var req notify.PaidResult
+	if err = xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		return
+	}
+	if ok := global.WxPay.GetNotify().PaidVerifySign(req); !ok {
+		global.SugarLog.Errorf("支付回调签名验证失败! %#v \n", req)
+		return result, errors.New("签名失败")
+	}
+	if req.ReturnCode == nil || *req.ReturnCode != "SUCCESS" {
+		return result, errors.New("通知返回失败")
+	}
+	if req.ResultCode == nil || *req.ResultCode != "SUCCESS" {
+		return result, errors.New("支付结果失败")
+	}
+	if req.OutTradeNo == nil || req.TotalFee == nil || req.TimeEnd == nil {
+		return result, errors.New("通知参数不完整")
+	}
+	req.TradeState = utils.Pointer("SUCCESS") // 支付通知没有 trade_state，通知即为支付成功
+	return wechatTradeResult(req)
+}
+
+func (p *WechatProvider) VerifyRefundNotify(r *http.Request) (result RefundResult, err error) {
+	var req notify.RefundedResult
+	if err = xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		return
+	}
+	info, err := (&wechat.RefundService{}).DecryptRefundNotify(&req)
+	if err != nil {
+		return
+	}
+	result = RefundResult{OrderSn: *info.OutTradeNO, RefundSn: *info.OutRefundNO, State: *info.RefundStatus, Status: RefundFailed}
+	// 其余为退款异常 CHANGE 或退款关闭 REFUNDCLOSE
+	if *info.RefundStatus == "SUCCESS" {
+		result.Status = RefundSuccess
+	}
+	return
+}
+
+func (p *WechatProvider) NotifyAck(w http.ResponseWriter, err error) {
+	resp := notify.PaidResp{ReturnCode: "SUCCESS", ReturnMsg: "OK"}
+	if err != nil {
+		resp = notify.PaidResp{ReturnCode: "FAIL", ReturnMsg: err.Error()}
+	}
+	body, _ := xml.Marshal(struct {
+		XMLName xml.Name `xml:"xml"`
+		notify.PaidResp
+	}{PaidResp: resp})
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+type wechatBillParams struct {
+	XMLName  xml.Name `xml:"xml"`
+	AppID    string   `xml:"appid"`
+	MchID    string   `xml:"mch_id"`
+	NonceStr string   `xml:"nonce_str"`
+	Sign     string   `xml:"sign"`
+	BillDate string   `xml:"bill_date"`
+	BillType string   `xml:"bill_type"`
+}
+
+// DownloadBill 下载交易账单，成功时直接返回账单文本，失败时返回 XML 错误信息
+func (p *WechatProvider) DownloadBill(date time.Time) ([]byte, error) {
+	params := map[string]string{
+		"appid":     global.Config.Wechat.Appid,
+		"mch_id":    global.Config.WechatPay.MchId,
+		"nonce_str": util.RandomStr(32),
+		"bill_date": date.Format("20060102"),
+		"bill_type": "ALL",
+	}
+	sign, err := util.ParamSign(params, global.Config.WechatPay.ApiV2Key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := util.PostXML("https://api.mch.weixin.qq.com/pay/downloadbill", wechatBillParams{
+		AppID:    params["appid"],
+		MchID:    params["mch_id"],
+		NonceStr: params["nonce_str"],
+		Sign:     sign,
+		BillDate: params["bill_date"],
+		BillType: params["bill_type"],
+	})
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("<xml>")) {
+		var rsp struct {
+			ReturnCode string `xml:"return_code"`
+			ReturnMsg  string `xml:"return_msg"`
+		}
+		_ = xml.Unmarshal(raw, &rsp)
+		return nil, fmt.Errorf("下载账单失败 return_code:%s, return_msg:%s", rsp.ReturnCode, rsp.ReturnMsg)
+	}
+	return raw, nil
+}
+
+// wechatTradeResult 将微信查询订单和支付通知的结果转换为交易结果
+func wechatTradeResult(res notify.PaidResult) (result TradeResult, err error) {
+	result = TradeResult{
+		Payment: Wechat,
+		OrderSn: stringValue(res.OutTradeNo),
+		State:   stringValue(res.TradeState),
+		AppId:   stringValue(res.AppID),
+		MchId:   stringValue(res.MchID),
+	}
+	if result.State != "SUCCESS" {
+		return result, nil
+	}
+	if res.TotalFee == nil || res.TimeEnd == nil {
+		return result, errors.New("交易结果参数不完整")
+	}
+	result.Paid = true
+	result.Amount, _ = strconv.ParseFloat(fmt.Sprintf("%.2f", float64(*res.TotalFee)/100), 64)
+	if result.PayTime, err = time.ParseInLocation("20060102150405", *res.TimeEnd, time.Local); err != nil {
+		return result, fmt.Errorf("支付时间格式错误 time_end:%s", *res.TimeEnd)
+	}
+	result.TransactionId = stringValue(res.TransactionID)
+	result.Buyer = stringValue(res.OpenID)
+	result.Attach = stringValue(res.Attach)
+	return result, nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,568 @@
+package payment
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"fresh-shop/server/utils"
+	orderPay "github.com/silenceper/wechat/v2/pay/order"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	wechatV3Host = "https://api.mch.weixin.qq.com"
+	// 平台证书缓存有效期，过期后下次使用时重新下载
+	wechatV3CertTTL = 12 * time.Hour
+	// 遇到未知证书序列号时重新下载平台证书的最小间隔，防止伪造的回调频繁触发下载
+	wechatV3CertRetry = time.Minute
+	// 回调通知时间戳允许的最大偏差
+	wechatV3NotifySkew = 5 * time.Minute
+)
+
+// WechatV3Provider 微信支付 APIv3 JSAPI，请求使用商户私钥 RSA 签名，回调通知使用 APIv3 密钥 AES-GCM 加密
+type WechatV3Provider struct {
+}
+
+type wechatV3Amount struct {
+	Total    int    `json:"total"`
+	Refund   int    `json:"refund,omitempty"`
+	Currency string `json:"currency,omitempty"`
+}
+
+// wechatV3Transaction 查询订单和支付通知解密后的交易信息
+type wechatV3Transaction struct {
+	Appid         string         `json:"appid"`
+	Mchid         string         `json:"mchid"`
+	OutTradeNo    string         `json:"out_trade_no"`
+	TransactionId string         `json:"transaction_id"`
+	TradeState    string         `json:"trade_state"`
+	SuccessTime   string         `json:"success_time"`
+	Attach        string         `json:"attach"`
+	Amount        wechatV3Amount `json:"amount"`
+	Payer         struct {
+		OpenId string `json:"openid"`
+	} `json:"payer"`
+}
+
+// wechatV3Refund 申请退款响应和退款通知解密后的退款信息
+type wechatV3Refund struct {
+	OutTradeNo   string `json:"out_trade_no"`
+	OutRefundNo  string `json:"out_refund_no"`
+	Status       string `json:"status"`        // 申请退款响应中的退款状态
+	RefundStatus string `json:"refund_status"` // 退款通知中的退款状态
+}
+
+type wechatV3Notify struct {
+	Id        string `json:"id"`
+	EventType string `json:"event_type"`
+	Resource  struct {
+		Algorithm      string `json:"algorithm"`
+		Ciphertext     string `json:"ciphertext"`
+		AssociatedData string `json:"associated_data"`
+		Nonce          string `json:"nonce"`
+	} `json:"resource"`
+}
+
+// wechatV3Error 接口返回的错误信息
+type wechatV3Error struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *wechatV3Error) Error() string {
+	return fmt.Sprintf("微信支付 APIv3 错误 status:%d, code:%s, message:%s", e.StatusCode, e.Code, e.Message)
+}
+
+func (p *WechatV3Provider) Create(req CreateReq) (interface{}, error) {
+	cfg := global.Config.WechatPay
+	body := map[string]interface{}{
+		"appid":        global.Config.Wechat.Appid,
+		"mchid":        cfg.MchId,
+		"description":  req.Subject,
+		"out_trade_no": req.OrderSn,
+		"time_expire":  req.ExpireAt.Format(time.RFC3339), // 与订单超时取消时间保持一致
+		"attach":       strconv.Itoa(int(req.OrderId)),
+		"notify_url":   cfg.NotifyURL,
+		"amount":       wechatV3Amount{Total: yuanToFen(req.Amount), Currency: "CNY"},
+		"payer":        map[string]string{"openid": req.OpenId},
+		"scene_info":   map[string]string{"payer_client_ip": req.ClientIP},
+	}
+	var rsp struct {
+		PrepayId string `json:"prepay_id"`
+	}
+	if err := wechatV3Call(http.MethodPost, "/v3/pay/transactions/jsapi", body, &rsp); err != nil {
+		global.SugarLog.Errorf("微信支付 APIv3 - 发起 JSAPI 发生错误 orderSn:%s, err:%s", req.OrderSn, err.Error())
+		return nil, err
+	}
+	// 返回与 APIv2 相同结构的调起支付参数，小程序端不需要区分接口版本
+	config := orderPay.Config{
+		Timestamp: strconv.FormatInt(time.Now().Unix(), 10),
+		NonceStr:  utils.GenerateInviteCode(32),
+		PrePayID:  rsp.PrepayId,
+		SignType:  "RSA",
+		Package:   "prepay_id=" + rsp.PrepayId,
+	}
+	sign, err := wechatV3Sign(global.Config.Wechat.Appid, config.Timestamp, config.NonceStr, config.Package)
+	if err != nil {
+		return nil, err
+	}
+	config.PaySign = sign
+	return config, nil
+}
+
+func (p *WechatV3Provider) Query(orderSn string) (result TradeResult, err error) {
+	var rsp wechatV3Transaction
+	path := "/v3/pay/transactions/out-trade-no/" + url.PathEscape(orderSn) + "?mchid=" + url.QueryEscape(global.Config.WechatPay.MchId)
+	if err = wechatV3Call(http.MethodGet, path, nil, &rsp); err != nil {
+		// 订单不存在说明用户没有发起过微信支付
+		var e *wechatV3Error
+		if errors.As(err, &e) && e.Code == "ORDER_NOT_EXIST" {
+			return TradeResult{Payment: Wechat, OrderSn: orderSn, State: "NOTPAY"}, nil
+		}
+		global.SugarLog.Errorf("微信支付 APIv3 - 查询订单发生错误 orderSn:%s, err:%s", orderSn, err.Error())
+		return
+	}
+	return wechatV3TradeResult(rsp)
+}
+
+// Close 关闭微信预支付订单，APIv3 关单接口不区分订单状态，先查询订单确认未支付
+// 订单已关闭或不存在视为成功，订单已支付返回 ErrOrderPaid
+func (p *WechatV3Provider) Close(orderSn string) error {
+	trade, err := p.Query(orderSn)
+	if err != nil {
+		return err
+	}
+	switch {
+	case trade.Paid, trade.State == "REFUND":
+		return ErrOrderPaid
+	case trade.State == "CLOSED", trade.State == "REVOKED":
+		return nil
+	}
+	path := "/v3/pay/transactions/out-trade-no/" + url.PathEscape(orderSn) + "/close"
+	if err = wechatV3Call(http.MethodPost, path, map[string]string{"mchid": global.Config.WechatPay.MchId}, nil); err != nil {
+		// 查询后用户可能刚好完成支付，再查一次确认
+		if trade, qErr := p.Query(orderSn); qErr == nil && trade.Paid {
+			return ErrOrderPaid
+		}
+		global.SugarLog.Errorf("微信支付 APIv3 - 关闭订单发生错误 orderSn:%s, err:%s", orderSn, err.Error())
+		return err
+	}
+	return nil
+}
+
+func (p *WechatV3Provider) Refund(req RefundReq) (RefundResult, error) {
+	result := RefundResult{OrderSn: req.OrderSn, RefundSn: req.RefundSn, Status: RefundProcessing}
+	body := map[string]interface{}{
+		"out_trade_no":  req.OrderSn,
+		"out_refund_no": req.RefundSn,
+		"reason":        req.Reason,
+		"notify_url":    global.Config.WechatPay.RefundNotifyURL,
+		"amount":        wechatV3Amount{Refund: yuanToFen(req.Amount), Total: yuanToFen(req.Total), Currency: "CNY"},
+	}
+	var rsp wechatV3Refund
+	if err := wechatV3Call(http.MethodPost, "/v3/refund/domestic/refunds", body, &rsp); err != nil {
+		global.SugarLog.Errorf("微信支付 APIv3 - 申请退款发生错误 orderSn:%s, refundSn:%s, err:%s", req.OrderSn, req.RefundSn, err.Error())
+		return result, err
+	}
+	// 退款结果以退款通知为准，这里只记录受理状态
+	result.State = rsp.Status
+	return result, nil
+}
+
+func (p *WechatV3Provider) VerifyPayNotify(r *http.Request) (result TradeResult, err error) {
+	var trade wechatV3Transaction
+	event, err := wechatV3DecodeNotify(r, &trade)
+	if err != nil {
+		return
+	}
+	if event != "TRANSACTION.SUCCESS" {
+		return result, fmt.Errorf("不支持的通知类型 %s", event)
+	}
+	return wechatV3TradeResult(trade)
+}
+
+func (p *WechatV3Provider) VerifyRefundNotify(r *http.Request) (result RefundResult, err error) {
+	var refund wechatV3Refund
+	if _, err = wechatV3DecodeNotify(r, &refund); err != nil {
+		return
+	}
+	result = RefundResult{OrderSn: refund.OutTradeNo, RefundSn: refund.OutRefundNo, State: refund.RefundStatus, Status: RefundFailed}
+	// 其余为退款异常 ABNORMAL 或退款关闭 CLOSED
+	if refund.RefundStatus == "SUCCESS" {
+		result.Status = RefundSuccess
+	}
+	return
+}
+
+// NotifyAck APIv3 回调成功返回 204，失败返回 5XX 和错误信息，微信会重新通知
+func (p *WechatV3Provider) NotifyAck(w http.ResponseWriter, err error) {
+	if err == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	_ = json.NewEncoder(w).Encode(map[string]string{"code": "FAIL", "message": err.Error()})
+}
+
+// DownloadBill 申请交易账单后下载账单文件，并校验文件摘要
+func (p *WechatV3Provider) DownloadBill(date time.Time) ([]byte, error) {
+	var rsp struct {
+		HashType    string `json:"hash_type"`
+		HashValue   string `json:"hash_value"`
+		DownloadUrl string `json:"download_url"`
+	}
+	path := "/v3/bill/tradebill?bill_type=ALL&bill_date=" + date.Format("2006-01-02")
+	if err := wechatV3Call(http.MethodGet, path, nil, &rsp); err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(rsp.DownloadUrl)
+	if err != nil || wechatV3Host != u.Scheme+"://"+u.Host {
+		return nil, fmt.Errorf("账单下载地址错误 %s", rsp.DownloadUrl)
+	}
+	// 账单文件的应答没有签名，通过摘要校验内容
+	raw, _, err := wechatV3Do(http.MethodGet, u.RequestURI(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if sum := sha1.Sum(raw); !strings.EqualFold(hex.EncodeToString(sum[:]), rsp.HashValue) {
+		return nil, errors.New("账单文件摘要校验失败")
+	}
+	return raw, nil
+}
+
+// wechatV3TradeResult 将 APIv3 查询订单和支付通知的结果转换为交易结果
+func wechatV3TradeResult(t wechatV3Transaction) (result TradeResult, err error) {
+	result = TradeResult{Payment: Wechat, OrderSn: t.OutTradeNo, State: t.TradeState, AppId: t.Appid, MchId: t.Mchid}
+	if t.TradeState != "SUCCESS" {
+		return result, nil
+	}
+	result.Paid = true
+	result.Amount = float64(t.Amount.Total) / 100
+	if result.PayTime, err = time.Parse(time.RFC3339, t.SuccessTime); err != nil {
+		return result, fmt.Errorf("支付时间格式错误 success_time:%s", t.SuccessTime)
+	}
+	result.PayTime = result.PayTime.Local()
+	result.TransactionId = t.TransactionId
+	result.Buyer = t.Payer.OpenId
+	result.Attach = t.Attach
+	return result, nil
+}
+
+// wechatV3DecodeNotify 校验回调通知签名并解密通知资源，返回通知类型
+func wechatV3DecodeNotify(r *http.Request, resource interface{}) (string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", err
+	}
+	ts, err := strconv.ParseInt(r.Header.Get("Wechatpay-Timestamp"), 10, 64)
+	if err != nil || math.Abs(float64(time.Now().Unix()-ts)) > wechatV3NotifySkew.Seconds() {
+		return "", errors.New("通知时间戳无效")
+	}
+	if err = wechatV3Verify(r.Header, body); err != nil {
+		global.SugarLog.Errorf("微信支付 APIv3 回调签名验证失败! err: %v, body: %s \n", err, string(body))
+		return "", errors.New("签名失败")
+	}
+	var n wechatV3Notify
+	if err = json.Unmarshal(body, &n); err != nil {
+		return "", err
+	}
+	if n.Resource.Algorithm != "AEAD_AES_256_GCM" {
+		return "", fmt.Errorf("不支持的加密算法 %s", n.Resource.Algorithm)
+	}
+	plain, err := utils.AesGcmDecrypt(global.Config.WechatPay.ApiV3Key, n.Resource.Nonce, n.Resource.AssociatedData, n.Resource.Ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("通知解密失败 %v", err)
+	}
+	return n.EventType, json.Unmarshal(plain, resource)
+}
+
+// wechatV3Call 调用 APIv3 接口并校验响应签名，path 为包含查询参数的请求路径
+func wechatV3Call(method, path string, body interface{}, rsp interface{}) error {
+	raw, header, err := wechatV3Do(method, path, body)
+	if err != nil {
+		return err
+	}
+	if err = wechatV3Verify(header, raw); err != nil {
+		return fmt.Errorf("响应验签失败 %v", err)
+	}
+	if rsp == nil || len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, rsp)
+}
+
+// wechatV3Do 发送签名后的请求，非 2XX 响应返回 wechatV3Error
+func wechatV3Do(method, path string, body interface{}) ([]byte, http.Header, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, nil, err
+		}
+	}
+	auth, err := wechatV3Authorization(method, path, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest(method, wechatV3Host+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "fresh-shop")
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		e := &wechatV3Error{StatusCode: resp.StatusCode}
+		_ = json.Unmarshal(raw, e)
+		return nil, nil, e
+	}
+	return raw, resp.Header, nil
+}
+
+// wechatV3Authorization 生成请求签名头，签名串为 请求方法\n路径\n时间戳\n随机串\n请求体\n
+func wechatV3Authorization(method, path string, body []byte) (string, error) {
+	key, serialNo, err := wechatV3MerchantKey()
+	if err != nil {
+		return "", err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := utils.GenerateInviteCode(32)
+	sign, err := rsaSign(key, method, path, ts, nonce, string(body))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`WECHATPAY2-SHA256-RSA2048 mchid="%s",nonce_str="%s",signature="%s",timestamp="%s",serial_no="%s"`,
+		global.Config.WechatPay.MchId, nonce, sign, ts, serialNo), nil
+}
+
+// wechatV3Sign 使用商户私钥对小程序调起支付参数签名
+func wechatV3Sign(lines ...string) (string, error) {
+	key, _, err := wechatV3MerchantKey()
+	if err != nil {
+		return "", err
+	}
+	return rsaSign(key, lines...)
+}
+
+// wechatV3Verify 使用平台证书校验应答或回调的签名，验签串为 时间戳\n随机串\n报文主体\n
+func wechatV3Verify(header http.Header, body []byte) error {
+	serial := header.Get("Wechatpay-Serial")
+	key, err := wechatV3PlatformKey(serial)
+	if err != nil {
+		return err
+	}
+	return wechatV3VerifyWith(key, header, body)
+}
+
+func wechatV3VerifyWith(key *rsa.PublicKey, header http.Header, body []byte) error {
+	sign, err := base64.StdEncoding.DecodeString(header.Get("Wechatpay-Signature"))
+	if err != nil {
+		return err
+	}
+	hashed := sha256.Sum256([]byte(signLines(header.Get("Wechatpay-Timestamp"), header.Get("Wechatpay-Nonce"), string(body))))
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], sign)
+}
+
+// rsaSign SHA256withRSA 签名，每行以 \n 结尾
+func rsaSign(key *rsa.PrivateKey, lines ...string) (string, error) {
+	hashed := sha256.Sum256([]byte(signLines(lines...)))
+	sign, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sign), nil
+}
+
+func signLines(lines ...string) string {
+	var buf bytes.Buffer
+	for _, l := range lines {
+		buf.WriteString(l)
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// wechatV3Merchant 商户私钥和证书序列号，首次使用时从证书文件读取
+var wechatV3Merchant struct {
+	sync.Mutex
+	key      *rsa.PrivateKey
+	serialNo string
+}
+
+func wechatV3MerchantKey() (*rsa.PrivateKey, string, error) {
+	wechatV3Merchant.Lock()
+	defer wechatV3Merchant.Unlock()
+	if wechatV3Merchant.key != nil {
+		return wechatV3Merchant.key, wechatV3Merchant.serialNo, nil
+	}
+	cfg := global.Config.WechatPay
+	raw, err := os.ReadFile(cfg.KeyPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("读取商户私钥失败 %v", err)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, "", errors.New("商户私钥格式错误")
+	}
+	var key *rsa.PrivateKey
+	if parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		key, _ = parsed.(*rsa.PrivateKey)
+	} else if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+		return nil, "", fmt.Errorf("商户私钥格式错误 %v", err)
+	}
+	if key == nil {
+		return nil, "", errors.New("商户私钥不是 RSA 密钥")
+	}
+	serialNo := cfg.SerialNo
+	if serialNo == "" {
+		cert, err := readCertificate(cfg.CertPath)
+		if err != nil {
+			return nil, "", fmt.Errorf("读取商户证书序列号失败 %v", err)
+		}
+		serialNo = fmt.Sprintf("%X", cert.SerialNumber)
+	}
+	wechatV3Merchant.key, wechatV3Merchant.serialNo = key, serialNo
+	return key, serialNo, nil
+}
+
+func readCertificate(path string) (*x509.Certificate, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseCertificate(raw)
+}
+
+func parseCertificate(raw []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("证书格式错误")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// wechatV3Certs 平台证书缓存，key 为证书序列号
+var wechatV3Certs struct {
+	sync.RWMutex
+	keys      map[string]*rsa.PublicKey
+	updatedAt time.Time
+}
+
+// wechatV3PlatformKey 根据序列号获取平台证书公钥，缓存过期或遇到新序列号(证书轮换)时重新下载
+func wechatV3PlatformKey(serial string) (*rsa.PublicKey, error) {
+	wechatV3Certs.RLock()
+	key, ok := wechatV3Certs.keys[serial]
+	age := time.Since(wechatV3Certs.updatedAt)
+	wechatV3Certs.RUnlock()
+	if ok && age < wechatV3CertTTL {
+		return key, nil
+	}
+	if !ok && age < wechatV3CertRetry {
+		return nil, fmt.Errorf("未找到平台证书 serial:%s", serial)
+	}
+	if err := refreshWechatV3Certs(); err != nil {
+		global.SugarLog.Errorf("微信支付 APIv3 - 下载平台证书失败 err:%v", err)
+		// 下载失败时继续使用已缓存的证书
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+	wechatV3Certs.RLock()
+	defer wechatV3Certs.RUnlock()
+	if key, ok = wechatV3Certs.keys[serial]; !ok {
+		return nil, fmt.Errorf("未找到平台证书 serial:%s", serial)
+	}
+	return key, nil
+}
+
+// refreshWechatV3Certs 下载并解密平台证书，使用下载到的证书校验本次应答签名
+func refreshWechatV3Certs() error {
+	wechatV3Certs.Lock()
+	defer wechatV3Certs.Unlock()
+	// 等待锁期间其他请求可能已经完成下载
+	if time.Since(wechatV3Certs.updatedAt) < wechatV3CertRetry {
+		return nil
+	}
+	raw, header, err := wechatV3Do(http.MethodGet, "/v3/certificates", nil)
+	if err != nil {
+		return err
+	}
+	var rsp struct {
+		Data []struct {
+			SerialNo           string `json:"serial_no"`
+			EncryptCertificate struct {
+				Ciphertext     string `json:"ciphertext"`
+				AssociatedData string `json:"associated_data"`
+				Nonce          string `json:"nonce"`
+			} `json:"encrypt_certificate"`
+		} `json:"data"`
+	}
+	if err = json.Unmarshal(raw, &rsp); err != nil {
+		return err
+	}
+	keys := make(map[string]*rsa.PublicKey, len(rsp.Data))
+	for _, item := range rsp.Data {
+		c := item.EncryptCertificate
+		plain, err := utils.AesGcmDecrypt(global.Config.WechatPay.ApiV3Key, c.Nonce, c.AssociatedData, c.Ciphertext)
+		if err != nil {
+			return fmt.Errorf("平台证书解密失败 serial:%s, err:%v", item.SerialNo, err)
+		}
+		cert, err := parseCertificate(plain)
+		if err != nil {
+			return fmt.Errorf("平台证书解析失败 serial:%s, err:%v", item.SerialNo, err)
+		}
+		pub, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("平台证书不是 RSA 公钥 serial:%s", item.SerialNo)
+		}
+		keys[item.SerialNo] = pub
+	}
+	key, ok := keys[header.Get("Wechatpay-Serial")]
+	if !ok {
+		return errors.New("平台证书应答签名序列号不匹配")
+	}
+	if err = wechatV3VerifyWith(key, header, raw); err != nil {
+		return fmt.Errorf("平台证书应答验签失败 %v", err)
+	}
+	wechatV3Certs.keys = keys
+	wechatV3Certs.updatedAt = time.Now()
+	return nil
+}
+
+// yuanToFen 元转换为分
+func yuanToFen(amount float64) int {
+	return int(math.Round(amount * 100))
+}
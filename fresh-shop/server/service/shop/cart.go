@@ -6,8 +6,10 @@ import (
 	"fresh-shop/server/model/common/request"
 	"fresh-shop/server/model/shop"
 	shopReq "fresh-shop/server/model/shop/request"
+	shopResp "fresh-shop/server/model/shop/response"
 	"fresh-shop/server/utils"
 	"gorm.io/gorm"
+	"math"
 )
 
 type CartService struct {
@@ -21,18 +23,33 @@ func (cartService *CartService) CreateCart(cart shop.Cart) (err error) {
 	if errors.Is(global.DB.Where("id = ?", cart.GoodsId).First(&goods).Error, gorm.ErrRecordNotFound) {
 		return errors.New("商品不存在")
 	}
+	// 购物车按 商品 + 规格 区分，单规格商品规格id为 0
+	store := *goods.Store
+	if *goods.SpecType == 1 {
+		if cart.SpecItemId <= 0 {
+			return errors.New("请选择商品规格")
+		}
+		var specValue shop.GoodsSpecValue
+		if errors.Is(global.DB.Where("id = ? and goods_id = ?", cart.SpecItemId, goods.ID).First(&specValue).Error, gorm.ErrRecordNotFound) {
+			return errors.New("商品规格不存在")
+		}
+		cart.SpecType = 1
+		store = *specValue.Store
+	} else {
+		cart.SpecType = 0
+		cart.SpecItemId = 0
+	}
 
 	// 记录不存在则创建
-	cart.SpecItemId = 0
-	if errors.Is(global.DB.Where("user_id = ? and goods_id = ?", cart.UserId, cart.GoodsId).First(&c).Error, gorm.ErrRecordNotFound) {
-		if *goods.Store < cart.Num {
+	if errors.Is(global.DB.Where("user_id = ? and goods_id = ? and spec_item_id = ?", cart.UserId, cart.GoodsId, cart.SpecItemId).First(&c).Error, gorm.ErrRecordNotFound) {
+		if store < cart.Num {
 			return errors.New("商品库存不足")
 		}
 		if cart.Num > 0 {
 			err = global.DB.Create(&cart).Error
 		}
 	} else {
-		if cart.Num > c.Num && *goods.Store < cart.Num {
+		if cart.Num > c.Num && store < cart.Num {
 			return errors.New("商品库存不足")
 		}
 
@@ -65,12 +82,12 @@ func (cartService *CartService) DeleteCartByIds(ids request.IdsReq) (err error)
 // Author [likfees](https://github.com/likfees)
 func (cartService *CartService) UpdateCart(cart shop.Cart) (err error) {
 	var dbC shop.Cart
-	err = global.DB.Where("id = ?", cart.ID).Preload("Goods").First(&dbC).Error
+	err = global.DB.Where("id = ?", cart.ID).Preload("Goods").Preload("SpecValue").First(&dbC).Error
 	if err != nil {
 		return err
 	}
 	if *cart.Checked == 1 {
-		if *dbC.Goods.Store <= 0 || *dbC.Goods.Store < dbC.Num {
+		if store := cartStore(dbC); store <= 0 || store < dbC.Num {
 			return errors.New("商品库存不足")
 		}
 	}
@@ -84,7 +101,7 @@ func (cartService *CartService) UpdateCart(cart shop.Cart) (err error) {
 func (cartService *CartService) SelectAllChecked(userId uint) (err error) {
 	var ids []uint
 	var carts []shop.Cart
-	err = global.DB.Model(&shop.Cart{}).Where("user_id = ?", userId).Preload("Goods").Find(&carts).Error
+	err = global.DB.Model(&shop.Cart{}).Where("user_id = ?", userId).Preload("Goods").Preload("SpecValue").Find(&carts).Error
 	if err != nil {
 		return err
 	}
@@ -92,9 +109,9 @@ func (cartService *CartService) SelectAllChecked(userId uint) (err error) {
 		return nil
 	}
 	for _, c := range carts {
-		if *c.Goods.Store <= 0 {
+		if store := cartStore(c); store <= 0 {
 			continue
-		} else if *c.Goods.Store < c.Num {
+		} else if store < c.Num {
 			continue
 		}
 		ids = append(ids, c.ID)
@@ -126,7 +143,7 @@ func (cartService *CartService) GetCart(id uint) (cart shop.Cart, err error) {
 // Author [likfees](https://github.com/likfees)
 func (cartService *CartService) GetCartInfoList(info shopReq.CartSearch, userId uint) (list []shop.Cart, total int64, err error) {
 	// 创建db
-	db := global.DB.Model(&shop.Cart{}).Where("user_id = ?", userId).Preload("Goods.Images")
+	db := global.DB.Model(&shop.Cart{}).Where("user_id = ?", userId).Preload("Goods.Images").Preload("SpecValue")
 	var carts []shop.Cart
 	if info.Checked != nil {
 		db = db.Where("checked = ?", *info.Checked)
@@ -148,10 +165,10 @@ func (cartService *CartService) GetCartInfoList(info shopReq.CartSearch, userId
 	// 将库存不足的取消选择
 	cancelCheckIds := make([]uint, 0)
 	for i, c := range carts {
-		if *c.Goods.Store <= 0 {
+		if store := cartStore(c); store <= 0 {
 			carts[i].Checked = utils.Pointer(0)
 			cancelCheckIds = append(cancelCheckIds, c.ID)
-		} else if *c.Goods.Store < c.Num {
+		} else if store < c.Num {
 			carts[i].Checked = utils.Pointer(0)
 			cancelCheckIds = append(cancelCheckIds, c.ID)
 		}
@@ -161,3 +178,91 @@ func (cartService *CartService) GetCartInfoList(info shopReq.CartSearch, userId
 	}
 	return carts, total, err
 }
+
+// GetPostageQuote 根据购物车已选中的商品和收货地址试算运费
+func (cartService *CartService) GetPostageQuote(userId uint, req shopReq.PostageQuoteReq) (resp shopResp.PostageQuoteResp, err error) {
+	var carts []shop.Cart
+	if err = global.DB.Where("user_id = ? and checked = 1", userId).Preload("Goods").Preload("SpecValue").Find(&carts).Error; err != nil {
+		return
+	}
+	lines := make([]couponLine, 0, len(carts))
+	for _, c := range carts {
+		resp.Total += cartAmount(c)
+		resp.Weight += cartWeight(c)
+		lines = append(lines, cartCouponLine(c))
+	}
+	if req.CouponId > 0 {
+		var userCoupon shop.UserCoupon
+		if errors.Is(global.DB.Where("id = ? and user_id = ?", req.CouponId, userId).First(&userCoupon).Error, gorm.ErrRecordNotFound) {
+			return resp, errors.New("优惠券不存在")
+		}
+		if resp.Coupon, _, err = calcCouponDiscount(userCoupon, lines); err != nil {
+			return
+		}
+	}
+	var address shop.UserAddress
+	if req.AddressId > 0 {
+		if errors.Is(global.DB.Where("id = ? and user_id = ?", req.AddressId, userId).First(&address).Error, gorm.ErrRecordNotFound) {
+			return resp, errors.New("收货地址不存在")
+		}
+	}
+	if resp.Postage, resp.Distance, err = calcOrderPostage(req.ShipmentType, address, resp.Total-resp.Coupon, resp.Weight); err != nil {
+		return
+	}
+	if resp.PointOffset, resp.PointAmount, err = calcPointOffset(resp.Total-resp.Coupon, req.PointOffset); err != nil {
+		return
+	}
+	resp.PayAmount = math.Round((resp.Total-resp.Coupon-resp.PointAmount+resp.Postage)*100) / 100
+	return
+}
+
+// cartStore 获取购物车商品的可用库存，多规格商品取所选规格的库存
+func cartStore(c shop.Cart) int {
+	if c.SpecItemId > 0 {
+		if c.SpecValue.Store == nil {
+			return 0
+		}
+		return *c.SpecValue.Store
+	}
+	if c.Goods.Store == nil {
+		return 0
+	}
+	return *c.Goods.Store
+}
+
+// cartPrice 获取购物车商品的优惠价和原价，多规格商品取所选规格的价格
+func cartPrice(c shop.Cart) (price, costPrice float64) {
+	if c.SpecItemId > 0 {
+		if c.SpecValue.Price != nil {
+			price = *c.SpecValue.Price
+		}
+		if c.SpecValue.CostPrice != nil {
+			costPrice = *c.SpecValue.CostPrice
+		}
+		return
+	}
+	if c.Goods.Price != nil {
+		price = *c.Goods.Price
+	}
+	if c.Goods.CostPrice != nil {
+		costPrice = *c.Goods.CostPrice
+	}
+	return
+}
+
+// cartAmount 计算购物车商品金额 如果优惠价小于原价按优惠价计算
+func cartAmount(c shop.Cart) float64 {
+	price, costPrice := cartPrice(c)
+	if price > 0 && price < costPrice {
+		return float64(c.Num) * price
+	}
+	return float64(c.Num) * costPrice
+}
+
+// cartWeight 计算购物车商品重量，单位克
+func cartWeight(c shop.Cart) int {
+	if c.Goods.Weight == nil {
+		return 0
+	}
+	return c.Num * *c.Goods.Weight
+}
@@ -0,0 +1,196 @@
+package shop
+
+import (
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/account"
+	"fresh-shop/server/model/shop"
+	sysModel "fresh-shop/server/model/system"
+	"fresh-shop/server/service/common"
+	"gorm.io/gorm"
+	"math"
+	"strconv"
+	"time"
+)
+
+// commissionRateConfigs 各级佣金比例配置参数名，值为百分比，配置不存在或禁用时该级不分佣
+var commissionRateConfigs = [common.CommissionLevels]string{"commissionLevel1", "commissionLevel2"}
+
+// commissionRate 获取指定层级的佣金比例(百分比)
+func commissionRate(level int) float64 {
+	value, err := common.GetSysConfig(commissionRateConfigs[level-1])
+	if err != nil {
+		if !errors.Is(err, common.ErrConfigDisabled) && !errors.Is(err, gorm.ErrRecordNotFound) {
+			global.SugarLog.Errorf("查询佣金比例配置异常 level:%d, err:%v \n", level, err)
+		}
+		return 0
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 || rate > 100 {
+		global.SugarLog.Errorf("佣金比例配置参数错误 %s:%s \n", commissionRateConfigs[level-1], value)
+		return 0
+	}
+	return rate
+}
+
+// commissionBase 订单计佣金额，商品金额扣除优惠券和积分抵扣，不含运费
+func commissionBase(order shop.Order) float64 {
+	return goodsPayAmount(order)
+}
+
+// createOrderCommission 订单支付后在同一事务中给一级、二级上级发放冻结佣金并累计团队业绩
+// 积分商城订单不参与分销
+func createOrderCommission(tx *gorm.DB, order shop.Order) error {
+	if *order.GoodsArea != 0 {
+		return nil
+	}
+	base := commissionBase(order)
+	if base <= 0 {
+		return nil
+	}
+	chain, err := common.GetInviterChain(tx, uint(*order.UserId))
+	if err != nil {
+		return err
+	}
+	for i, inviterId := range chain {
+		level := i + 1
+		if err = common.AddTeamAmount(tx, inviterId, base, 0); err != nil {
+			return err
+		}
+		rate := commissionRate(level)
+		amount := math.Round(base*rate) / 100
+		if amount <= 0 {
+			continue
+		}
+		var inviter sysModel.SysUser
+		if err = tx.Where("id = ?", inviterId).First(&inviter).Error; err != nil {
+			return err
+		}
+		commission := account.UserCommission{
+			UserId:     inviterId,
+			FromUserId: uint(*order.UserId),
+			OrderId:    order.ID,
+			OrderSn:    order.OrderSn,
+			Level:      level,
+			Rate:       rate,
+			BaseAmount: base,
+			Amount:     amount,
+			Status:     account.CommissionFrozen,
+		}
+		if err = tx.Create(&commission).Error; err != nil {
+			return err
+		}
+		f := common.NewFinance(common.OptionTypeFreeze, common.FinanceTypeCommission, inviter.ID, inviter.Username, amount, order.OrderSn, uint(*order.UserId), "", fmt.Sprintf("%d级分销佣金", level))
+		if err = common.AccountUnifyDeductionTx(tx, common.CASH, f); err != nil {
+			global.SugarLog.Errorf("发放分销佣金失败 UserFinance:%v, error: %v", f, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// settleOrderCommission 订单确认收货后将冻结佣金转入可用余额
+func settleOrderCommission(tx *gorm.DB, order shop.Order) error {
+	var list []account.UserCommission
+	if err := tx.Preload("User").Where("order_id = ? and status = ?", order.ID, account.CommissionFrozen).Find(&list).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, c := range list {
+		res := tx.Model(&account.UserCommission{}).Where("id = ? and status = ?", c.ID, account.CommissionFrozen).
+			Updates(map[string]interface{}{"status": account.CommissionSettled, "settle_time": now})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		f := common.NewFinance(common.OptionTypeFreeze, common.FinanceTypeCommissionSettle, c.UserId, c.User.Username, -c.Amount, order.OrderSn, c.FromUserId, "", "订单收货佣金解冻")
+		if err := common.AccountUnifyDeductionTx(tx, common.CASH, f); err != nil {
+			global.SugarLog.Errorf("分销佣金解冻失败 UserFinance:%v, error: %v", f, err)
+			return err
+		}
+		f = common.NewFinance(common.OptionTypeCASH, common.FinanceTypeCommissionSettle, c.UserId, c.User.Username, c.Amount, order.OrderSn, c.FromUserId, "", "订单收货佣金到账")
+		if err := common.AccountUnifyDeductionTx(tx, common.CASH, f); err != nil {
+			global.SugarLog.Errorf("分销佣金到账失败 UserFinance:%v, error: %v", f, err)
+			return err
+		}
+		if err := common.AddTeamAmount(tx, c.UserId, 0, c.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reverseOrderCommission 订单退款时按退款对应的计佣金额撤销佣金并扣减团队业绩
+// refundBase 为本次退款的计佣金额，部分售后按其占计佣金额的比例扣回佣金；full 表示订单商品已全部退款，扣回剩余的全部佣金
+// 冻结中的佣金直接扣回冻结余额；已结算的从可用余额扣回，余额不足时不阻断退款，
+// 佣金标记为撤销失败供后台按状态查询后人工追回
+func reverseOrderCommission(tx *gorm.DB, order shop.Order, refundBase float64, full bool) error {
+	if *order.GoodsArea != 0 {
+		return nil
+	}
+	var list []account.UserCommission
+	if err := tx.Preload("User").Where("order_id = ? and status in ?", order.ID, []int{account.CommissionFrozen, account.CommissionSettled}).Find(&list).Error; err != nil {
+		return err
+	}
+	for _, c := range list {
+		amount := math.Round((c.Amount-c.RefundAmount)*100) / 100
+		if !full && c.BaseAmount > 0 {
+			amount = math.Min(math.Round(c.Amount*refundBase/c.BaseAmount*100)/100, amount)
+		}
+		if amount <= 0 && !full {
+			continue
+		}
+		updates := map[string]interface{}{"refund_amount": gorm.Expr("refund_amount + ?", math.Max(amount, 0))}
+		if full {
+			updates["status"] = account.CommissionReversed
+		}
+		res := tx.Model(&account.UserCommission{}).Where("id = ? and status = ?", c.ID, c.Status).Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 || amount <= 0 {
+			continue
+		}
+		if c.Status == account.CommissionFrozen {
+			f := common.NewFinance(common.OptionTypeFreeze, common.FinanceTypeCommissionRefund, c.UserId, c.User.Username, -amount, order.OrderSn, c.FromUserId, "", "订单退款扣回冻结佣金")
+			if err := common.AccountUnifyDeductionTx(tx, common.CASH, f); err != nil {
+				global.SugarLog.Errorf("扣回冻结佣金失败 UserFinance:%v, error: %v", f, err)
+				return err
+			}
+		} else {
+			f := common.NewFinance(common.OptionTypeCASH, common.FinanceTypeCommissionRefund, c.UserId, c.User.Username, -amount, order.OrderSn, c.FromUserId, "", "订单退款扣回佣金")
+			if err := common.AccountUnifyDeductionTx(tx, common.CASH, f); err != nil {
+				global.SugarLog.Warnf("扣回已结算佣金失败，需人工处理 UserFinance:%v, error: %v", f, err)
+				err = tx.Model(&account.UserCommission{}).Where("id = ?", c.ID).Updates(map[string]interface{}{
+					"status":        account.CommissionReverseFailed,
+					"refund_amount": c.RefundAmount,
+				}).Error
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if err := common.AddTeamAmount(tx, c.UserId, 0, -amount); err != nil {
+				return err
+			}
+		}
+	}
+	// 团队业绩按上级链扣减，未产生佣金的层级同样累计过业绩
+	base := math.Min(refundBase, commissionBase(order))
+	if base <= 0 {
+		return nil
+	}
+	chain, err := common.GetInviterChain(tx, uint(*order.UserId))
+	if err != nil {
+		return err
+	}
+	for _, inviterId := range chain {
+		if err = common.AddTeamAmount(tx, inviterId, -base, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,351 @@
+package shop
+
+import (
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/common/request"
+	"fresh-shop/server/model/shop"
+	shopReq "fresh-shop/server/model/shop/request"
+	"fresh-shop/server/service/common"
+	"fresh-shop/server/utils"
+	"gorm.io/gorm"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CouponService struct {
+}
+
+// CreateCoupon 创建Coupon记录
+// Author [likfees](https://github.com/likfees)
+func (couponService *CouponService) CreateCoupon(coupon shop.Coupon) (err error) {
+	if err = checkCoupon(coupon); err != nil {
+		return err
+	}
+	coupon.Issued = 0
+	err = global.DB.Create(&coupon).Error
+	return err
+}
+
+// DeleteCoupon 删除Coupon记录
+// Author [likfees](https://github.com/likfees)
+func (couponService *CouponService) DeleteCoupon(coupon shop.Coupon) (err error) {
+	err = global.DB.Delete(&coupon).Error
+	return err
+}
+
+// DeleteCouponByIds 批量删除Coupon记录
+// Author [likfees](https://github.com/likfees)
+func (couponService *CouponService) DeleteCouponByIds(ids request.IdsReq) (err error) {
+	err = global.DB.Delete(&[]shop.Coupon{}, "id in ?", ids.Ids).Error
+	return err
+}
+
+// UpdateCoupon 更新Coupon记录，已发放的优惠券不受影响
+// Author [likfees](https://github.com/likfees)
+func (couponService *CouponService) UpdateCoupon(coupon shop.Coupon) (err error) {
+	if err = checkCoupon(coupon); err != nil {
+		return err
+	}
+	err = global.DB.Omit("issued").Save(&coupon).Error
+	return err
+}
+
+// GetCoupon 根据id获取Coupon记录
+// Author [likfees](https://github.com/likfees)
+func (couponService *CouponService) GetCoupon(id uint) (coupon shop.Coupon, err error) {
+	err = global.DB.Where("id = ?", id).First(&coupon).Error
+	return
+}
+
+// GetCouponInfoList 分页获取Coupon记录
+// Author [likfees](https://github.com/likfees)
+func (couponService *CouponService) GetCouponInfoList(info shopReq.CouponSearch) (list []shop.Coupon, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	// 创建db
+	db := global.DB.Model(&shop.Coupon{})
+	var coupons []shop.Coupon
+	// 如果有条件搜索 下方会自动创建搜索语句
+	if info.StartCreatedAt != nil && info.EndCreatedAt != nil {
+		db = db.Where("created_at BETWEEN ? AND ?", info.StartCreatedAt, info.EndCreatedAt)
+	}
+	if info.Name != "" {
+		db = db.Where("name LIKE ?", "%"+info.Name+"%")
+	}
+	if info.Type != 0 {
+		db = db.Where("type = ?", info.Type)
+	}
+	if info.Status != nil {
+		db = db.Where("status = ?", info.Status)
+	}
+	err = db.Count(&total).Error
+	if err != nil {
+		return
+	}
+
+	err = db.Limit(limit).Offset(offset).Order("id desc").Find(&coupons).Error
+	return coupons, total, err
+}
+
+// IssueCoupon 后台给用户发放优惠券，每个用户单独发放，返回发放成功的数量
+func (couponService *CouponService) IssueCoupon(req shopReq.IssueCouponReq) (success int, err error) {
+	var failed []string
+	for _, userId := range req.UserIds {
+		txErr := global.DB.Transaction(func(tx *gorm.DB) error {
+			_, err := common.IssueCoupon(tx, req.CouponId, userId, shop.CouponSourceAdmin)
+			return err
+		})
+		if txErr != nil {
+			global.SugarLog.Errorf("发放优惠券失败 couponId:%d, userId:%d, err:%v \n", req.CouponId, userId, txErr)
+			failed = append(failed, fmt.Sprintf("用户%d: %s", userId, txErr.Error()))
+			// 优惠券不可用或已发完时后续用户也无法发放
+			if errors.Is(txErr, common.ErrCouponNotAvailable) || errors.Is(txErr, common.ErrCouponSoldOut) {
+				break
+			}
+			continue
+		}
+		success++
+	}
+	if len(failed) > 0 {
+		err = errors.New(strings.Join(failed, "; "))
+	}
+	return
+}
+
+// GetClaimableCoupons 领券中心 获取可领取的优惠券及当前用户已领取的数量
+func (couponService *CouponService) GetClaimableCoupons(userId uint) (list []shop.Coupon, err error) {
+	err = global.DB.Where("status = 1 and claimable = 1").
+		Where("valid_type = 1 or end_time > ?", time.Now()).
+		Order("id desc").Find(&list).Error
+	if err != nil || len(list) == 0 {
+		return
+	}
+	var claimed []struct {
+		CouponId uint
+		Count    int
+	}
+	err = global.DB.Model(&shop.UserCoupon{}).Select("coupon_id, count(*) as count").
+		Where("user_id = ?", userId).Group("coupon_id").Scan(&claimed).Error
+	if err != nil {
+		return
+	}
+	counts := make(map[uint]int, len(claimed))
+	for _, c := range claimed {
+		counts[c.CouponId] = c.Count
+	}
+	for i := range list {
+		list[i].Claimed = counts[list[i].ID]
+	}
+	return
+}
+
+// ClaimCoupon 领券中心领取优惠券
+func (couponService *CouponService) ClaimCoupon(userId uint, couponId uint) (userCoupon shop.UserCoupon, err error) {
+	var coupon shop.Coupon
+	if errors.Is(global.DB.Where("id = ? and status = 1 and claimable = 1", couponId).First(&coupon).Error, gorm.ErrRecordNotFound) {
+		return userCoupon, common.ErrCouponNotAvailable
+	}
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		var txErr error
+		userCoupon, txErr = common.IssueCoupon(tx, couponId, userId, shop.CouponSourceClaim)
+		return txErr
+	})
+	return
+}
+
+// GetUserCouponList 分页获取用户的优惠券 Status 0未使用 1已使用 2已过期
+func (couponService *CouponService) GetUserCouponList(userId uint, info shopReq.UserCouponSearch) (list []shop.UserCoupon, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.DB.Model(&shop.UserCoupon{}).Where("user_id = ?", userId)
+	now := time.Now()
+	switch info.Status {
+	case 0:
+		db = db.Where("status = 0 and end_time > ?", now)
+	case 1:
+		db = db.Where("status = 1")
+	case 2:
+		db = db.Where("status = 0 and end_time <= ?", now)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Limit(limit).Offset(offset).Order("end_time asc, id desc").Find(&list).Error
+	return
+}
+
+// GetUsableCoupons 获取购物车已选中商品可使用的优惠券，按优惠金额从大到小排列
+func (couponService *CouponService) GetUsableCoupons(userId uint) (list []shop.UserCoupon, err error) {
+	var carts []shop.Cart
+	if err = global.DB.Where("user_id = ? and checked = 1", userId).Preload("Goods").Preload("SpecValue").Find(&carts).Error; err != nil {
+		return
+	}
+	if len(carts) == 0 {
+		return
+	}
+	lines := make([]couponLine, 0, len(carts))
+	for _, c := range carts {
+		lines = append(lines, cartCouponLine(c))
+	}
+	var coupons []shop.UserCoupon
+	now := time.Now()
+	if err = global.DB.Where("user_id = ? and status = 0 and start_time <= ? and end_time > ?", userId, now, now).Find(&coupons).Error; err != nil {
+		return
+	}
+	for _, c := range coupons {
+		discount, _, calcErr := calcCouponDiscount(c, lines)
+		if calcErr != nil {
+			continue
+		}
+		c.Discount = discount
+		list = append(list, c)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Discount > list[j].Discount })
+	return
+}
+
+// checkCoupon 校验优惠券模板参数
+func checkCoupon(coupon shop.Coupon) error {
+	if strings.TrimSpace(coupon.Name) == "" {
+		return errors.New("优惠券名称不能为空")
+	}
+	switch coupon.Type {
+	case shop.CouponTypeAmount:
+		if coupon.Amount <= 0 {
+			return errors.New("减免金额必须大于 0")
+		}
+		if coupon.Threshold > 0 && coupon.Amount > coupon.Threshold {
+			return errors.New("减免金额不能大于使用门槛")
+		}
+	case shop.CouponTypePercent:
+		if coupon.Percent <= 0 || coupon.Percent >= 100 {
+			return errors.New("折扣百分比必须在 1-99 之间")
+		}
+	default:
+		return errors.New("优惠券类型错误")
+	}
+	if coupon.ScopeType != shop.CouponScopeAll {
+		if _, err := parseScopeIds(coupon.ScopeIds); err != nil || strings.TrimSpace(coupon.ScopeIds) == "" {
+			return errors.New("请选择优惠券适用的分类或品牌")
+		}
+	}
+	if coupon.ValidType == 0 {
+		if coupon.StartTime == nil || coupon.EndTime == nil || !coupon.EndTime.After(*coupon.StartTime) {
+			return errors.New("请设置正确的有效期")
+		}
+	} else if coupon.ValidDays <= 0 {
+		return errors.New("领取后有效天数必须大于 0")
+	}
+	return nil
+}
+
+// couponLine 计算优惠使用的商品行
+type couponLine struct {
+	amount     float64
+	categoryId int
+	brandId    int
+}
+
+func cartCouponLine(c shop.Cart) couponLine {
+	line := couponLine{amount: cartAmount(c)}
+	if c.Goods.CategoryId != nil {
+		line.categoryId = *c.Goods.CategoryId
+	}
+	if c.Goods.BrandId != nil {
+		line.brandId = *c.Goods.BrandId
+	}
+	return line
+}
+
+// calcCouponDiscount 计算优惠券在商品上的优惠金额，shares 为每行商品分摊的优惠金额
+// 只有适用范围内的商品参与门槛判断和优惠分摊
+func calcCouponDiscount(c shop.UserCoupon, lines []couponLine) (discount float64, shares []float64, err error) {
+	now := time.Now()
+	if c.Status != 0 || now.Before(c.StartTime) || !now.Before(c.EndTime) {
+		return 0, nil, errors.New("优惠券不在有效期内或已使用")
+	}
+	scope, err := parseScopeIds(c.ScopeIds)
+	if err != nil {
+		return 0, nil, errors.New("优惠券适用范围配置错误")
+	}
+	weights := make([]float64, len(lines))
+	eligible := 0.0
+	for i, l := range lines {
+		switch c.ScopeType {
+		case shop.CouponScopeCategory:
+			if !scope[l.categoryId] {
+				continue
+			}
+		case shop.CouponScopeBrand:
+			if !scope[l.brandId] {
+				continue
+			}
+		}
+		weights[i] = l.amount
+		eligible += l.amount
+	}
+	if eligible <= 0 {
+		return 0, nil, errors.New("没有适用该优惠券的商品")
+	}
+	if eligible < c.Threshold {
+		return 0, nil, fmt.Errorf("适用商品满 %.2f 元才能使用该优惠券", c.Threshold)
+	}
+	switch c.Type {
+	case shop.CouponTypeAmount:
+		discount = c.Amount
+	case shop.CouponTypePercent:
+		discount = eligible * float64(100-c.Percent) / 100
+		if c.MaxDiscount > 0 && discount > c.MaxDiscount {
+			discount = c.MaxDiscount
+		}
+	default:
+		return 0, nil, errors.New("优惠券类型错误")
+	}
+	discount = math.Round(math.Min(discount, eligible)*100) / 100
+	return discount, utils.ProRate(weights, discount), nil
+}
+
+func parseScopeIds(ids string) (map[int]bool, error) {
+	scope := make(map[int]bool)
+	for _, s := range strings.Split(ids, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		scope[id] = true
+	}
+	return scope, nil
+}
+
+// useOrderCoupon 在事务中核销订单使用的优惠券，并发使用同一张优惠券时只有一个订单成功
+func useOrderCoupon(tx *gorm.DB, order shop.Order) error {
+	if order.CouponId == 0 {
+		return nil
+	}
+	result := tx.Model(&shop.UserCoupon{}).Where("id = ? and user_id = ? and status = 0", order.CouponId, order.UserId).
+		Updates(map[string]interface{}{"status": 1, "order_id": order.ID, "used_time": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("优惠券已被使用")
+	}
+	return nil
+}
+
+// returnOrderCoupon 在事务中退回订单使用的优惠券，订单取消或退款时调用，重复调用不会重复退回
+func returnOrderCoupon(tx *gorm.DB, order shop.Order) error {
+	if order.CouponId == 0 {
+		return nil
+	}
+	return tx.Model(&shop.UserCoupon{}).Where("id = ? and order_id = ? and status = 1", order.CouponId, order.ID).
+		Updates(map[string]interface{}{"status": 0, "order_id": 0, "used_time": nil}).Error
+}
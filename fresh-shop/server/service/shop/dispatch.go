@@ -0,0 +1,166 @@
+package shop
+
+import (
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/business"
+	"fresh-shop/server/model/shop"
+	shopReq "fresh-shop/server/model/shop/request"
+	shopResp "fresh-shop/server/model/shop/response"
+	"fresh-shop/server/utils"
+	"gorm.io/gorm"
+	"math"
+	"time"
+)
+
+// BatchDispatch 批量派单
+// 按收货地址相对门店的方位将订单平均分给配送员，每位配送员的订单按最近邻 + 2-opt 规划配送顺序，
+// 并按配送距离、配送速度和每单停留时间估算预计到达时间；没有坐标的订单排在路线最后
+// 所有订单在同一个事务中发货，任一订单失败则全部不发货
+func (orderDeliveryService *OrderDeliveryService) BatchDispatch(req shopReq.BatchDispatchReq, op OrderOperator) (routes []shopResp.DispatchRoute, err error) {
+	if len(req.OrderIds) == 0 || len(req.RiderIds) == 0 {
+		return nil, errors.New("请选择订单和配送员")
+	}
+	storeLng, storeLat, ok := getStoreLocation()
+	if !ok {
+		return nil, errors.New("请先在系统配置中设置门店坐标 storeLocation")
+	}
+	store := utils.RoutePoint{Lng: storeLng, Lat: storeLat}
+	var riders []business.UserDelivery
+	if err = global.DB.Where("id in ? and status = 1", req.RiderIds).Order("id asc").Find(&riders).Error; err != nil {
+		return
+	}
+	if len(riders) != len(req.RiderIds) {
+		return nil, errors.New("配送员不存在或已禁用")
+	}
+	var orders []shop.Order
+	if err = global.DB.Where("id in ?", req.OrderIds).Order("id asc").Find(&orders).Error; err != nil {
+		return
+	}
+	if len(orders) != len(req.OrderIds) {
+		return nil, errors.New("部分订单不存在")
+	}
+	var dispatched []int64
+	if err = global.DB.Model(&shop.OrderDelivery{}).Where("order_id in ? and type = ?", req.OrderIds, shop.DeliveryTypeShip).Pluck("order_id", &dispatched).Error; err != nil {
+		return
+	}
+	if len(dispatched) > 0 {
+		return nil, fmt.Errorf("订单 %v 已发货", dispatched)
+	}
+	for _, o := range orders {
+		if *o.ShipmentType != 0 {
+			return nil, fmt.Errorf("订单 %s 为自提订单", o.OrderSn)
+		}
+		if !canOrderTransit(o, OrderEventShip) {
+			return nil, fmt.Errorf("订单 %s 不是待发货状态", o.OrderSn)
+		}
+	}
+
+	// 有坐标的订单按方位分组，无坐标的订单分给订单最少的配送员
+	var located, unlocated []shop.Order
+	var points []utils.RoutePoint
+	for _, o := range orders {
+		if o.ShipmentLng == nil || o.ShipmentLat == nil || (*o.ShipmentLng == 0 && *o.ShipmentLat == 0) {
+			unlocated = append(unlocated, o)
+			continue
+		}
+		located = append(located, o)
+		points = append(points, utils.RoutePoint{Lng: *o.ShipmentLng, Lat: *o.ShipmentLat})
+	}
+	groups := utils.SweepPartition(store, points, len(riders))
+	extra := make([][]shop.Order, len(riders))
+	for _, o := range unlocated {
+		target := 0
+		for g := range groups {
+			if len(groups[g])+len(extra[g]) < len(groups[target])+len(extra[target]) {
+				target = g
+			}
+		}
+		extra[target] = append(extra[target], o)
+	}
+
+	start := time.Now()
+	if req.StartTime != nil && req.StartTime.After(start) {
+		start = *req.StartTime
+	}
+	speed := global.Config.Order.GetRiderSpeed() * 1000 / 3600 // 米/秒
+	stopDuration := global.Config.Order.GetStopDuration()
+	var deliveries []shop.OrderDelivery
+	for g, rider := range riders {
+		groupPoints := make([]utils.RoutePoint, len(groups[g]))
+		for i, idx := range groups[g] {
+			groupPoints[i] = points[idx]
+		}
+		route := utils.PlanRoute(store, groupPoints)
+		r := shopResp.DispatchRoute{
+			RiderId:   rider.ID,
+			RiderName: rider.Name,
+			Distance:  math.Round(utils.RouteDistance(store, groupPoints, route)/10) / 100,
+		}
+		eta, cur := start, store
+		for _, i := range route {
+			o := located[groups[g][i]]
+			eta = eta.Add(time.Duration(utils.Distance(cur.Lng, cur.Lat, groupPoints[i].Lng, groupPoints[i].Lat) / speed * float64(time.Second)))
+			cur = groupPoints[i]
+			stop := dispatchStop(o, len(r.Stops)+1, true, eta)
+			r.Stops = append(r.Stops, stop)
+			// 等待预约时段开始的订单会推迟后续订单的到达时间
+			eta = stop.ScheduledTime.Add(stopDuration)
+		}
+		for _, o := range extra[g] {
+			stop := dispatchStop(o, len(r.Stops)+1, false, eta)
+			r.Stops = append(r.Stops, stop)
+			eta = stop.ScheduledTime.Add(stopDuration)
+		}
+		for _, s := range r.Stops {
+			deliveries = append(deliveries, shop.OrderDelivery{
+				OrderId:       utils.Pointer(int(s.OrderId)),
+				ScheduledTime: s.ScheduledTime,
+				DeliverName:   rider.Name,
+				DeliveryId:    utils.Pointer(int(rider.ID)),
+				DeliverMobile: rider.Mobile,
+				RouteSeq:      s.Seq,
+			})
+		}
+		routes = append(routes, r)
+	}
+
+	orderMap := make(map[uint]*shop.Order, len(orders))
+	for i := range orders {
+		orderMap[orders[i].ID] = &orders[i]
+	}
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, d := range deliveries {
+			order := orderMap[uint(*d.OrderId)]
+			if txErr := orderTransit(tx, order, OrderEventShip, op, d.DeliverName, map[string]interface{}{
+				"shipment_time": now,
+			}); txErr != nil {
+				global.SugarLog.Errorf("批量派单发货失败 orderSn:%s, err:%v \n", order.OrderSn, txErr)
+				return fmt.Errorf("订单 %s 发货失败: %v", order.OrderSn, txErr)
+			}
+		}
+		return tx.Create(&deliveries).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
+// dispatchStop 生成配送路线中的订单，预约了配送时段的订单预计到达时间不早于时段开始时间
+// 调用方以返回的 ScheduledTime 作为后续订单的出发时间
+func dispatchStop(order shop.Order, seq int, located bool, eta time.Time) shopResp.DispatchStop {
+	if order.SlotStart != nil && eta.Before(*order.SlotStart) {
+		eta = *order.SlotStart
+	}
+	return shopResp.DispatchStop{
+		Seq:           seq,
+		OrderId:       order.ID,
+		OrderSn:       order.OrderSn,
+		Address:       order.ShipmentAddress,
+		Located:       located,
+		ScheduledTime: eta,
+	}
+}
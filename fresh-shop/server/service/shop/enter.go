@@ -13,4 +13,10 @@ type ServiceGroup struct {
 	FavoritesService
 	CartService
 	UserAddressService
+	RiderService
+	CouponService
+	FlashSaleService
+	GroupBuyService
+	PaymentReconcileService
+	PaymentNotifyLogService
 }
@@ -0,0 +1,580 @@
+package shop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/common/request"
+	"fresh-shop/server/model/shop"
+	shopReq "fresh-shop/server/model/shop/request"
+	shopResp "fresh-shop/server/model/shop/response"
+	sysModel "fresh-shop/server/model/system"
+	"github.com/go-redis/redis/v8"
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 秒杀
+// 秒杀商品使用单独的秒杀库存(FlashSaleGoods.Stock)，下单分两步：
+// 1. 下单请求只在 Redis 中用 Lua 脚本原子地扣减秒杀库存、累加用户已购数量，成功后将下单消息放入队列并返回排队凭证
+// 2. 队列消费者逐条创建订单，在数据库中按 sold + num <= stock 条件扣减秒杀库存，并扣减商品库存
+// 客户端使用排队凭证轮询下单结果，下单成功后通过 order/orderPay 发起支付，超时未支付的订单按普通订单取消并归还秒杀库存
+
+type FlashSaleService struct {
+}
+
+var (
+	ErrFlashSaleRedis    = errors.New("秒杀服务未开启，请联系管理员")
+	ErrFlashSaleNotStart = errors.New("秒杀活动未开始或已结束")
+	ErrFlashSoldOut      = errors.New("秒杀商品已抢光")
+	ErrFlashLimit        = errors.New("已超过秒杀商品的限购数量")
+)
+
+const (
+	flashQueueKey     = "shop:flash:queue"
+	flashResultTTL    = 30 * time.Minute
+	flashQueueTimeout = 5 * time.Second
+)
+
+// 秒杀排队下单结果状态
+const (
+	flashOrderQueuing = 0
+	flashOrderSuccess = 1
+	flashOrderFailed  = 2
+)
+
+// 返回 -1 缓存不存在，0 库存不足，-2 超过限购数量，1 扣减成功
+var flashReserveScript = redis.NewScript(`
+local stock = redis.call('HGET', KEYS[1], ARGV[1])
+if not stock then
+	return -1
+end
+local num = tonumber(ARGV[3])
+local limit = tonumber(ARGV[4])
+if limit > 0 then
+	local bought = tonumber(redis.call('HGET', KEYS[2], ARGV[2]) or '0')
+	if bought + num > limit then
+		return -2
+	end
+end
+if tonumber(stock) < num then
+	return 0
+end
+redis.call('HINCRBY', KEYS[1], ARGV[1], -num)
+redis.call('HINCRBY', KEYS[2], ARGV[2], num)
+if redis.call('TTL', KEYS[2]) < 0 then
+	redis.call('EXPIRE', KEYS[2], ARGV[5])
+end
+return 1
+`)
+
+// 秒杀库存仅在缓存存在时归还，用户已购数量不会减到 0 以下
+var flashReleaseScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then
+	redis.call('HINCRBY', KEYS[1], ARGV[1], tonumber(ARGV[3]))
+end
+local bought = tonumber(redis.call('HGET', KEYS[2], ARGV[2]) or '0')
+if bought > 0 then
+	redis.call('HINCRBY', KEYS[2], ARGV[2], -math.min(bought, tonumber(ARGV[3])))
+end
+return 1
+`)
+
+// flashOrderMessage 秒杀下单队列消息
+type flashOrderMessage struct {
+	Token   string                `json:"token"`
+	UserId  uint                  `json:"userId"`
+	Req     shopReq.FlashOrderReq `json:"req"`
+	Created time.Time             `json:"created"`
+}
+
+// flashStockKey 活动的秒杀库存 hash，field 为秒杀商品id，value 为剩余秒杀库存
+func flashStockKey(flashSaleId uint) string {
+	return fmt.Sprintf("shop:flash:stock:%d", flashSaleId)
+}
+
+// flashUserKey 活动的用户已购数量 hash，field 为 秒杀商品id:用户id
+func flashUserKey(flashSaleId uint) string {
+	return fmt.Sprintf("shop:flash:user:%d", flashSaleId)
+}
+
+func flashUserField(flashGoodsId, userId uint) string {
+	return fmt.Sprintf("%d:%d", flashGoodsId, userId)
+}
+
+func flashResultKey(token string) string {
+	return "shop:flash:result:" + token
+}
+
+// CreateFlashSale 创建FlashSale记录
+// Author [likfees](https://github.com/likfees)
+func (flashSaleService *FlashSaleService) CreateFlashSale(flashSale shop.FlashSale) (err error) {
+	if err = checkFlashSale(flashSale); err != nil {
+		return err
+	}
+	for i := range flashSale.Goods {
+		flashSale.Goods[i].ID = 0
+		flashSale.Goods[i].Sold = 0
+	}
+	err = global.DB.Create(&flashSale).Error
+	return err
+}
+
+// DeleteFlashSale 删除FlashSale记录
+// Author [likfees](https://github.com/likfees)
+func (flashSaleService *FlashSaleService) DeleteFlashSale(flashSale shop.FlashSale) (err error) {
+	return flashSaleService.DeleteFlashSaleByIds(request.IdsReq{Ids: []int{int(flashSale.ID)}})
+}
+
+// DeleteFlashSaleByIds 批量删除FlashSale记录，进行中的活动不能删除
+// Author [likfees](https://github.com/likfees)
+func (flashSaleService *FlashSaleService) DeleteFlashSaleByIds(ids request.IdsReq) (err error) {
+	now := time.Now()
+	var count int64
+	if err = global.DB.Model(&shop.FlashSale{}).Where("id in ? and status = 1 and start_time <= ? and end_time > ?", ids.Ids, now, now).Count(&count).Error; err != nil {
+		return
+	}
+	if count > 0 {
+		return errors.New("进行中的秒杀活动不能删除，请先停用")
+	}
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("flash_sale_id in ?", ids.Ids).Delete(&shop.FlashSaleGoods{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&[]shop.FlashSale{}, "id in ?", ids.Ids).Error
+	})
+	if err == nil {
+		for _, id := range ids.Ids {
+			clearFlashStock(uint(id))
+		}
+	}
+	return err
+}
+
+// UpdateFlashSale 更新FlashSale记录
+// 活动开始前可以修改秒杀商品，开始后秒杀商品不能修改，只能修改活动名称、说明、状态和结束时间
+// Author [likfees](https://github.com/likfees)
+func (flashSaleService *FlashSaleService) UpdateFlashSale(flashSale shop.FlashSale) (err error) {
+	var old shop.FlashSale
+	if errors.Is(global.DB.Where("id = ?", flashSale.ID).First(&old).Error, gorm.ErrRecordNotFound) {
+		return errors.New("秒杀活动不存在")
+	}
+	if old.StartTime != nil && !old.StartTime.After(time.Now()) {
+		if flashSale.EndTime == nil || !flashSale.EndTime.After(*old.StartTime) {
+			return errors.New("结束时间必须晚于开始时间")
+		}
+		err = global.DB.Model(&old).Updates(map[string]interface{}{
+			"name":     flashSale.Name,
+			"remark":   flashSale.Remark,
+			"status":   flashSale.Status,
+			"end_time": flashSale.EndTime,
+		}).Error
+		if err == nil {
+			clearFlashStock(old.ID)
+		}
+		return err
+	}
+	if err = checkFlashSale(flashSale); err != nil {
+		return err
+	}
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Goods").Save(&flashSale).Error; err != nil {
+			return err
+		}
+		// 活动未开始时没有销量，直接重建秒杀商品
+		if err := tx.Unscoped().Where("flash_sale_id = ?", flashSale.ID).Delete(&shop.FlashSaleGoods{}).Error; err != nil {
+			return err
+		}
+		for i := range flashSale.Goods {
+			flashSale.Goods[i].ID = 0
+			flashSale.Goods[i].Sold = 0
+			flashSale.Goods[i].FlashSaleId = flashSale.ID
+		}
+		return tx.Create(&flashSale.Goods).Error
+	})
+	if err == nil {
+		clearFlashStock(flashSale.ID)
+	}
+	return err
+}
+
+// GetFlashSale 根据id获取FlashSale记录
+// Author [likfees](https://github.com/likfees)
+func (flashSaleService *FlashSaleService) GetFlashSale(id uint) (flashSale shop.FlashSale, err error) {
+	err = global.DB.Where("id = ?", id).
+		Preload("Goods", func(db *gorm.DB) *gorm.DB { return db.Order("sort asc, id asc") }).
+		Preload("Goods.Goods").Preload("Goods.SpecValue").
+		First(&flashSale).Error
+	return
+}
+
+// GetFlashSaleInfoList 分页获取FlashSale记录
+// Author [likfees](https://github.com/likfees)
+func (flashSaleService *FlashSaleService) GetFlashSaleInfoList(info shopReq.FlashSaleSearch) (list []shop.FlashSale, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	// 创建db
+	db := global.DB.Model(&shop.FlashSale{})
+	var flashSales []shop.FlashSale
+	// 如果有条件搜索 下方会自动创建搜索语句
+	if info.StartCreatedAt != nil && info.EndCreatedAt != nil {
+		db = db.Where("created_at BETWEEN ? AND ?", info.StartCreatedAt, info.EndCreatedAt)
+	}
+	if info.Name != "" {
+		db = db.Where("name LIKE ?", "%"+info.Name+"%")
+	}
+	if info.Status != nil {
+		db = db.Where("status = ?", info.Status)
+	}
+	err = db.Count(&total).Error
+	if err != nil {
+		return
+	}
+
+	err = db.Limit(limit).Offset(offset).Order("start_time desc").Preload("Goods").Find(&flashSales).Error
+	return flashSales, total, err
+}
+
+// GetFlashSaleCountdown 获取进行中和即将开始的秒杀活动及倒计时，剩余秒杀库存优先取 Redis 中的数量
+func (flashSaleService *FlashSaleService) GetFlashSaleCountdown() (resp shopResp.FlashSaleCountdown, err error) {
+	now := time.Now()
+	resp.Now = now
+	err = global.DB.Where("status = 1 and end_time > ?", now).Order("start_time asc").
+		Preload("Goods", func(db *gorm.DB) *gorm.DB { return db.Order("sort asc, id asc") }).
+		Preload("Goods.Goods.Images").Preload("Goods.SpecValue").
+		Find(&resp.FlashSales).Error
+	if err != nil {
+		return
+	}
+	for i := range resp.FlashSales {
+		f := &resp.FlashSales[i]
+		f.StartIn = int64(math.Max(0, math.Ceil(f.StartTime.Sub(now).Seconds())))
+		f.EndIn = int64(math.Ceil(f.EndTime.Sub(now).Seconds()))
+		var cached map[string]string
+		if global.Redis != nil {
+			cached, _ = global.Redis.HGetAll(context.Background(), flashStockKey(f.ID)).Result()
+		}
+		for k := range f.Goods {
+			g := &f.Goods[k]
+			g.Remain = g.Stock - g.Sold
+			if v, ok := cached[strconv.Itoa(int(g.ID))]; ok {
+				if remain, convErr := strconv.Atoi(v); convErr == nil {
+					g.Remain = remain
+				}
+			}
+			if g.Remain < 0 {
+				g.Remain = 0
+			}
+		}
+	}
+	return
+}
+
+// CreateFlashOrder 秒杀下单，在 Redis 中扣减秒杀库存后加入下单队列，返回排队凭证
+func (flashSaleService *FlashSaleService) CreateFlashOrder(userId uint, req shopReq.FlashOrderReq) (token string, err error) {
+	if global.Redis == nil {
+		return "", ErrFlashSaleRedis
+	}
+	if req.Num <= 0 {
+		return "", errors.New("购买数量错误")
+	}
+	if req.ShipmentType == 0 && req.AddressId <= 0 {
+		return "", errors.New("请选择收货地址")
+	}
+	var goods shop.FlashSaleGoods
+	if errors.Is(global.DB.Where("id = ?", req.FlashGoodsId).First(&goods).Error, gorm.ErrRecordNotFound) {
+		return "", errors.New("秒杀商品不存在")
+	}
+	var flashSale shop.FlashSale
+	if errors.Is(global.DB.Where("id = ?", goods.FlashSaleId).First(&flashSale).Error, gorm.ErrRecordNotFound) {
+		return "", ErrFlashSaleNotStart
+	}
+	now := time.Now()
+	if *flashSale.Status != 1 || flashSale.StartTime.After(now) || !flashSale.EndTime.After(now) {
+		return "", ErrFlashSaleNotStart
+	}
+	if goods.LimitPerUser > 0 && req.Num > goods.LimitPerUser {
+		return "", ErrFlashLimit
+	}
+	if err = reserveFlashStock(flashSale, goods, userId, req.Num); err != nil {
+		return "", err
+	}
+
+	token = uuid.NewV4().String()
+	msg, _ := json.Marshal(flashOrderMessage{Token: token, UserId: userId, Req: req, Created: now})
+	result, _ := json.Marshal(shopResp.FlashOrderResult{Token: token, Status: flashOrderQueuing})
+	ctx := context.Background()
+	pipe := global.Redis.TxPipeline()
+	pipe.Set(ctx, flashResultKey(token), result, flashResultTTL)
+	pipe.RPush(ctx, flashQueueKey, msg)
+	if _, err = pipe.Exec(ctx); err != nil {
+		global.SugarLog.Errorf("秒杀下单加入队列失败 userId:%d, flashGoodsId:%d, err:%v \n", userId, goods.ID, err)
+		releaseFlashStock(goods.FlashSaleId, goods.ID, userId, req.Num)
+		return "", errors.New("秒杀下单失败，请重试")
+	}
+	return token, nil
+}
+
+// GetFlashOrderResult 查询秒杀排队下单结果
+func (flashSaleService *FlashSaleService) GetFlashOrderResult(userId uint, token string) (result shopResp.FlashOrderResult, err error) {
+	if global.Redis == nil {
+		return result, ErrFlashSaleRedis
+	}
+	data, err := global.Redis.Get(context.Background(), flashResultKey(token)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return result, errors.New("下单记录不存在或已过期")
+	}
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(data, &result); err != nil {
+		return
+	}
+	// 订单只能由下单用户查询
+	if result.Status == flashOrderSuccess {
+		var count int64
+		global.DB.Model(&shop.Order{}).Where("id = ? and user_id = ?", result.OrderId, userId).Count(&count)
+		if count == 0 {
+			return shopResp.FlashOrderResult{}, errors.New("下单记录不存在或已过期")
+		}
+	}
+	return
+}
+
+// ConsumeFlashOrderQueue 消费秒杀下单队列，逐条创建订单，需要在 Redis 初始化后启动
+func (flashSaleService *FlashSaleService) ConsumeFlashOrderQueue() {
+	ctx := context.Background()
+	for {
+		values, err := global.Redis.BLPop(ctx, flashQueueTimeout, flashQueueKey).Result()
+		if err != nil {
+			if !errors.Is(err, redis.Nil) {
+				global.SugarLog.Errorf("读取秒杀下单队列异常, err:%v \n", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+		var msg flashOrderMessage
+		if err = json.Unmarshal([]byte(values[1]), &msg); err != nil {
+			global.SugarLog.Errorf("秒杀下单消息解析失败 msg:%s, err:%v \n", values[1], err)
+			continue
+		}
+		result := shopResp.FlashOrderResult{Token: msg.Token, Status: flashOrderSuccess}
+		order, err := createFlashOrder(msg)
+		if err != nil {
+			global.SugarLog.Errorf("秒杀下单失败 userId:%d, flashGoodsId:%d, err:%v \n", msg.UserId, msg.Req.FlashGoodsId, err)
+			result.Status, result.Msg = flashOrderFailed, err.Error()
+		} else {
+			result.OrderId, result.OrderSn = order.ID, order.OrderSn
+		}
+		data, _ := json.Marshal(result)
+		if err = global.Redis.Set(ctx, flashResultKey(msg.Token), data, flashResultTTL).Err(); err != nil {
+			global.SugarLog.Errorf("保存秒杀下单结果失败 token:%s, err:%v \n", msg.Token, err)
+		}
+	}
+}
+
+// createFlashOrder 创建秒杀订单，失败时归还 Redis 中扣减的秒杀库存和用户已购数量
+func createFlashOrder(msg flashOrderMessage) (order shop.Order, err error) {
+	req := msg.Req
+	var goods shop.FlashSaleGoods
+	if err = global.DB.Where("id = ?", req.FlashGoodsId).Preload("Goods.Images").Preload("SpecValue").First(&goods).Error; err != nil {
+		releaseFlashStock(0, req.FlashGoodsId, msg.UserId, req.Num)
+		return order, errors.New("秒杀商品不存在")
+	}
+	defer func() {
+		if err != nil {
+			releaseFlashStock(goods.FlashSaleId, goods.ID, msg.UserId, req.Num)
+		}
+	}()
+	var flashSale shop.FlashSale
+	if errors.Is(global.DB.Where("id = ?", goods.FlashSaleId).First(&flashSale).Error, gorm.ErrRecordNotFound) {
+		return order, ErrFlashSaleNotStart
+	}
+	// 排队期间活动结束的订单仍然创建，以进入队列的时间为准
+	if *flashSale.Status != 1 || flashSale.StartTime.After(msg.Created) || !flashSale.EndTime.After(msg.Created) {
+		return order, ErrFlashSaleNotStart
+	}
+	var user sysModel.SysUser
+	if err = global.DB.Where("id = ?", msg.UserId).First(&user).Error; err != nil {
+		return order, errors.New("用户查询失败")
+	}
+	order, detail, slot, err := newGoodsOrder(msg.UserId, req.GoodsOrderReq, goods.Goods, goods.SpecValue, goods.Price)
+	if err != nil {
+		return
+	}
+	order.FlashGoodsId = goods.ID
+
+	log := fmt.Sprintf("[FlashSaleService] createFlashOrder token:%s, userId:%d, flashGoodsId:%d, num:%d; ", msg.Token, msg.UserId, goods.ID, req.Num)
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		// 数据库中再次校验限购数量，Redis 数据丢失时兜底
+		if goods.LimitPerUser > 0 {
+			var bought int64
+			if err := tx.Model(&shop.Order{}).Select("COALESCE(SUM(num), 0)").
+				Where("user_id = ? and flash_goods_id = ? and status_cancel = 0", msg.UserId, goods.ID).
+				Scan(&bought).Error; err != nil {
+				return err
+			}
+			if int(bought)+req.Num > goods.LimitPerUser {
+				return ErrFlashLimit
+			}
+		}
+		// 按 sold + num <= stock 条件扣减秒杀库存，保证不会超卖
+		res := tx.Model(&shop.FlashSaleGoods{}).Where("id = ? and sold + ? <= stock", goods.ID, req.Num).
+			Update("sold", gorm.Expr("sold + ?", req.Num))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrFlashSoldOut
+		}
+		if err := saveGoodsOrder(tx, &order, &detail, slot, user, "秒杀订单已提交", log); err != nil {
+			if errors.Is(err, ErrStockNotEnough) {
+				return ErrFlashSoldOut
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	// 商品库存已在数据库中扣减，清除普通下单使用的库存缓存
+	clearStockReserve(goods.GoodsId)
+	return
+}
+
+// restoreFlashStock 取消秒杀订单时归还数据库中的秒杀库存
+// 事务可能回滚，Redis 中的秒杀库存和用户已购数量由调用方在事务提交后通过 releaseOrderFlashStock 归还
+func restoreFlashStock(tx *gorm.DB, order shop.Order) error {
+	if order.FlashGoodsId == 0 {
+		return nil
+	}
+	var goods shop.FlashSaleGoods
+	if err := tx.Where("id = ?", order.FlashGoodsId).First(&goods).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	return tx.Model(&goods).Update("sold", gorm.Expr("GREATEST(sold - ?, 0)", order.Num)).Error
+}
+
+// releaseOrderFlashStock 秒杀订单取消的事务提交后归还 Redis 中的秒杀库存和用户已购数量
+func releaseOrderFlashStock(order shop.Order) {
+	if order.FlashGoodsId == 0 {
+		return
+	}
+	releaseFlashStock(0, order.FlashGoodsId, uint(*order.UserId), order.Num)
+}
+
+// reserveFlashStock 在 Redis 中扣减秒杀库存并累加用户已购数量，缓存不存在时从数据库加载
+func reserveFlashStock(flashSale shop.FlashSale, goods shop.FlashSaleGoods, userId uint, num int) error {
+	ctx := context.Background()
+	keys := []string{flashStockKey(flashSale.ID), flashUserKey(flashSale.ID)}
+	field := strconv.Itoa(int(goods.ID))
+	ttl := int(time.Until(*flashSale.EndTime).Seconds()) + 3600
+	for i := 0; i < 2; i++ {
+		ret, err := flashReserveScript.Run(ctx, global.Redis, keys, field, flashUserField(goods.ID, userId), num, goods.LimitPerUser, ttl).Int()
+		if err != nil {
+			global.SugarLog.Errorf("Redis 扣减秒杀库存异常 flashGoodsId:%d, err:%v \n", goods.ID, err)
+			return errors.New("秒杀下单失败，请重试")
+		}
+		switch ret {
+		case 1:
+			return nil
+		case 0:
+			return ErrFlashSoldOut
+		case -2:
+			return ErrFlashLimit
+		}
+		// 缓存不存在 从数据库加载剩余秒杀库存后重试
+		pipe := global.Redis.TxPipeline()
+		pipe.HSetNX(ctx, keys[0], field, goods.Stock-goods.Sold)
+		pipe.ExpireAt(ctx, keys[0], flashSale.EndTime.Add(time.Hour))
+		if _, err = pipe.Exec(ctx); err != nil {
+			global.SugarLog.Errorf("Redis 加载秒杀库存异常 flashGoodsId:%d, err:%v \n", goods.ID, err)
+			return errors.New("秒杀下单失败，请重试")
+		}
+	}
+	return errors.New("秒杀下单失败，请重试")
+}
+
+// releaseFlashStock 归还 Redis 中的秒杀库存和用户已购数量，flashSaleId 为 0 时从数据库查询
+func releaseFlashStock(flashSaleId uint, flashGoodsId uint, userId uint, num int) {
+	if global.Redis == nil {
+		return
+	}
+	if flashSaleId == 0 {
+		if err := global.DB.Model(&shop.FlashSaleGoods{}).Where("id = ?", flashGoodsId).Pluck("flash_sale_id", &flashSaleId).Error; err != nil || flashSaleId == 0 {
+			return
+		}
+	}
+	keys := []string{flashStockKey(flashSaleId), flashUserKey(flashSaleId)}
+	if err := flashReleaseScript.Run(context.Background(), global.Redis, keys, strconv.Itoa(int(flashGoodsId)), flashUserField(flashGoodsId, userId), num).Err(); err != nil {
+		global.SugarLog.Errorf("Redis 归还秒杀库存异常 flashGoodsId:%d, userId:%d, err:%v \n", flashGoodsId, userId, err)
+	}
+}
+
+// clearFlashStock 清除活动的秒杀库存缓存，修改活动后调用，下次下单时从数据库重新加载
+func clearFlashStock(flashSaleId uint) {
+	if global.Redis == nil {
+		return
+	}
+	if err := global.Redis.Del(context.Background(), flashStockKey(flashSaleId)).Err(); err != nil {
+		global.SugarLog.Errorf("Redis 清除秒杀库存缓存异常 flashSaleId:%d, err:%v \n", flashSaleId, err)
+	}
+}
+
+// checkFlashSale 校验秒杀活动参数，秒杀库存不能超过商品当前库存
+func checkFlashSale(flashSale shop.FlashSale) error {
+	if strings.TrimSpace(flashSale.Name) == "" {
+		return errors.New("活动名称不能为空")
+	}
+	if flashSale.StartTime == nil || flashSale.EndTime == nil || !flashSale.EndTime.After(*flashSale.StartTime) {
+		return errors.New("请设置正确的活动时间")
+	}
+	if len(flashSale.Goods) == 0 {
+		return errors.New("请选择秒杀商品")
+	}
+	seen := make(map[string]bool, len(flashSale.Goods))
+	for _, g := range flashSale.Goods {
+		key := fmt.Sprintf("%d_%d", g.GoodsId, g.SpecId)
+		if seen[key] {
+			return errors.New("秒杀商品重复")
+		}
+		seen[key] = true
+		var goods shop.Goods
+		if errors.Is(global.DB.Where("id = ? and goods_area = 0", g.GoodsId).First(&goods).Error, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("商品 %d 不存在", g.GoodsId)
+		}
+		store := 0
+		if *goods.SpecType == 1 {
+			var spec shop.GoodsSpecValue
+			if g.SpecId <= 0 || errors.Is(global.DB.Where("id = ? and goods_id = ?", g.SpecId, goods.ID).First(&spec).Error, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("请选择商品 %s 参与秒杀的规格", goods.Name)
+			}
+			store = *spec.Store
+		} else {
+			if g.SpecId != 0 {
+				return fmt.Errorf("商品 %s 为单规格商品", goods.Name)
+			}
+			store = *goods.Store
+		}
+		if g.Price <= 0 {
+			return fmt.Errorf("商品 %s 的秒杀价必须大于 0", goods.Name)
+		}
+		if g.Stock <= 0 || g.Stock > store {
+			return fmt.Errorf("商品 %s 的秒杀库存必须在 1-%d 之间", goods.Name, store)
+		}
+		if g.LimitPerUser < 0 {
+			return fmt.Errorf("商品 %s 的限购数量错误", goods.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,376 @@
+package shop
+
+import (
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/common/request"
+	"fresh-shop/server/model/shop"
+	shopReq "fresh-shop/server/model/shop/request"
+	sysModel "fresh-shop/server/model/system"
+	systemReq "fresh-shop/server/model/system/request"
+	"fresh-shop/server/model/wechat/response"
+	"fresh-shop/server/service/common"
+	"fresh-shop/server/service/payment"
+	"fresh-shop/server/utils"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// 拼团
+// 用户开团或参团时创建拼团订单并扣减商品库存，按用户选择的支付方式支付（默认微信）。支付后订单为已付款待成团，
+// 团内已支付人数达到成团人数时所有团员订单流转为已付款待发货；超过成团时限仍未成团的团由定时任务处理，
+// 已支付的订单按原支付方式退款，未支付的订单取消，库存全部归还
+
+type GroupBuyService struct {
+}
+
+// CreateGroupBuy 创建GroupBuy记录
+// Author [likfees](https://github.com/likfees)
+func (groupBuyService *GroupBuyService) CreateGroupBuy(groupBuy shop.GroupBuy) (err error) {
+	if err = checkGroupBuy(groupBuy); err != nil {
+		return err
+	}
+	err = global.DB.Create(&groupBuy).Error
+	return err
+}
+
+// DeleteGroupBuy 删除GroupBuy记录
+// Author [likfees](https://github.com/likfees)
+func (groupBuyService *GroupBuyService) DeleteGroupBuy(groupBuy shop.GroupBuy) (err error) {
+	return groupBuyService.DeleteGroupBuyByIds(request.IdsReq{Ids: []int{int(groupBuy.ID)}})
+}
+
+// DeleteGroupBuyByIds 批量删除GroupBuy记录，有进行中拼团的活动不能删除
+// Author [likfees](https://github.com/likfees)
+func (groupBuyService *GroupBuyService) DeleteGroupBuyByIds(ids request.IdsReq) (err error) {
+	var count int64
+	if err = global.DB.Model(&shop.GroupBuyTeam{}).Where("group_buy_id in ? and status = ?", ids.Ids, shop.GroupTeamOngoing).Count(&count).Error; err != nil {
+		return
+	}
+	if count > 0 {
+		return errors.New("活动还有进行中的拼团，请先停用活动并等待拼团结束")
+	}
+	err = global.DB.Delete(&[]shop.GroupBuy{}, "id in ?", ids.Ids).Error
+	return err
+}
+
+// UpdateGroupBuy 更新GroupBuy记录，已开的团按开团时的成团人数和截止时间执行
+// Author [likfees](https://github.com/likfees)
+func (groupBuyService *GroupBuyService) UpdateGroupBuy(groupBuy shop.GroupBuy) (err error) {
+	if err = checkGroupBuy(groupBuy); err != nil {
+		return err
+	}
+	err = global.DB.Omit("Goods", "SpecValue").Save(&groupBuy).Error
+	return err
+}
+
+// GetGroupBuy 根据id获取GroupBuy记录
+// Author [likfees](https://github.com/likfees)
+func (groupBuyService *GroupBuyService) GetGroupBuy(id uint) (groupBuy shop.GroupBuy, err error) {
+	err = global.DB.Where("id = ?", id).Preload("Goods.Images").Preload("SpecValue").First(&groupBuy).Error
+	return
+}
+
+// GetGroupBuyInfoList 分页获取GroupBuy记录
+// Author [likfees](https://github.com/likfees)
+func (groupBuyService *GroupBuyService) GetGroupBuyInfoList(info shopReq.GroupBuySearch) (list []shop.GroupBuy, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	// 创建db
+	db := global.DB.Model(&shop.GroupBuy{})
+	var groupBuys []shop.GroupBuy
+	// 如果有条件搜索 下方会自动创建搜索语句
+	if info.StartCreatedAt != nil && info.EndCreatedAt != nil {
+		db = db.Where("created_at BETWEEN ? AND ?", info.StartCreatedAt, info.EndCreatedAt)
+	}
+	if info.Name != "" {
+		db = db.Where("name LIKE ?", "%"+info.Name+"%")
+	}
+	if info.GoodsId != 0 {
+		db = db.Where("goods_id = ?", info.GoodsId)
+	}
+	if info.Status != nil {
+		db = db.Where("status = ?", info.Status)
+	}
+	err = db.Count(&total).Error
+	if err != nil {
+		return
+	}
+
+	err = db.Limit(limit).Offset(offset).Order("id desc").Preload("Goods").Preload("SpecValue").Find(&groupBuys).Error
+	return groupBuys, total, err
+}
+
+// GetActiveGroupBuys 获取进行中的拼团活动
+func (groupBuyService *GroupBuyService) GetActiveGroupBuys() (list []shop.GroupBuy, err error) {
+	now := time.Now()
+	err = global.DB.Where("status = 1 and start_time <= ? and end_time > ?", now, now).
+		Order("sort asc, id desc").Preload("Goods.Images").Preload("SpecValue").Find(&list).Error
+	return
+}
+
+// GetJoinableTeams 获取活动可参加的团，团长已支付、未满员且未到截止时间，即将截止的排在前面
+func (groupBuyService *GroupBuyService) GetJoinableTeams(groupBuyId uint) (list []shop.GroupBuyTeam, err error) {
+	err = global.DB.Where("group_buy_id = ? and status = ? and paid_num > 0 and joined_num < group_size and expire_time > ?", groupBuyId, shop.GroupTeamOngoing, time.Now()).
+		Order("expire_time asc").Limit(20).Find(&list).Error
+	return
+}
+
+// GetGroupBuyTeamInfoList 分页获取拼团团队及团员订单
+func (groupBuyService *GroupBuyService) GetGroupBuyTeamInfoList(info shopReq.GroupBuyTeamSearch) (list []shop.GroupBuyTeam, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.DB.Model(&shop.GroupBuyTeam{})
+	if info.GroupBuyId != 0 {
+		db = db.Where("group_buy_id = ?", info.GroupBuyId)
+	}
+	if info.Status != nil {
+		db = db.Where("status = ?", info.Status)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Limit(limit).Offset(offset).Order("id desc").Preload("GroupBuy").Preload("Orders").Find(&list).Error
+	return
+}
+
+// CreateGroupOrder 拼团下单，TeamId 为 0 时开团，否则参加该团，返回微信支付所需要的参数
+func (groupBuyService *GroupBuyService) CreateGroupOrder(req shopReq.GroupOrderReq, userClaims *systemReq.CustomClaims, clientIP string) (resp *response.CreateOrderResp, err error) {
+	var groupBuy shop.GroupBuy
+	if errors.Is(global.DB.Where("id = ? and status = 1", req.GroupBuyId).Preload("Goods.Images").Preload("SpecValue").First(&groupBuy).Error, gorm.ErrRecordNotFound) {
+		return nil, errors.New("拼团活动不存在或已结束")
+	}
+	now := time.Now()
+	if req.TeamId == 0 && (groupBuy.StartTime.After(now) || !groupBuy.EndTime.After(now)) {
+		return nil, errors.New("拼团活动未开始或已结束")
+	}
+	var user sysModel.SysUser
+	if err = global.DB.Where("id = ?", userClaims.ID).First(&user).Error; err != nil {
+		return nil, errors.New("用户查询失败")
+	}
+	order, detail, slot, err := newGoodsOrder(user.ID, req.GoodsOrderReq, groupBuy.Goods, groupBuy.SpecValue, groupBuy.Price)
+	if err != nil {
+		return nil, err
+	}
+
+	log := fmt.Sprintf("[GroupBuyService] CreateGroupOrder userId:%d, groupBuyId:%d, teamId:%d; ", user.ID, groupBuy.ID, req.TeamId)
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		team := shop.GroupBuyTeam{
+			GroupBuyId: groupBuy.ID,
+			LeaderId:   user.ID,
+			GroupSize:  groupBuy.GroupSize,
+			JoinedNum:  1,
+			Status:     shop.GroupTeamOngoing,
+			ExpireTime: now.Add(time.Duration(groupBuy.TimeLimit) * time.Minute),
+		}
+		title := "拼团订单已提交(开团)"
+		if req.TeamId == 0 {
+			if err := tx.Create(&team).Error; err != nil {
+				global.SugarLog.Errorf("log:%s, 开团失败 err:%v \n", log, err)
+				return errors.New("开团失败")
+			}
+		} else {
+			var joined int64
+			if err := tx.Model(&shop.Order{}).Where("group_team_id = ? and user_id = ? and status_cancel = 0 and status_refund = 0", req.TeamId, user.ID).Count(&joined).Error; err != nil {
+				return err
+			}
+			if joined > 0 {
+				return errors.New("您已参加该团")
+			}
+			// 按 joined_num < group_size 条件占用名额，并发参团不会超员
+			res := tx.Model(&shop.GroupBuyTeam{}).
+				Where("id = ? and group_buy_id = ? and status = ? and joined_num < group_size and expire_time > ?", req.TeamId, groupBuy.ID, shop.GroupTeamOngoing, now).
+				Update("joined_num", gorm.Expr("joined_num + 1"))
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errors.New("该团已满员或已结束")
+			}
+			team.ID = req.TeamId
+			title = "拼团订单已提交(参团)"
+		}
+		order.GroupTeamId = team.ID
+		return saveGoodsOrder(tx, &order, &detail, slot, user, title, log)
+	})
+	if err != nil {
+		return nil, err
+	}
+	clearStockReserve(groupBuy.GoodsId)
+	var payParams interface{}
+	// 余额支付的订单由用户输入安全密码后调用 BalancePay 完成支付
+	if payment.IsOnline(*order.Payment) {
+		// 发起支付返回参数
+		if payParams, err = startPayment(order, userClaims.OpenId, clientIP); err != nil {
+			global.SugarLog.Errorf("log:%s, 发起支付异常, err: %v \n", log, err)
+			return
+		}
+	}
+	resp = &response.CreateOrderResp{
+		Order: order,
+		Pay:   payParams,
+	}
+	return
+}
+
+// ExpireGroupTeams 处理超过成团时限仍未成团的团，由定时任务调用
+func (groupBuyService *GroupBuyService) ExpireGroupTeams() {
+	var teams []shop.GroupBuyTeam
+	err := global.DB.Where("status = ? and expire_time < ?", shop.GroupTeamOngoing, time.Now()).
+		Order("id asc").Limit(100).Find(&teams).Error
+	if err != nil {
+		global.SugarLog.Errorf("查询超时未成团的拼团失败, err:%v \n", err)
+		return
+	}
+	for _, team := range teams {
+		if err = failGroupTeam(team); err != nil {
+			global.SugarLog.Errorf("拼团失败处理异常 teamId:%d, err:%v \n", team.ID, err)
+		}
+	}
+}
+
+// failGroupTeam 拼团失败，已支付的订单退款，未支付的订单取消，库存全部归还
+func failGroupTeam(team shop.GroupBuyTeam) error {
+	var orders []shop.Order
+	if err := global.DB.Where("group_team_id = ? and status_cancel = 0 and status_refund = 0", team.ID).Find(&orders).Error; err != nil {
+		return err
+	}
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		// 带上状态条件，与最后一位团员支付成团并发时以先提交的为准
+		res := tx.Model(&shop.GroupBuyTeam{}).Where("id = ? and status = ?", team.ID, shop.GroupTeamOngoing).
+			Updates(map[string]interface{}{"status": shop.GroupTeamFailed, "finish_time": time.Now()})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrOrderStateChanged
+		}
+		for i := range orders {
+			if *orders[i].Status != orderStatusGrouping {
+				continue
+			}
+			if err := failGroupOrder(tx, &orders[i], TimerOperator, "拼团失败退款"); err != nil {
+				global.SugarLog.Errorf("拼团失败订单退款失败 orderSn:%s, err:%v \n", orders[i].OrderSn, err)
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, ErrOrderStateChanged) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// 未支付的订单按超时订单取消，已在微信侧支付的订单在支付回调中退款
+	orderService := OrderService{}
+	for _, o := range orders {
+		if *o.Status != 0 {
+			releaseOrderStockReserve(o.ID)
+			submitRefund(o.ID, TimerOperator)
+			continue
+		}
+		if err = orderService.cancelTimeoutOrder(o); err != nil {
+			global.SugarLog.Errorf("拼团失败取消未支付订单失败 orderSn:%s, err:%v \n", o.OrderSn, err)
+		}
+	}
+	global.SugarLog.Infof("拼团失败 teamId:%d, 已支付人数:%d/%d \n", team.ID, team.PaidNum, team.GroupSize)
+	return nil
+}
+
+// groupOrderPaid 在事务中处理拼团订单支付，已支付人数达到成团人数时拼团成功
+// 支付完成时拼团已经失败或已超过成团时限的订单直接退款；调用前需要设置 order.Finish 为实付金额
+func groupOrderPaid(tx *gorm.DB, order *shop.Order, op OrderOperator, remark string, updates map[string]interface{}) error {
+	var team shop.GroupBuyTeam
+	if err := tx.Where("id = ?", order.GroupTeamId).First(&team).Error; err != nil {
+		return err
+	}
+	if err := orderTransit(tx, order, OrderEventGroupPay, op, remark, updates); err != nil {
+		return err
+	}
+	// 已超过成团时限但定时任务尚未处理的团同样视为已结束，不能再成团
+	res := tx.Model(&shop.GroupBuyTeam{}).Where("id = ? and status = ? and paid_num < group_size and expire_time > ?", team.ID, shop.GroupTeamOngoing, time.Now()).
+		Update("paid_num", gorm.Expr("paid_num + 1"))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return failGroupOrder(tx, order, op, "拼团已结束，支付退款")
+	}
+	if err := tx.Where("id = ?", team.ID).First(&team).Error; err != nil {
+		return err
+	}
+	if team.PaidNum < team.GroupSize {
+		return nil
+	}
+	// 拼团成功 所有已支付的团员订单流转为待发货
+	if err := tx.Model(&team).Updates(map[string]interface{}{"status": shop.GroupTeamSuccess, "finish_time": time.Now()}).Error; err != nil {
+		return err
+	}
+	var orders []shop.Order
+	if err := tx.Where("group_team_id = ? and status = ? and status_cancel = 0 and status_refund = 0", team.ID, orderStatusGrouping).Find(&orders).Error; err != nil {
+		return err
+	}
+	for i := range orders {
+		if err := orderTransit(tx, &orders[i], OrderEventGroupSuccess, op, fmt.Sprintf("%d人团已成团", team.GroupSize), nil); err != nil {
+			return err
+		}
+	}
+	global.SugarLog.Infof("拼团成功 teamId:%d \n", team.ID)
+	return nil
+}
+
+// failGroupOrder 拼团失败的已支付订单，归还库存和配送时段后按原支付方式退款
+func failGroupOrder(tx *gorm.DB, order *shop.Order, op OrderOperator, reason string) error {
+	if err := restoreOrderStock(tx, order.ID); err != nil {
+		return err
+	}
+	if err := common.ReleaseDeliverySlot(tx, order.SlotId, order.SlotDay); err != nil {
+		return err
+	}
+	return startOrderRefund(tx, order, op, utils.GenerateOrderNumber("RF"), order.Finish, reason)
+}
+
+// releaseGroupSeat 取消拼团订单时释放团内名额
+func releaseGroupSeat(tx *gorm.DB, order shop.Order) error {
+	if order.GroupTeamId == 0 {
+		return nil
+	}
+	return tx.Model(&shop.GroupBuyTeam{}).Where("id = ? and status = ?", order.GroupTeamId, shop.GroupTeamOngoing).
+		Update("joined_num", gorm.Expr("GREATEST(joined_num - 1, 0)")).Error
+}
+
+// checkGroupBuy 校验拼团活动参数
+func checkGroupBuy(groupBuy shop.GroupBuy) error {
+	if strings.TrimSpace(groupBuy.Name) == "" {
+		return errors.New("活动名称不能为空")
+	}
+	if groupBuy.StartTime == nil || groupBuy.EndTime == nil || !groupBuy.EndTime.After(*groupBuy.StartTime) {
+		return errors.New("请设置正确的活动时间")
+	}
+	if groupBuy.GroupSize < 2 {
+		return errors.New("成团人数至少为 2 人")
+	}
+	if groupBuy.TimeLimit <= 0 {
+		return errors.New("成团时限必须大于 0")
+	}
+	if groupBuy.Price <= 0 {
+		return errors.New("拼团价必须大于 0")
+	}
+	var goods shop.Goods
+	if errors.Is(global.DB.Where("id = ? and goods_area = 0", groupBuy.GoodsId).First(&goods).Error, gorm.ErrRecordNotFound) {
+		return errors.New("商品不存在")
+	}
+	if *goods.SpecType == 1 {
+		var count int64
+		global.DB.Model(&shop.GoodsSpecValue{}).Where("id = ? and goods_id = ?", groupBuy.SpecId, goods.ID).Count(&count)
+		if groupBuy.SpecId <= 0 || count == 0 {
+			return errors.New("请选择参与拼团的商品规格")
+		}
+	} else if groupBuy.SpecId != 0 {
+		return errors.New("单规格商品不能选择规格")
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"fresh-shop/server/global"
+	"fresh-shop/server/model/business"
 	"fresh-shop/server/model/common/request"
 	"fresh-shop/server/model/shop"
 	shopReq "fresh-shop/server/model/shop/request"
@@ -12,11 +13,11 @@ import (
 	systemReq "fresh-shop/server/model/system/request"
 	"fresh-shop/server/model/wechat/response"
 	"fresh-shop/server/service/common"
-	"fresh-shop/server/service/wechat"
+	"fresh-shop/server/service/payment"
 	"fresh-shop/server/utils"
 	"github.com/gin-gonic/gin"
-	orderPay "github.com/silenceper/wechat/v2/pay/order"
 	"gorm.io/gorm"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -38,7 +39,7 @@ func (orderService *OrderService) CreateOrder(order shop.Order, userClaims *syst
 	address := shop.UserAddress{}
 	addressName := ""
 	if order.AddressId > 0 {
-		if errors.Is(global.DB.Where("id = ?", order.AddressId).First(&address).Error, gorm.ErrRecordNotFound) {
+		if errors.Is(global.DB.Where("id = ? and user_id = ?", order.AddressId, user.ID).First(&address).Error, gorm.ErrRecordNotFound) {
 			return nil, errors.New("收货地址不存在")
 		}
 		if *address.Sex == 1 {
@@ -49,6 +50,7 @@ func (orderService *OrderService) CreateOrder(order shop.Order, userClaims *syst
 	}
 	var cartList []shop.Cart
 	var orderDetailList []shop.OrderDetails
+	var slot business.DeliverySlot
 
 	if order.PointGoodsId != 0 { // 积分商品
 		var goodsInfo shop.Goods
@@ -69,7 +71,7 @@ func (orderService *OrderService) CreateOrder(order shop.Order, userClaims *syst
 
 	} else { // 普通商品
 		// 获取购物车已选中的商品数据
-		global.DB.Where("user_id = ? and checked = 1", order.UserId).Preload("Goods.Images").Find(&cartList)
+		global.DB.Where("user_id = ? and checked = 1", order.UserId).Preload("Goods.Images").Preload("SpecValue").Find(&cartList)
 		if len(cartList) <= 0 {
 			global.SugarLog.Errorf("创建订单时查询商品信息异常, err:%v \n", err)
 			return nil, errors.New("商品查询失败")
@@ -85,35 +87,28 @@ func (orderService *OrderService) CreateOrder(order shop.Order, userClaims *syst
 			return
 		}
 	}
-	// 获取取餐号码
-	if *order.ShipmentType == 1 {
-		// 获取到最后一条有取餐号码的订单
-		var pickOrder shop.Order
-		if pickErr := global.DB.Select("pick_up_number").Where("pick_up_number is not null and pick_up_number <> 0").Order("id desc").First(&pickOrder).Error; pickErr == nil {
-			order.PickUpNumber = pickOrder.PickUpNumber + 1
-		} else {
-			order.PickUpNumber = 101
-		}
-	}
-
-	// 判断库存是否充足  以后可以上锁，解决高并发
+	// 预先判断库存是否充足，并发下以事务中的条件扣减为准
+	weight := 0 // 商品总重量 计算运费使用
 	for _, c := range cartList {
+		// 多规格商品按所选规格计算价格和库存
+		if *c.Goods.SpecType == 1 && (c.SpecItemId <= 0 || c.SpecValue.GoodsId != c.Goods.ID) {
+			global.SugarLog.Errorf("创建订单时商品规格不存在 goodsId:%d, specItemId:%d \n", c.Goods.ID, c.SpecItemId)
+			return nil, errors.New("请重新选择商品规格")
+		}
+		price, costPrice := cartPrice(c)
 		// 购物车数量大于库存
-		if c.Num > *c.Goods.Store {
-			global.SugarLog.Errorf("创建订单使库存不足 goodsId:%d, 购买数量:%d, 库存数量:%d \n", c.Goods.ID, c.Num, *c.Goods.Store)
-			return nil, errors.New("商品库存不足")
+		if store := cartStore(c); c.Num > store {
+			global.SugarLog.Errorf("创建订单使库存不足 goodsId:%d, specItemId:%d, 购买数量:%d, 库存数量:%d \n", c.Goods.ID, c.SpecItemId, c.Num, store)
+			return nil, ErrStockNotEnough
 		}
 		// 计算总数量
 		order.Num = order.Num + c.Num
+		weight += cartWeight(c)
 		if order.PointGoodsId != 0 { // 积分商品
-			order.Total = *c.Goods.CostPrice
+			order.Total = costPrice
 		} else {
 			// 计算总金额 如果优惠价小于成本价
-			if *c.Goods.Price > 0 && *c.Goods.Price < *c.Goods.CostPrice {
-				order.Total += float64(c.Num) * *c.Goods.Price
-			} else {
-				order.Total += float64(c.Num) * *c.Goods.CostPrice
-			}
+			order.Total += cartAmount(c)
 		}
 
 		// 组织订单详情数据
@@ -127,31 +122,32 @@ func (orderService *OrderService) CreateOrder(order shop.Order, userClaims *syst
 		orderDetail.GoodsImage = imgUrl
 		orderDetail.Unit = c.Goods.Unit
 		orderDetail.Num = c.Num
-		orderDetail.Price = *c.Goods.Price
+		orderDetail.Price = price
 		orderDetail.Total = 0
 		if order.PointGoodsId != 0 { // 积分商品
-			orderDetail.Total = *c.Goods.CostPrice
+			orderDetail.Total = costPrice
 		} else {
 			// 计算单个商品多个数量的总金额
-			if *c.Goods.Price > 0 && *c.Goods.Price < *c.Goods.CostPrice {
-				orderDetail.Total = float64(c.Num) * *c.Goods.Price
-			} else {
-				orderDetail.Total = float64(c.Num) * *c.Goods.CostPrice
-			}
+			orderDetail.Total = cartAmount(c)
 		}
 
-		// 规格id 现在只开发了单规格订单，多规格以后在支持
-		orderDetail.SpecId = 0
-		spec := ""
-		if *c.Goods.Weight > 0 {
-			spec = fmt.Sprintf("%dg", *c.Goods.Weight)
-		}
-		if strings.TrimSpace(spec) == "" {
-			spec = c.Goods.Unit
+		if *c.Goods.SpecType == 1 {
+			// 多规格记录所选规格
+			orderDetail.SpecId = int(c.SpecValue.ID)
+			orderDetail.SpecKeyName = c.SpecValue.KeyName
 		} else {
-			spec = spec + "/" + c.Goods.Unit
+			orderDetail.SpecId = 0
+			spec := ""
+			if *c.Goods.Weight > 0 {
+				spec = fmt.Sprintf("%dg", *c.Goods.Weight)
+			}
+			if strings.TrimSpace(spec) == "" {
+				spec = c.Goods.Unit
+			} else {
+				spec = spec + "/" + c.Goods.Unit
+			}
+			orderDetail.SpecKeyName = spec
 		}
-		orderDetail.SpecKeyName = spec
 		// 计算赠送积分
 		if pointSwitch && order.PointGoodsId == 0 {
 			point, err := strconv.Atoi(pointCfg)
@@ -164,9 +160,50 @@ func (orderService *OrderService) CreateOrder(order shop.Order, userClaims *syst
 		orderDetailList = append(orderDetailList, orderDetail)
 	}
 
+	// 使用优惠券 优惠金额按商品金额分摊到订单详情，积分商品不能使用优惠券
+	order.CouponAmount = 0
+	if order.PointGoodsId != 0 {
+		order.CouponId = 0
+	}
+	if order.CouponId > 0 {
+		var userCoupon shop.UserCoupon
+		if errors.Is(global.DB.Where("id = ? and user_id = ?", order.CouponId, order.UserId).First(&userCoupon).Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("优惠券不存在")
+		}
+		lines := make([]couponLine, 0, len(cartList))
+		for _, c := range cartList {
+			lines = append(lines, cartCouponLine(c))
+		}
+		discount, shares, couponErr := calcCouponDiscount(userCoupon, lines)
+		if couponErr != nil {
+			return nil, couponErr
+		}
+		order.CouponAmount = discount
+		for k := range orderDetailList {
+			orderDetailList[k].CouponAmount = shares[k]
+			// 赠送积分按优惠后的金额计算
+			if orderDetailList[k].Total > 0 {
+				orderDetailList[k].GiftPoints = orderDetailList[k].GiftPoints * (orderDetailList[k].Total - shares[k]) / orderDetailList[k].Total
+			}
+		}
+	}
+
+	// 校验配送范围和预约的配送时段，配送时段容量在订单事务中占用
+	if slot, err = checkOrderShipment(&order, address); err != nil {
+		return nil, err
+	}
+	// 计算运费 积分商品不收运费
+	if order.PointGoodsId == 0 {
+		if order.Postage, _, err = calcOrderPostage(*order.ShipmentType, address, order.Total-order.CouponAmount, weight); err != nil {
+			return nil, err
+		}
+	}
+
 	// 设置订单基本信息
 	order.OrderSn = utils.GenerateOrderNumber("SN")
+	order.PointAmount = 0
 	if order.PointGoodsId > 0 { // 积分商品
+		order.PointOffset = 0
 		order.GoodsArea = utils.Pointer(1)
 		order.Payment = utils.Pointer(4) // 积分支付
 		order.Status = utils.Pointer(1)  // 已付款状态
@@ -174,12 +211,20 @@ func (orderService *OrderService) CreateOrder(order shop.Order, userClaims *syst
 		order.PayTime = utils.Pointer(time.Now())
 	} else { // 普通商品
 		order.GoodsArea = utils.Pointer(0)
-		order.Payment = utils.Pointer(2) // 默认是微信支付
-		order.Status = utils.Pointer(0)  // 未付款状态
+		order.Payment = orderPayment(order.Payment)
+		order.Status = utils.Pointer(0) // 未付款状态
+		// 积分抵扣部分商品金额，剩余金额使用微信或余额支付
+		if order.PointOffset, order.PointAmount, err = calcPointOffset(order.Total-order.CouponAmount, order.PointOffset); err != nil {
+			return nil, err
+		}
+		if order.PointAmount > 0 && payAmount(order) <= 0 {
+			return nil, errors.New("积分抵扣后应付金额不能为0")
+		}
 	}
 	order.ShipmentName = addressName
 	order.ShipmentMobile = address.Mobile
 	order.ShipmentAddress = address.Address + address.Title + address.Detail
+	order.ShipmentLng, order.ShipmentLat = address.Longitude, address.Latitude
 	order.StatusCancel = utils.Pointer(0)
 	order.StatusRefund = utils.Pointer(0)
 	// 计算总赠送积分
@@ -189,86 +234,140 @@ func (orderService *OrderService) CreateOrder(order shop.Order, userClaims *syst
 			global.SugarLog.Errorf("创建订单时转换积分配置参数异常, err:%v \n", err)
 			return nil, err
 		}
-		// 公式 总金额 * n%
-		order.GiftPoints = order.Total * (float64(point) / 100)
+		// 公式 (总金额 - 优惠券抵扣 - 积分抵扣) * n%
+		order.GiftPoints = goodsPayAmount(order) * (float64(point) / 100)
 	}
 
 	log := fmt.Sprintf("[OrderService] CreateOrder submit data:%+v; \n", order)
-	// 启动事务
-	txDB := global.DB.Begin()
-	// 创建订单
-	if err = global.DB.Create(&order).Error; err != nil {
-		txDB.Rollback()
-		global.SugarLog.Errorf("log:%s,err:%v \n", log, err)
-		return nil, errors.New("订单创建失败")
-	}
-	if order.ID == 0 {
-		txDB.Rollback()
-		global.SugarLog.Errorf("log:%s, err: 创建订单后订单ID获取失败 \n", log)
-		return nil, errors.New("订单创建失败")
-	}
-	// 创建订单详情
-	// 设置订单详情 orderId
-	for k, _ := range orderDetailList {
-		orderDetailList[k].OrderId = order.ID
-	}
-	if err = global.DB.Create(&orderDetailList).Error; err != nil {
-		txDB.Rollback()
-		global.SugarLog.Errorf("log:%s,err:%v \n", log, err)
-		return nil, errors.New("订单详情创建失败")
-	}
-	// 扣减库存
-	for _, v := range cartList {
-		if err = global.DB.Model(&shop.Goods{}).Where("id = ?", v.GoodsId).Update("store", gorm.Expr("store - ?", v.Num)).Error; err != nil {
-			txDB.Rollback()
-			global.SugarLog.Errorf("log:%s,err:%v \n", log, err)
-			return nil, errors.New("库存扣减失败")
+	// Redis 预占库存，库存不足的请求直接返回，不再进入数据库事务
+	reservation := &stockReservation{}
+	for _, c := range cartList {
+		if err = reservation.reserve(c.Goods.ID, c.SpecItemId, c.Num); err != nil {
+			reservation.release()
+			global.SugarLog.Errorf("log:%s, 预占库存失败 goodsId:%d, specItemId:%d, err:%v \n", log, c.Goods.ID, c.SpecItemId, err)
+			return nil, err
 		}
 	}
-	if order.PointGoodsId == 0 {
-		// 删除购物车列表
-		if err = global.DB.Delete(&cartList).Error; err != nil {
-			txDB.Rollback()
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		// 获取取餐号码
+		if *order.ShipmentType == 1 {
+			var err error
+			if order.PickUpNumber, order.PickUpCode, err = nextPickUpNumber(tx); err != nil {
+				global.SugarLog.Errorf("log:%s, 生成取餐号码失败 err:%v \n", log, err)
+				return errors.New("取餐号码生成失败")
+			}
+		}
+		// 占用配送时段容量
+		if order.SlotId > 0 {
+			if err := common.ReserveDeliverySlot(tx, slot, order.SlotDay); err != nil {
+				global.SugarLog.Errorf("log:%s, 占用配送时段失败 slotId:%d, day:%s, err:%v \n", log, order.SlotId, order.SlotDay, err)
+				if errors.Is(err, common.ErrDeliverySlotFull) {
+					return err
+				}
+				return errors.New("配送时段预约失败")
+			}
+		}
+		// 创建订单
+		if err := tx.Create(&order).Error; err != nil {
 			global.SugarLog.Errorf("log:%s,err:%v \n", log, err)
-			return nil, errors.New("购物车删除失败")
+			return errors.New("订单创建失败")
 		}
-	}
-
-	if order.PointGoodsId > 0 {
-		// 扣减积分
-		f := common.NewFinance(common.OptionTypeCASH, 1, user.ID, user.Username, -order.Total, order.OrderSn, user.ID, user.Username, "购买积分商品")
-		err := common.AccountUnifyDeduction(common.POINT, f)
-		if err != nil {
-			txDB.Rollback()
-			global.SugarLog.Errorf("log:%s, 积分扣减失败 err:%v \n", log, err)
-			return nil, err
+		if order.ID == 0 {
+			global.SugarLog.Errorf("log:%s, err: 创建订单后订单ID获取失败 \n", log)
+			return errors.New("订单创建失败")
+		}
+		if err := writeOrderLog(tx, order.ID, OrderEventCreate, "订单已提交", currentOrderState(order), UserOperator(user), ""); err != nil {
+			global.SugarLog.Errorf("log:%s, 写入订单日志失败 err:%v \n", log, err)
+			return errors.New("订单创建失败")
+		}
+		// 核销优惠券
+		if err := useOrderCoupon(tx, order); err != nil {
+			global.SugarLog.Errorf("log:%s, 核销优惠券失败 couponId:%d, err:%v \n", log, order.CouponId, err)
+			return err
+		}
+		// 冻结抵扣积分
+		if err := freezeOrderPoints(tx, order, user); err != nil {
+			global.SugarLog.Errorf("log:%s, 冻结抵扣积分失败 points:%f, err:%v \n", log, order.PointOffset, err)
+			return err
+		}
+		// 创建订单详情
+		// 设置订单详情 orderId
+		for k := range orderDetailList {
+			orderDetailList[k].OrderId = order.ID
+		}
+		if err := tx.Create(&orderDetailList).Error; err != nil {
+			global.SugarLog.Errorf("log:%s,err:%v \n", log, err)
+			return errors.New("订单详情创建失败")
+		}
+		// 扣减库存 增加销量
+		for _, v := range cartList {
+			if err := deductStock(tx, v.Goods.ID, v.SpecItemId, v.Num); err != nil {
+				global.SugarLog.Errorf("log:%s, 库存扣减失败 goodsId:%d, specItemId:%d, err:%v \n", log, v.Goods.ID, v.SpecItemId, err)
+				if errors.Is(err, ErrStockNotEnough) {
+					return err
+				}
+				return errors.New("库存扣减失败")
+			}
+		}
+		if order.PointGoodsId == 0 {
+			// 删除购物车列表
+			if err := tx.Delete(&cartList).Error; err != nil {
+				global.SugarLog.Errorf("log:%s,err:%v \n", log, err)
+				return errors.New("购物车删除失败")
+			}
 		}
-	}
 
-	// 提交事务
-	txDB.Commit()
-	jsApiData := &orderPay.Config{}
-	if order.PointGoodsId == 0 {
-		// 发起 JSAIP 支付返回参数
-		err, jsApiData = wechat.JSAPIPay(userClaims.OpenId, order.OrderSn, order.ID, order.Total, clientIP)
-		if err != nil {
-			global.SugarLog.Errorf("log:%s, 微信 JsApi 发起调用异常, err: %v \n", log, err)
+		if order.PointGoodsId > 0 {
+			// 扣减积分
+			f := common.NewFinance(common.OptionTypeCASH, common.FinanceTypePointGoods, user.ID, user.Username, -order.Total, order.OrderSn, user.ID, user.Username, "购买积分商品")
+			if err := common.AccountUnifyDeductionTx(tx, common.POINT, f); err != nil {
+				global.SugarLog.Errorf("log:%s, 积分扣减失败 err:%v \n", log, err)
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		reservation.release()
+		return nil, err
+	}
+	var payParams interface{}
+	// 余额支付的订单由用户输入安全密码后调用 BalancePay 完成支付
+	if order.PointGoodsId == 0 && payment.IsOnline(*order.Payment) {
+		// 发起支付返回参数
+		if payParams, err = startPayment(order, userClaims.OpenId, clientIP); err != nil {
+			global.SugarLog.Errorf("log:%s, 发起支付异常, err: %v \n", log, err)
 			return
 		}
 	}
 	resp = &response.CreateOrderResp{
 		Order: order,
-		Pay:   *jsApiData,
+		Pay:   payParams,
 	}
 	return
 }
 
-// OrderPay 支付 Order, 返回微信支付所需要的参数
+// orderPayment 下单选择的支付方式，支持余额、微信、支付宝，未选择或不支持时默认为微信支付
+func orderPayment(p *int) *int {
+	if p == nil || (*p != payment.Balance && *p != payment.Alipay) {
+		return utils.Pointer(payment.Wechat)
+	}
+	return utils.Pointer(*p)
+}
+
+// payAmount 订单应付金额 商品总金额 - 优惠券抵扣 - 积分抵扣 + 运费
+func payAmount(order shop.Order) float64 {
+	return math.Round((goodsPayAmount(order)+order.Postage)*100) / 100
+}
+
+// OrderPay 支付 Order, 返回客户端调起支付所需要的参数
+// 传入 payment 时切换为该支付方式，切换前先关闭原渠道的待支付交易
 // Author [likfees](https://github.com/likfees)
 func (orderService *OrderService) OrderPay(order shop.Order, userClaims *systemReq.CustomClaims, clientIP string) (resp *response.CreateOrderResp, err error) {
 	log := fmt.Sprintf("[OrderService] OrderPay orderId:%d; \n", order.ID)
+	payWith := order.Payment
 	// 查询订单信息
-	if errors.Is(global.DB.Where("id = ?", order.ID).First(&order).Error, gorm.ErrRecordNotFound) {
+	if errors.Is(global.DB.Where("id = ? and user_id = ?", order.ID, order.UserId).First(&order).Error, gorm.ErrRecordNotFound) {
 		global.SugarLog.Errorf("log:%s,err:订单不存在 \n", log)
 		return nil, errors.New("订单不存在")
 	}
@@ -279,19 +378,92 @@ func (orderService *OrderService) OrderPay(order shop.Order, userClaims *systemR
 		global.SugarLog.Errorf("log:%s,err:订单状态不正确 \n", log)
 		return nil, errors.New("订单状态不正确")
 	}
-	// 发起 JSAIP 支付返回参数
-	err, jsApiData := wechat.JSAPIPay(userClaims.OpenId, order.OrderSn, order.ID, order.Total, clientIP)
+	if payWith != nil && *payWith != *order.Payment {
+		if !payment.IsOnline(*payWith) {
+			return nil, errors.New("不支持的支付方式")
+		}
+		if err = closePayment(order); err != nil {
+			if errors.Is(err, payment.ErrOrderPaid) {
+				return nil, errors.New("订单已支付，请稍后刷新")
+			}
+			return nil, errors.New("关闭支付订单失败")
+		}
+		if err = global.DB.Model(&order).Update("payment", *payWith).Error; err != nil {
+			return nil, err
+		}
+		order.Payment = payWith
+	}
+	// 发起支付返回参数
+	payParams, err := startPayment(order, userClaims.OpenId, clientIP)
 	if err != nil {
-		global.SugarLog.Errorf("log:%s, 微信 JsApi 发起调用异常, err: %v \n", log, err)
+		global.SugarLog.Errorf("log:%s, 发起支付异常, err: %v \n", log, err)
 		return
 	}
 	resp = &response.CreateOrderResp{
 		Order: order,
-		Pay:   *jsApiData,
+		Pay:   payParams,
 	}
 	return
 }
 
+// BalancePay 使用余额支付订单，扣减余额和订单状态流转在同一事务中完成
+// 先关闭支付渠道的待支付交易，防止同一订单余额和第三方渠道重复支付
+func (orderService *OrderService) BalancePay(req shopReq.BalancePayReq, userId uint) (order shop.Order, err error) {
+	log := fmt.Sprintf("[OrderService] BalancePay orderId:%d, userId:%d; ", req.OrderId, userId)
+	var user sysModel.SysUser
+	if err = global.DB.Where("id = ?", userId).First(&user).Error; err != nil {
+		return order, errors.New("用户查询失败")
+	}
+	if user.SafePassword == "" {
+		return order, errors.New("请先设置安全密码")
+	}
+	if err = common.CheckSafePassword(user.ID, req.SafePassword, user.SafePassword); err != nil {
+		global.SugarLog.Warnf("log:%s, 安全密码校验失败 err:%v \n", log, err)
+		return order, err
+	}
+	if errors.Is(global.DB.Where("id = ? and user_id = ?", req.OrderId, userId).First(&order).Error, gorm.ErrRecordNotFound) {
+		return order, errors.New("订单不存在")
+	}
+	if !canOrderTransit(order, OrderEventPay) {
+		return order, errors.New("订单状态不正确")
+	}
+	if err = closePayment(order); err != nil {
+		if errors.Is(err, payment.ErrOrderPaid) {
+			return order, errors.New("订单已支付，请稍后刷新")
+		}
+		return order, errors.New("关闭支付订单失败")
+	}
+	amount := payAmount(order)
+	order.Finish = amount
+	order.Payment = utils.Pointer(payment.Balance)
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		if amount > 0 {
+			f := common.NewFinance(common.OptionTypeCASH, common.FinanceTypeBalancePay, user.ID, user.Username, -amount, order.OrderSn, user.ID, user.Username, "余额支付订单")
+			if err := common.AccountUnifyDeductionTx(tx, common.CASH, f); err != nil {
+				global.SugarLog.Errorf("log:%s, 余额扣减失败 err:%v \n", log, err)
+				return err
+			}
+		}
+		return orderPaid(tx, &order, UserOperator(user), "余额支付", map[string]interface{}{
+			"finish":   amount,
+			"payment":  payment.Balance,
+			"pay_time": time.Now(),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, ErrOrderStateChanged) {
+			return order, errors.New("订单状态已变更，请刷新后重试")
+		}
+		return order, err
+	}
+	if *order.StatusRefund != 0 {
+		// 支付时拼团已结束，订单已退回余额并归还库存
+		releaseOrderStockReserve(order.ID)
+	}
+	global.SugarLog.Infof("log:%s, 余额支付成功 amount:%.2f \n", log, amount)
+	return order, nil
+}
+
 // OrderDeliver 订单发货
 func (orderService *OrderService) OrderDeliver(order shop.Order) (err error) {
 	return
@@ -319,23 +491,253 @@ func (orderService *OrderService) DeleteOrder(order shop.Order) (err error) {
 
 // CancelOrder 取消订单
 // Author [likfees](https://github.com/likfees)
-func (orderService *OrderService) CancelOrder(order shop.Order) (err error) {
-	cancelType := 1 // 默认用户取消
-	if order.StatusCancel != nil && *order.StatusCancel > 1 {
-		cancelType = *order.StatusCancel
+func (orderService *OrderService) CancelOrder(order shop.Order, op OrderOperator) (err error) {
+	db := global.DB.Where("id = ?", order.ID)
+	if op.Source == shop.OrderSourceUser {
+		// 用户只能取消自己的订单
+		db = db.Where("user_id = ?", op.Id)
 	}
-	if errors.Is(global.DB.Where("id = ?", order.ID).First(&order).Error, gorm.ErrRecordNotFound) {
+	if errors.Is(db.First(&order).Error, gorm.ErrRecordNotFound) {
 		return errors.New("订单不存在")
 	}
+	if *order.StatusCancel != 0 {
+		return errors.New("订单已取消")
+	}
+	// 已经发起过退款的订单不能重复退款
+	if *order.StatusRefund != 0 {
+		return errors.New("订单已申请退款")
+	}
 	// 发货 收货状态不允许取消
-	if *order.Status >= 2 {
+	if !canOrderTransit(order, OrderEventCancel) {
 		return errors.New("订单不允许取消")
 	}
-	// 如果订单已支付需要进行退款
-	order.StatusCancel = &cancelType
-	order.CancelTime = utils.Pointer(time.Now())
-	err = global.DB.Where("id = ?", order.ID).Updates(&order).Error
-	return err
+	paid := *order.Status == 1
+	if !paid {
+		// 未支付订单先关闭支付渠道的待支付交易，防止取消后继续支付
+		if err = closePayment(order); err != nil {
+			if errors.Is(err, payment.ErrOrderPaid) {
+				return errors.New("订单已支付，请稍后重试")
+			}
+			return errors.New("关闭支付订单失败")
+		}
+	}
+	log := fmt.Sprintf("[OrderService] CancelOrder orderSn:%s, source:%d; ", order.OrderSn, op.Source)
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		// 状态机使用当前状态进行条件更新，防止重复取消、重复退款
+		if txErr := orderTransit(tx, &order, OrderEventCancel, op, "", map[string]interface{}{"cancel_time": time.Now()}); txErr != nil {
+			global.SugarLog.Errorf("log:%s, 更新订单状态失败 err:%v \n", log, txErr)
+			return txErr
+		}
+		if txErr := restoreOrderStock(tx, order.ID); txErr != nil {
+			global.SugarLog.Errorf("log:%s, 归还库存失败 err:%v \n", log, txErr)
+			return errors.New("库存归还失败")
+		}
+		if txErr := releaseGroupSeat(tx, order); txErr != nil {
+			global.SugarLog.Errorf("log:%s, 退出拼团失败 err:%v \n", log, txErr)
+			return errors.New("退出拼团失败")
+		}
+		if txErr := restoreFlashStock(tx, order); txErr != nil {
+			global.SugarLog.Errorf("log:%s, 归还秒杀库存失败 err:%v \n", log, txErr)
+			return errors.New("秒杀库存归还失败")
+		}
+		if txErr := common.ReleaseDeliverySlot(tx, order.SlotId, order.SlotDay); txErr != nil {
+			global.SugarLog.Errorf("log:%s, 归还配送时段失败 err:%v \n", log, txErr)
+			return errors.New("配送时段归还失败")
+		}
+		if txErr := returnOrderCoupon(tx, order); txErr != nil {
+			global.SugarLog.Errorf("log:%s, 退回优惠券失败 err:%v \n", log, txErr)
+			return errors.New("优惠券退回失败")
+		}
+		if !paid {
+			if txErr := unfreezeOrderPoints(tx, order); txErr != nil {
+				global.SugarLog.Errorf("log:%s, 解冻抵扣积分失败 err:%v \n", log, txErr)
+				return errors.New("积分退回失败")
+			}
+			return nil
+		}
+		// 如果订单已支付需要按原支付方式进行退款
+		if txErr := startOrderRefund(tx, &order, op, utils.GenerateOrderNumber("RF"), order.Finish, "取消订单退款"); txErr != nil {
+			global.SugarLog.Errorf("log:%s, 订单退款失败 err:%v \n", log, txErr)
+			return txErr
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	releaseOrderStockReserve(order.ID)
+	releaseOrderFlashStock(order)
+	if paid {
+		submitRefund(order.ID, op)
+	}
+	return nil
+}
+
+// startOrderRefund 流转订单为退款中并按原支付方式发起退款，同步到账的退款直接流转为退款成功
+// 在线支付的订单需要在事务提交后调用 submitRefund 向支付渠道申请退款
+func startOrderRefund(tx *gorm.DB, order *shop.Order, op OrderOperator, refundSn string, amount float64, reason string) error {
+	firstRefund := *order.StatusRefund == 0 // 退款失败后重新发起时佣金和团队业绩已经扣回
+	if err := orderTransit(tx, order, OrderEventRefund, op, reason, nil); err != nil {
+		return err
+	}
+	if err := returnOrderCoupon(tx, *order); err != nil {
+		return err
+	}
+	if firstRefund {
+		if err := reverseOrderCommission(tx, *order, commissionBase(*order), true); err != nil {
+			return err
+		}
+		if err := returnOrderPoints(tx, *order); err != nil {
+			return err
+		}
+	}
+	refundStatus, err := refundOrder(tx, *order, refundSn, amount, reason)
+	if err != nil {
+		return err
+	}
+	if refundStatus == 2 {
+		return orderTransit(tx, order, OrderEventRefundSuccess, op, reason, nil)
+	}
+	return nil
+}
+
+// refundOrder 按订单原支付方式退款，返回退款后的订单退款状态
+// 余额、积分退款在事务中直接到账，返回已退款；微信、支付宝只在订单上记录退款单号并返回退款中，
+// 事务提交后由调用方通过 submitRefund 向支付渠道申请退款，不在事务中发起网络请求
+func refundOrder(tx *gorm.DB, order shop.Order, refundSn string, amount float64, reason string) (refundStatus int, err error) {
+	if amount <= 0 {
+		return 2, nil
+	}
+	switch *order.Payment {
+	case payment.Balance:
+		var user sysModel.SysUser
+		if err = tx.Where("id = ?", order.UserId).First(&user).Error; err != nil {
+			return 0, errors.New("用户查询失败")
+		}
+		f := common.NewFinance(common.OptionTypeCASH, common.FinanceTypeRefund, user.ID, user.Username, amount, order.OrderSn, user.ID, user.Username, reason)
+		if err = common.AccountUnifyDeductionTx(tx, common.CASH, f); err != nil {
+			return 0, err
+		}
+		return 2, nil
+	case payment.Wechat, payment.Alipay:
+		if err = tx.Model(&shop.Order{}).Where("id = ?", order.ID).Updates(map[string]interface{}{"refund_sn": refundSn, "refund_applied": 0}).Error; err != nil {
+			return 0, err
+		}
+		return 1, nil
+	case payment.Point:
+		var user sysModel.SysUser
+		if err = tx.Where("id = ?", order.UserId).First(&user).Error; err != nil {
+			return 0, errors.New("用户查询失败")
+		}
+		f := common.NewFinance(common.OptionTypeCASH, common.FinanceTypeRefund, user.ID, user.Username, amount, order.OrderSn, user.ID, user.Username, reason)
+		if err = common.AccountUnifyDeductionTx(tx, common.POINT, f); err != nil {
+			return 0, err
+		}
+		return 2, nil
+	default:
+		return 0, errors.New("不支持的退款方式")
+	}
+}
+
+// CancelTimeoutOrder 取消超时未支付的订单并归还库存，由定时任务调用
+// Author [likfees](https://github.com/likfees)
+func (orderService *OrderService) CancelTimeoutOrder() {
+	deadline := time.Now().Add(-global.Config.Order.GetPayTimeout())
+	var orders []shop.Order
+	err := global.DB.Where("status = 0 and status_cancel = 0 and created_at < ?", deadline).
+		Order("id asc").Limit(100).Find(&orders).Error
+	if err != nil {
+		global.SugarLog.Errorf("查询超时未支付订单失败, err:%v \n", err)
+		return
+	}
+	for _, o := range orders {
+		if err = orderService.cancelTimeoutOrder(o); err != nil {
+			global.SugarLog.Errorf("超时订单取消失败 orderSn:%s, err:%v \n", o.OrderSn, err)
+		}
+	}
+}
+
+// cancelTimeoutOrder 关闭支付渠道的待支付交易后取消单个超时订单
+func (orderService *OrderService) cancelTimeoutOrder(order shop.Order) error {
+	// 先关闭支付渠道的待支付交易，避免订单取消后用户仍然可以完成支付
+	if err := closePayment(order); err != nil {
+		if errors.Is(err, payment.ErrOrderPaid) {
+			// 用户已经支付，等待支付回调更新订单状态
+			global.SugarLog.Infof("超时订单已在支付渠道侧支付, 跳过取消 orderSn:%s \n", order.OrderSn)
+			return nil
+		}
+		return err
+	}
+	cancelled := false
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		// 状态机带上状态条件更新，防止与支付回调并发时覆盖已支付的订单
+		if err := orderTransit(tx, &order, OrderEventCancel, TimerOperator, "超时未支付", map[string]interface{}{"cancel_time": time.Now()}); err != nil {
+			if errors.Is(err, ErrOrderStateChanged) {
+				return nil
+			}
+			return err
+		}
+		if err := restoreOrderStock(tx, order.ID); err != nil {
+			return err
+		}
+		if err := releaseGroupSeat(tx, order); err != nil {
+			return err
+		}
+		if err := restoreFlashStock(tx, order); err != nil {
+			return err
+		}
+		if err := common.ReleaseDeliverySlot(tx, order.SlotId, order.SlotDay); err != nil {
+			return err
+		}
+		if err := returnOrderCoupon(tx, order); err != nil {
+			return err
+		}
+		if err := unfreezeOrderPoints(tx, order); err != nil {
+			return err
+		}
+		cancelled = true
+		return nil
+	})
+	if err != nil || !cancelled {
+		return err
+	}
+	releaseOrderStockReserve(order.ID)
+	releaseOrderFlashStock(order)
+	global.SugarLog.Infof("超时订单已取消 orderSn:%s \n", order.OrderSn)
+	return nil
+}
+
+// restoreOrderStock 将订单商品数量加回商品库存并扣回销量
+func restoreOrderStock(tx *gorm.DB, orderId uint) error {
+	var details []shop.OrderDetails
+	if err := tx.Where("order_id = ?", orderId).Find(&details).Error; err != nil {
+		return err
+	}
+	for _, d := range details {
+		if err := restoreGoodsStock(tx, d, d.Num); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreGoodsStock 将订单商品的 num 件加回商品库存并扣回销量
+// 事务可能回滚，Redis 预占库存由调用方在事务提交后归还
+func restoreGoodsStock(tx *gorm.DB, d shop.OrderDetails, num int) error {
+	restore := map[string]interface{}{
+		"store": gorm.Expr("store + ?", num),
+		"sale":  gorm.Expr("GREATEST(sale - ?, 0)", num),
+	}
+	if err := tx.Model(&shop.Goods{}).Where("id = ?", d.GoodsId).Updates(restore).Error; err != nil {
+		return err
+	}
+	// 多规格商品同时归还规格库存
+	if d.SpecId > 0 {
+		if err := tx.Model(&shop.GoodsSpecValue{}).Where("id = ?", d.SpecId).Updates(restore).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // DeleteOrderByIds 批量删除Order记录
@@ -348,7 +750,8 @@ func (orderService *OrderService) DeleteOrderByIds(ids request.IdsReq) (err erro
 // UpdateOrder 更新Order记录
 // Author [likfees](https://github.com/likfees)
 func (orderService *OrderService) UpdateOrder(order shop.Order) (err error) {
-	err = global.DB.Save(&order).Error
+	// 订单状态只能通过状态机流转，这里不允许直接修改
+	err = global.DB.Omit("status", "status_cancel", "status_refund").Save(&order).Error
 	return err
 }
 
@@ -358,6 +761,7 @@ func (orderService *OrderService) GetOrder(id uint) (order shop.Order, err error
 	err = global.DB.Where("id = ?", id).
 		Preload("OrderDetails.Goods").
 		Preload("OrderReturn.Details").
+		Preload("OrderDelivery", "type = ?", shop.DeliveryTypeShip).
 		Preload("OrderDelivery.UserDelivery").
 		First(&order).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -396,12 +800,12 @@ func (orderService *OrderService) GetOrderInfoList(info shopReq.OrderSearch) (li
 	limit := info.PageSize
 	offset := info.PageSize * (info.Page - 1)
 	// 创建db
-	db := global.DB.Debug().Model(&shop.Order{}).Preload("OrderDetails").Preload("OrderDelivery").Joins("OrderReturn")
+	db := global.DB.Debug().Model(&shop.Order{}).Preload("OrderDetails").Preload("OrderDelivery", "type = ?", shop.DeliveryTypeShip).Joins("OrderReturn")
 	var orders []shop.Order
 	// 如果有条件搜索 下方会自动创建搜索语句
 
 	if info.Status != nil {
-		if *info.Status == 0 || *info.Status == 1 || *info.Status == 2 || *info.Status == 3 { // 未付款
+		if *info.Status == 0 || *info.Status == 1 || *info.Status == 2 || *info.Status == 3 || *info.Status == orderStatusGrouping { // 未付款
 			db = db.Where("shop_order.status = ? and shop_order.status_cancel = 0 and shop_order.status_refund = 0", info.Status)
 		} else if *info.Status == 10 { // 售后订单
 			db = db.Where("OrderReturn.order_id = shop_order.id and shop_order.status_cancel = 0")
@@ -451,3 +855,165 @@ func (orderService *OrderService) GetOrderInfoList(info shopReq.OrderSearch) (li
 	err = db.Limit(limit).Offset(offset).Find(&orders).Error
 	return orders, total, err
 }
+
+// checkOrderShipment 配送订单校验收货地址是否在配送范围内(按当前的配送区域重新判断)以及预约的配送时段
+// 自提订单清空预约的配送时段
+func checkOrderShipment(order *shop.Order, address shop.UserAddress) (slot business.DeliverySlot, err error) {
+	if *order.ShipmentType != 0 {
+		order.SlotId, order.SlotDay = 0, ""
+		return
+	}
+	var lng, lat float64
+	if address.Longitude != nil && address.Latitude != nil {
+		lng, lat = *address.Longitude, *address.Latitude
+	}
+	zone, deliverable, err := common.MatchDeliveryZone(lng, lat)
+	if err != nil {
+		global.SugarLog.Errorf("创建订单时匹配配送区域异常, err:%v \n", err)
+		return slot, errors.New("配送区域查询失败")
+	}
+	if !deliverable {
+		return slot, errors.New("收货地址超出配送范围")
+	}
+	if order.SlotId > 0 {
+		return checkOrderSlot(order, zone)
+	}
+	return
+}
+
+// newGoodsOrder 组织单个商品的活动订单(秒杀、拼团)，按活动价格计算金额，校验收货地址、配送时段并计算运费
+// goods 需要预加载商品图片，spec 为多规格商品参与活动的规格；活动订单不使用优惠券，也不赠送积分
+func newGoodsOrder(userId uint, req shopReq.GoodsOrderReq, goods shop.Goods, spec shop.GoodsSpecValue, price float64) (order shop.Order, detail shop.OrderDetails, slot business.DeliverySlot, err error) {
+	if req.Num <= 0 {
+		return order, detail, slot, errors.New("购买数量错误")
+	}
+	address := shop.UserAddress{}
+	addressName := ""
+	if req.AddressId > 0 {
+		if errors.Is(global.DB.Where("id = ? and user_id = ?", req.AddressId, userId).First(&address).Error, gorm.ErrRecordNotFound) {
+			return order, detail, slot, errors.New("收货地址不存在")
+		}
+		if *address.Sex == 1 {
+			addressName = address.Name + "先生"
+		} else {
+			addressName = address.Name + "女士"
+		}
+	} else if req.ShipmentType == 0 {
+		return order, detail, slot, errors.New("请选择收货地址")
+	}
+	order = shop.Order{
+		UserId:          utils.Pointer(int(userId)),
+		OrderSn:         utils.GenerateOrderNumber("SN"),
+		GoodsArea:       utils.Pointer(0),
+		ShipmentName:    addressName,
+		ShipmentMobile:  address.Mobile,
+		ShipmentAddress: address.Address + address.Title + address.Detail,
+		ShipmentLng:     address.Longitude,
+		ShipmentLat:     address.Latitude,
+		ShipmentType:    utils.Pointer(req.ShipmentType),
+		Num:             req.Num,
+		Total:           math.Round(price*float64(req.Num)*100) / 100,
+		Payment:         orderPayment(req.Payment),
+		Status:          utils.Pointer(0),
+		StatusCancel:    utils.Pointer(0),
+		StatusRefund:    utils.Pointer(0),
+		SlotId:          req.SlotId,
+		SlotDay:         req.SlotDay,
+		Remarks:         req.Remarks,
+	}
+	if slot, err = checkOrderShipment(&order, address); err != nil {
+		return
+	}
+	weight := 0
+	if goods.Weight != nil {
+		weight = req.Num * *goods.Weight
+	}
+	if order.Postage, _, err = calcOrderPostage(req.ShipmentType, address, order.Total, weight); err != nil {
+		return
+	}
+	detail = shop.OrderDetails{
+		GoodsId:     goods.ID,
+		GoodsName:   goods.Name,
+		Unit:        goods.Unit,
+		Num:         req.Num,
+		Price:       price,
+		Total:       order.Total,
+		SpecId:      int(spec.ID),
+		SpecKeyName: spec.KeyName,
+	}
+	if len(goods.Images) > 0 {
+		detail.GoodsImage = goods.Images[0].Url
+	}
+	if spec.ID == 0 {
+		detail.SpecKeyName = goods.Unit
+		if goods.Weight != nil && *goods.Weight > 0 {
+			detail.SpecKeyName = fmt.Sprintf("%dg/%s", *goods.Weight, goods.Unit)
+		}
+	}
+	return
+}
+
+// saveGoodsOrder 在事务中扣减商品库存、生成取餐号码、占用配送时段并保存活动订单，库存不足时返回 ErrStockNotEnough
+func saveGoodsOrder(tx *gorm.DB, order *shop.Order, detail *shop.OrderDetails, slot business.DeliverySlot, user sysModel.SysUser, title string, log string) error {
+	if err := deductStock(tx, detail.GoodsId, detail.SpecId, detail.Num); err != nil {
+		global.SugarLog.Errorf("log:%s, 库存扣减失败 err:%v \n", log, err)
+		if errors.Is(err, ErrStockNotEnough) {
+			return err
+		}
+		return errors.New("库存扣减失败")
+	}
+	if *order.ShipmentType == 1 {
+		var err error
+		if order.PickUpNumber, order.PickUpCode, err = nextPickUpNumber(tx); err != nil {
+			global.SugarLog.Errorf("log:%s, 生成取餐号码失败 err:%v \n", log, err)
+			return errors.New("取餐号码生成失败")
+		}
+	}
+	if order.SlotId > 0 {
+		if err := common.ReserveDeliverySlot(tx, slot, order.SlotDay); err != nil {
+			global.SugarLog.Errorf("log:%s, 占用配送时段失败 slotId:%d, day:%s, err:%v \n", log, order.SlotId, order.SlotDay, err)
+			if errors.Is(err, common.ErrDeliverySlotFull) {
+				return err
+			}
+			return errors.New("配送时段预约失败")
+		}
+	}
+	if err := tx.Create(order).Error; err != nil {
+		global.SugarLog.Errorf("log:%s, err:%v \n", log, err)
+		return errors.New("订单创建失败")
+	}
+	if err := writeOrderLog(tx, order.ID, OrderEventCreate, title, currentOrderState(*order), UserOperator(user), ""); err != nil {
+		global.SugarLog.Errorf("log:%s, 写入订单日志失败 err:%v \n", log, err)
+		return errors.New("订单创建失败")
+	}
+	detail.OrderId = order.ID
+	if err := tx.Create(detail).Error; err != nil {
+		global.SugarLog.Errorf("log:%s, err:%v \n", log, err)
+		return errors.New("订单详情创建失败")
+	}
+	return nil
+}
+
+// checkOrderSlot 校验订单预约的配送时段，并写入时段的开始、结束时间
+// zone 为收货地址所在的配送区域，未配置配送区域时为 nil
+func checkOrderSlot(order *shop.Order, zone *business.DeliveryZone) (slot business.DeliverySlot, err error) {
+	if errors.Is(global.DB.Where("id = ? and status = 1", order.SlotId).First(&slot).Error, gorm.ErrRecordNotFound) {
+		return slot, errors.New("配送时段不存在或已停用")
+	}
+	if slot.ZoneId != 0 && (zone == nil || zone.ID != slot.ZoneId) {
+		return slot, errors.New("收货地址不在该配送时段的配送区域内")
+	}
+	start, end, err := common.DeliverySlotWindow(slot, order.SlotDay)
+	if err != nil {
+		return slot, errors.New("配送日期格式错误")
+	}
+	now := time.Now()
+	if !start.After(now) {
+		return slot, errors.New("该配送时段已开始，请选择其他时段")
+	}
+	if start.After(now.AddDate(0, 0, global.Config.Order.GetSlotDays())) {
+		return slot, errors.New("配送日期超出可预约范围")
+	}
+	order.SlotStart, order.SlotEnd = &start, &end
+	return slot, nil
+}
@@ -11,6 +11,7 @@ import (
 	"fresh-shop/server/service/common"
 	"fresh-shop/server/utils"
 	"gorm.io/gorm"
+	"strconv"
 	"time"
 )
 
@@ -19,17 +20,17 @@ type OrderDeliveryService struct {
 
 // CreateOrderDelivery 创建OrderDelivery记录
 // Author [likfees](https://github.com/likfees)
-func (orderDeliveryService *OrderDeliveryService) CreateOrderDelivery(orderDelivery shop.OrderDelivery) (err error) {
+func (orderDeliveryService *OrderDeliveryService) CreateOrderDelivery(orderDelivery shop.OrderDelivery, op OrderOperator) (err error) {
 	var order shop.Order
-	err = global.DB.Where("id = ? and status = 1 and status_cancel = 0", orderDelivery.OrderId).First(&order).Error
+	err = global.DB.Where("id = ?", orderDelivery.OrderId).First(&order).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		global.SugarLog.Errorf("获取订单信息失败 orderId:%d, error: %v", order.ID, err)
 		return err
 	}
-	order.Status = utils.Pointer(2)
-	order.ShipmentTime = utils.Pointer(time.Now()) //发货时间
 	err = global.DB.Transaction(func(tx *gorm.DB) error {
-		if txErr := tx.Save(&order).Error; txErr != nil {
+		if txErr := orderTransit(tx, &order, OrderEventShip, op, orderDelivery.DeliverName, map[string]interface{}{
+			"shipment_time": time.Now(), //发货时间
+		}); txErr != nil {
 			return txErr
 		}
 		err = tx.Create(&orderDelivery).Error
@@ -57,13 +58,16 @@ func (orderDeliveryService *OrderDeliveryService) DeleteOrderDeliveryByIds(ids r
 
 // UpdateOrderDelivery 订单收货
 // Author [likfees](https://github.com/likfees)
-func (orderDeliveryService *OrderDeliveryService) UpdateOrderDelivery(orderDelivery shop.OrderDelivery) (err error) {
+func (orderDeliveryService *OrderDeliveryService) UpdateOrderDelivery(orderDelivery shop.OrderDelivery, op OrderOperator) (err error) {
 	var order shop.Order
-	err = global.DB.Where("id = ? and status = 2 and status_cancel = 0", orderDelivery.OrderId).First(&order).Error
+	err = global.DB.Where("id = ?", orderDelivery.OrderId).First(&order).Error
 	if err != nil {
 		global.SugarLog.Errorf("获取订单信息失败 orderId:%d, error: %v", orderDelivery.OrderId, err)
 		return err
 	}
+	if !canOrderTransit(order, OrderEventReceive) {
+		return ErrOrderTransition
+	}
 	var user sysModel.SysUser
 	if err = global.DB.Where("id = ?", order.UserId).First(&user).Error; err != nil {
 		global.SugarLog.Errorf("获取用户信息失败 userId:%d, error: %v", order.UserId, err)
@@ -78,13 +82,12 @@ func (orderDeliveryService *OrderDeliveryService) UpdateOrderDelivery(orderDeliv
 		}
 		deliver.DeliverCount = utils.Pointer(*deliver.DeliverCount + 1)
 	}
-	if orderDelivery.ReceiptTime != nil {
-		order.Status = utils.Pointer(3)
-		order.ReceiveTime = orderDelivery.ReceiptTime
-	}
+	received := orderDelivery.ReceiptTime != nil
 	err = global.DB.Transaction(func(tx *gorm.DB) error {
-		if txErr := tx.Save(&order).Error; txErr != nil {
-			return txErr
+		if received {
+			if txErr := receiveOrder(tx, &order, user, op, *orderDelivery.ReceiptTime); txErr != nil {
+				return txErr
+			}
 		}
 		// 保存收货人信息
 		if orderDelivery.DeliveryId != nil && *orderDelivery.DeliveryId > 0 {
@@ -96,20 +99,79 @@ func (orderDeliveryService *OrderDeliveryService) UpdateOrderDelivery(orderDeliv
 		if err != nil {
 			return err
 		}
-		if *order.GoodsArea == 0 { // 普通商品才能发放积分
-			// 发放积分
-			f := common.NewFinance(0, 6, user.ID, user.Username, order.GiftPoints, order.OrderSn, user.ID, user.Username, "确认收货发放积分")
-			err = common.AccountUnifyDeduction(common.POINT, f)
-			if err != nil {
-				global.SugarLog.Errorf("发放积分失败 UserFinance:%v, error: %v", f, err)
-				return err
-			}
-		}
 		return nil
 	})
 	return
 }
 
+// AutoReceiveOrder 发货超过 sys_config autoReceiveDays 天仍未确认收货的订单自动确认收货，由定时任务调用
+// 有未处理售后的订单不会自动收货
+func (orderDeliveryService *OrderDeliveryService) AutoReceiveOrder() {
+	daysCfg, err := common.GetSysConfig("autoReceiveDays")
+	if err != nil {
+		if !errors.Is(err, common.ErrConfigDisabled) && !errors.Is(err, gorm.ErrRecordNotFound) {
+			global.SugarLog.Errorf("自动收货查询配置参数异常, err:%v \n", err)
+		}
+		return
+	}
+	days, err := strconv.Atoi(daysCfg)
+	if err != nil || days <= 0 {
+		global.SugarLog.Errorf("自动收货配置参数错误 autoReceiveDays:%s \n", daysCfg)
+		return
+	}
+	deadline := time.Now().AddDate(0, 0, -days)
+	var orders []shop.Order
+	err = global.DB.Where("status = 2 and status_cancel = 0 and status_refund = 0 and shipment_time < ?", deadline).
+		Where("NOT EXISTS (SELECT 1 FROM shop_order_return r WHERE r.order_id = shop_order.id AND r.status = 0 AND r.deleted_at IS NULL)").
+		Order("id asc").Limit(100).Find(&orders).Error
+	if err != nil {
+		global.SugarLog.Errorf("查询待自动收货订单失败, err:%v \n", err)
+		return
+	}
+	for _, o := range orders {
+		var user sysModel.SysUser
+		if err = global.DB.Where("id = ?", o.UserId).First(&user).Error; err != nil {
+			global.SugarLog.Errorf("自动收货获取用户信息失败 orderSn:%s, userId:%d, err:%v \n", o.OrderSn, *o.UserId, err)
+			continue
+		}
+		now := time.Now()
+		err = global.DB.Transaction(func(tx *gorm.DB) error {
+			if txErr := receiveOrder(tx, &o, user, TimerOperator, now); txErr != nil {
+				return txErr
+			}
+			return tx.Model(&shop.OrderDelivery{}).Where("order_id = ? and type = ?", o.ID, shop.DeliveryTypeShip).Update("receipt_time", now).Error
+		})
+		if err != nil {
+			global.SugarLog.Errorf("订单自动收货失败 orderSn:%s, err:%v \n", o.OrderSn, err)
+			continue
+		}
+		global.SugarLog.Infof("订单已自动收货 orderSn:%s \n", o.OrderSn)
+	}
+}
+
+// receiveOrder 订单确认收货并发放赠送积分
+// 状态机保证只有已发货的订单才能流转为已收货，因此每个订单的积分只会发放一次
+func receiveOrder(tx *gorm.DB, order *shop.Order, user sysModel.SysUser, op OrderOperator, receiveTime time.Time) error {
+	if err := orderTransit(tx, order, OrderEventReceive, op, "", map[string]interface{}{
+		"receive_time": receiveTime,
+	}); err != nil {
+		return err
+	}
+	if err := settleOrderCommission(tx, *order); err != nil {
+		return err
+	}
+	if *order.GoodsArea != 0 || order.GiftPoints <= 0 { // 普通商品才能发放积分
+		return nil
+	}
+	// 发放积分
+	f := common.NewFinance(common.OptionTypeCASH, common.FinanceTypeGiftPoint, user.ID, user.Username, order.GiftPoints, order.OrderSn, user.ID, user.Username, "确认收货发放积分")
+	if err := common.AccountUnifyDeductionTx(tx, common.POINT, f); err != nil {
+		global.SugarLog.Errorf("发放积分失败 UserFinance:%v, error: %v", f, err)
+		return err
+	}
+	return nil
+}
+
 // GetOrderDelivery 根据id获取OrderDelivery记录
 // Author [likfees](https://github.com/likfees)
 func (orderDeliveryService *OrderDeliveryService) GetOrderDelivery(id uint, orderId int) (orderDelivery shop.OrderDelivery, err error) {
@@ -117,7 +179,7 @@ func (orderDeliveryService *OrderDeliveryService) GetOrderDelivery(id uint, orde
 	if id != 0 {
 		db = db.Where("id = ?", id)
 	} else if orderId != 0 {
-		db = db.Where("order_id = ?", orderId)
+		db = db.Where("order_id = ? and type = ?", orderId, shop.DeliveryTypeShip)
 	} else {
 		return orderDelivery, errors.New("参数异常")
 	}
@@ -140,6 +202,9 @@ func (orderDeliveryService *OrderDeliveryService) GetOrderDeliveryInfoList(info
 	if info.StartScheduledTime != nil && info.EndScheduledTime != nil {
 		db = db.Where("scheduled_time BETWEEN ? AND ? ", info.StartScheduledTime, info.EndScheduledTime)
 	}
+	if info.Type != nil {
+		db = db.Where("type = ?", info.Type)
+	}
 	if info.DeliverName != "" {
 		db = db.Where("deliver_name LIKE ?", "%"+info.DeliverName+"%")
 	}
@@ -0,0 +1,169 @@
+package shop
+
+import (
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/shop"
+	"fresh-shop/server/service/payment"
+	"fresh-shop/server/utils"
+	"gorm.io/gorm"
+	"math"
+)
+
+var (
+	// ErrPayDuplicate 重复的支付结果，同一笔交易已经处理过
+	ErrPayDuplicate = errors.New("重复的支付结果")
+	// ErrPayAnomaly 支付结果异常，需要人工审核，订单不会被标记为已支付
+	ErrPayAnomaly = errors.New("支付结果异常")
+)
+
+// PayNotifyLogic 支付结果处理逻辑，支付回调和主动查询共用，签名校验在调用前由支付渠道完成
+// 同一笔交易重复处理返回 ErrPayDuplicate，金额不符、订单已取消或已通过其他交易支付返回 ErrPayAnomaly
+func (orderService *OrderService) PayNotifyLogic(result payment.TradeResult) error {
+	orderSn := result.OrderSn
+	log := fmt.Sprintf("订单支付回调逻辑: 订单号：%s, 支付方式：%d, 交易号：%s, ", orderSn, result.Payment, result.TransactionId)
+	if !result.Paid {
+		global.SugarLog.Infof(log+"交易未支付，忽略, state:%s \n", result.State)
+		return nil
+	}
+	var order shop.Order
+	if errors.Is(global.DB.Where("order_sn = ?", orderSn).First(&order).Error, gorm.ErrRecordNotFound) {
+		global.SugarLog.Errorf(log + "订单不存在 \n")
+		return fmt.Errorf("%w: 订单不存在", ErrPayAnomaly)
+	}
+	if err := checkPayResult(order, result); err != nil {
+		global.SugarLog.Errorf(log+"%v \n", err)
+		return err
+	}
+	updates := map[string]interface{}{
+		"finish":         result.Amount,
+		"pay_time":       result.PayTime,
+		"payment_openid": result.Buyer,
+		"payment_info":   result.Attach,
+		"transation_id":  result.TransactionId,
+		"payment":        result.Payment,
+	}
+	order.Finish = result.Amount
+	order.Payment = utils.Pointer(result.Payment)
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		return orderPaid(tx, &order, CallbackOperator, paymentName(result.Payment), updates)
+	})
+	if errors.Is(err, ErrOrderStateChanged) {
+		// 订单状态在读取后被并发修改，通常是同一笔交易的重复通知已经处理完成，按最新状态重新判断
+		if e := global.DB.Where("id = ?", order.ID).First(&order).Error; e == nil {
+			if e = checkPayResult(order, result); e != nil {
+				global.SugarLog.Errorf(log+"%v \n", e)
+				return e
+			}
+		}
+	}
+	if err != nil {
+		global.SugarLog.Errorf(log+"保存订单信息失败, err:%s \n", err.Error())
+		return err
+	}
+	if order.GroupTeamId > 0 && *order.StatusRefund != 0 {
+		// 支付时拼团已结束的订单在事务中归还库存并转为退款中，提交后归还预占库存并向支付渠道申请退款
+		releaseOrderStockReserve(order.ID)
+		submitRefund(order.ID, CallbackOperator)
+	}
+
+	global.SugarLog.Infof(log + "支付成功")
+	return nil
+}
+
+// checkPayResult 校验支付结果能否将订单标记为已支付
+func checkPayResult(order shop.Order, result payment.TradeResult) error {
+	if *order.Status != 0 {
+		if order.TransationId == result.TransactionId {
+			return ErrPayDuplicate
+		}
+		return fmt.Errorf("%w: 订单已通过其他交易支付 transactionId:%s", ErrPayAnomaly, order.TransationId)
+	}
+	if !canOrderTransit(order, OrderEventPay) {
+		return fmt.Errorf("%w: 订单状态不允许支付 Status：%d, StatusCancel：%d", ErrPayAnomaly, *order.Status, *order.StatusCancel)
+	}
+	if expect := payAmount(order); math.Abs(result.Amount-expect) > 0.001 {
+		return fmt.Errorf("%w: 支付金额 %.2f 与订单应付金额 %.2f 不一致", ErrPayAnomaly, result.Amount, expect)
+	}
+	return nil
+}
+
+// orderPaid 在事务中处理订单支付完成，扣除冻结的抵扣积分、发放分销佣金后流转订单状态，拼团订单支付后等待成团
+// 调用前需要设置 order.Finish 为实付金额
+func orderPaid(tx *gorm.DB, order *shop.Order, op OrderOperator, remark string, updates map[string]interface{}) error {
+	if err := settleOrderPoints(tx, *order); err != nil {
+		return err
+	}
+	if err := createOrderCommission(tx, *order); err != nil {
+		return err
+	}
+	if order.GroupTeamId > 0 {
+		return groupOrderPaid(tx, order, op, remark, updates)
+	}
+	return orderTransit(tx, order, OrderEventPay, op, remark, updates)
+}
+
+// RefundNotifyLogic 退款结果通知逻辑处理，重复通知不会重复修改数据
+func (orderService *OrderService) RefundNotifyLogic(result payment.RefundResult) error {
+	return handleRefundResult(result, CallbackOperator)
+}
+
+// handleRefundResult 处理退款结果，退款通知和同步返回结果的退款申请共用
+func handleRefundResult(result payment.RefundResult, op OrderOperator) error {
+	orderSn := result.OrderSn
+	refundSn := result.RefundSn
+	log := fmt.Sprintf("订单退款回调逻辑: 订单号：%s, 退款单号：%s, 退款状态：%s, ", orderSn, refundSn, result.State)
+	var order shop.Order
+	if errors.Is(global.DB.Where("order_sn = ?", orderSn).First(&order).Error, gorm.ErrRecordNotFound) {
+		global.SugarLog.Errorf(log + "订单不存在 \n")
+		return errors.New("订单不存在")
+	}
+	// 只处理退款中的订单，已处理过的通知直接忽略
+	if *order.StatusRefund != 1 {
+		global.SugarLog.Infof(log + "订单不是退款中状态，忽略 \n")
+		return nil
+	}
+	// 只处理当前退款单号或退款中的售后记录的结果，重新发起退款后旧退款单号的迟到通知直接忽略
+	var orderReturn shop.OrderReturn
+	err := global.DB.Where("order_id = ? and refund_sn = ? and refund_status = 1", order.ID, refundSn).First(&orderReturn).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		global.SugarLog.Errorf(log+"查询售后记录失败, err:%s \n", err.Error())
+		return err
+	}
+	isReturn := err == nil
+	// 未记录退款单号的订单为历史数据，按订单号处理
+	if !isReturn && order.RefundSn != "" && order.RefundSn != refundSn {
+		global.SugarLog.Infof(log+"不是当前退款单号 %s，忽略 \n", order.RefundSn)
+		return nil
+	}
+	success := result.Status == payment.RefundSuccess
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		// 售后退款同步售后记录
+		if isReturn {
+			if success {
+				if err := orderReturnTransit(tx, &orderReturn, OrderEventReturnFinish, op, "", map[string]interface{}{"refund_status": 2}); err != nil {
+					return err
+				}
+				event, err := returnRefundEvent(tx, order.ID)
+				if err != nil {
+					return err
+				}
+				return orderTransit(tx, &order, event, op, "", nil)
+			}
+			if err := tx.Model(&shop.OrderReturn{}).Where("id = ? and refund_status = 1", orderReturn.ID).Update("refund_status", 3).Error; err != nil {
+				return err
+			}
+		}
+		if success {
+			return orderTransit(tx, &order, OrderEventRefundSuccess, op, "", nil)
+		}
+		return orderTransit(tx, &order, OrderEventRefundFail, op, result.State, nil)
+	})
+	if err != nil {
+		global.SugarLog.Errorf(log+"更新退款状态失败, err:%s \n", err.Error())
+		return err
+	}
+	global.SugarLog.Infof(log + "处理完成")
+	return nil
+}
@@ -0,0 +1,204 @@
+package shop
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/shop"
+	"fresh-shop/server/service/payment"
+	"gorm.io/gorm"
+	"time"
+)
+
+// startPayment 按订单支付方式向支付渠道发起支付，返回客户端调起支付所需的参数
+func startPayment(order shop.Order, openId, clientIP string) (interface{}, error) {
+	provider, err := payment.GetProvider(*order.Payment)
+	if err != nil {
+		return nil, err
+	}
+	return provider.Create(payment.CreateReq{
+		OrderId:  order.ID,
+		OrderSn:  order.OrderSn,
+		Amount:   payAmount(order),
+		Subject:  fmt.Sprintf("用户下单 金额:%.2f", payAmount(order)),
+		OpenId:   openId,
+		ClientIP: clientIP,
+		ExpireAt: order.CreatedAt.Add(global.Config.Order.GetPayTimeout()),
+	})
+}
+
+// closePayment 关闭订单在支付渠道的待支付交易，防止订单取消或改用其他方式支付后继续付款
+// 余额等不经过第三方渠道的支付方式不需要关闭；交易已支付时返回 payment.ErrOrderPaid
+func closePayment(order shop.Order) error {
+	if order.Payment == nil || !payment.IsOnline(*order.Payment) {
+		return nil
+	}
+	provider, err := payment.GetProvider(*order.Payment)
+	if err != nil {
+		return err
+	}
+	return provider.Close(order.OrderSn)
+}
+
+// refundPayment 向订单支付渠道申请退款，返回退款后的订单退款状态
+func refundPayment(order shop.Order, refundSn string, amount float64, reason string) (int, error) {
+	provider, err := payment.GetProvider(*order.Payment)
+	if err != nil {
+		return 0, err
+	}
+	result, err := provider.Refund(payment.RefundReq{
+		OrderSn:  order.OrderSn,
+		RefundSn: refundSn,
+		Total:    order.Finish,
+		Amount:   amount,
+		Reason:   reason,
+	})
+	if err != nil {
+		return 0, errors.New(paymentName(*order.Payment) + "退款申请失败")
+	}
+	return result.Status, nil
+}
+
+// submitRefund 向支付渠道申请订单待处理的退款，在发起退款的事务提交后调用
+// 渠道受理后标记订单退款已受理，同步返回结果的按退款通知逻辑处理；申请失败时保持未受理，由定时任务按原单号重试，
+// 支付渠道按退款单号幂等，重复申请不会重复退款
+func submitRefund(orderId uint, op OrderOperator) {
+	var order shop.Order
+	if err := global.DB.Where("id = ?", orderId).First(&order).Error; err != nil {
+		global.SugarLog.Errorf("申请退款查询订单失败 orderId:%d, err:%v \n", orderId, err)
+		return
+	}
+	if *order.StatusRefund != payment.RefundProcessing || order.RefundSn == "" || (order.RefundApplied != nil && *order.RefundApplied == 1) ||
+		order.Payment == nil || !payment.IsOnline(*order.Payment) {
+		return
+	}
+	log := fmt.Sprintf("申请退款 orderSn:%s, refundSn:%s; ", order.OrderSn, order.RefundSn)
+	// 售后退款按售后金额退款，其他为整单退款
+	amount, reason := order.Finish, "订单退款"
+	var orderReturn shop.OrderReturn
+	err := global.DB.Where("refund_sn = ?", order.RefundSn).First(&orderReturn).Error
+	if err == nil {
+		amount, reason = *orderReturn.Amount, "售后退款"
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		global.SugarLog.Errorf("log:%s, 查询售后记录失败 err:%v \n", log, err)
+		return
+	}
+	status, err := refundPayment(order, order.RefundSn, amount, reason)
+	if err != nil {
+		global.SugarLog.Errorf("log:%s, 退款申请失败，等待重试 err:%v \n", log, err)
+		return
+	}
+	if err = global.DB.Model(&shop.Order{}).Where("id = ? and refund_sn = ?", order.ID, order.RefundSn).Update("refund_applied", 1).Error; err != nil {
+		global.SugarLog.Errorf("log:%s, 标记退款已受理失败 err:%v \n", log, err)
+	}
+	if status == payment.RefundProcessing {
+		// 异步到账的退款在退款通知中完成
+		return
+	}
+	result := payment.RefundResult{OrderSn: order.OrderSn, RefundSn: order.RefundSn, Status: status}
+	if err = handleRefundResult(result, op); err != nil {
+		global.SugarLog.Errorf("log:%s, 处理退款结果失败 err:%v \n", log, err)
+	}
+}
+
+// refundRetryDelay 退款申请失败后等待定时任务重试的最短时间，避免与事务提交后的申请同时进行
+const refundRetryDelay = time.Minute
+
+// SyncPendingRefunds 重新申请事务提交后未被支付渠道受理的退款，补偿申请失败或服务中断的情况
+func (orderService *OrderService) SyncPendingRefunds() {
+	var ids []uint
+	err := global.DB.Model(&shop.Order{}).Where("status_refund = ? and refund_sn <> '' and refund_applied = 0 and payment in ? and updated_at < ?",
+		payment.RefundProcessing, []int{payment.Wechat, payment.Alipay}, time.Now().Add(-refundRetryDelay)).
+		Order("id asc").Limit(100).Pluck("id", &ids).Error
+	if err != nil {
+		global.SugarLog.Errorf("查询待申请退款订单失败, err:%v \n", err)
+		return
+	}
+	for _, id := range ids {
+		submitRefund(id, TimerOperator)
+	}
+}
+
+// paySyncGrace 支付超时之后继续主动查询的时长，超时取消时发现已在渠道侧支付的订单会保持待支付状态等待回调
+const paySyncGrace = time.Hour
+
+// SyncUnpaidOrders 主动查询近期待支付订单在支付渠道的交易状态，已支付的按支付回调逻辑处理，补偿丢失或处理失败的支付回调
+func (orderService *OrderService) SyncUnpaidOrders() {
+	now := time.Now()
+	var orders []shop.Order
+	err := global.DB.Where("status = 0 and status_cancel = 0 and payment in ? and created_at between ? and ?",
+		[]int{payment.Wechat, payment.Alipay}, now.Add(-global.Config.Order.GetPayTimeout()-paySyncGrace), now.Add(-time.Minute)).
+		Order("id asc").Limit(100).Find(&orders).Error
+	if err != nil {
+		global.SugarLog.Errorf("查询待支付订单失败, err:%v \n", err)
+		return
+	}
+	for _, o := range orders {
+		if _, err = orderService.syncOrderPayment(o); err != nil {
+			global.SugarLog.Errorf("主动查询订单支付结果失败 orderSn:%s, err:%v \n", o.OrderSn, err)
+		}
+	}
+}
+
+// SyncOrderPayment 后台手动查询订单在支付渠道的支付结果，返回是否已支付
+func (orderService *OrderService) SyncOrderPayment(id uint) (paid bool, err error) {
+	var order shop.Order
+	if err = global.DB.Where("id = ?", id).First(&order).Error; err != nil {
+		return false, errors.New("订单不存在")
+	}
+	if *order.Status != 0 {
+		return true, nil
+	}
+	if order.Payment == nil || !payment.IsOnline(*order.Payment) {
+		return false, errors.New("该订单的支付方式不需要查询")
+	}
+	return orderService.syncOrderPayment(order)
+}
+
+// syncOrderPayment 查询单个订单的交易状态，已支付时执行与支付回调相同的处理
+func (orderService *OrderService) syncOrderPayment(order shop.Order) (bool, error) {
+	provider, err := payment.GetProvider(*order.Payment)
+	if err != nil {
+		return false, err
+	}
+	result, err := provider.Query(order.OrderSn)
+	if err != nil {
+		return false, err
+	}
+	if !result.Paid {
+		return false, nil
+	}
+	global.SugarLog.Infof("主动查询到订单已支付, 补偿处理支付结果 orderSn:%s, transactionId:%s \n", order.OrderSn, result.TransactionId)
+	raw, _ := json.Marshal(result)
+	notifyLog := createNotifyLog(payment.ChannelOf(*order.Payment), shop.NotifyTypeQuery, nil, raw)
+	notifyLog.OrderSn, notifyLog.TransactionId, notifyLog.Amount = result.OrderSn, result.TransactionId, result.Amount
+	return true, orderService.handlePayResult(&notifyLog, result)
+}
+
+// paymentName 支付方式名称，用于订单日志
+func paymentName(p int) string {
+	switch p {
+	case payment.Balance:
+		return "余额支付"
+	case payment.Wechat:
+		return "微信支付"
+	case payment.Alipay:
+		return "支付宝支付"
+	case payment.Point:
+		return "积分支付"
+	}
+	return "未知支付方式"
+}
+
+// SandboxPay 沙箱环境下模拟用户完成订单付款，只能支付自己的待支付订单
+func (orderService *OrderService) SandboxPay(orderSn string, userId uint) error {
+	var order shop.Order
+	if err := global.DB.Where("order_sn = ? and user_id = ?", orderSn, userId).First(&order).Error; err != nil {
+		return errors.New("订单不存在")
+	}
+	if *order.Status != 0 {
+		return errors.New("订单状态错误")
+	}
+	return payment.SandboxPay(orderSn)
+}
@@ -1,20 +1,97 @@
 package shop
 
 import (
+	"errors"
+	"fmt"
 	"fresh-shop/server/global"
+	"fresh-shop/server/model/business"
 	"fresh-shop/server/model/common/request"
 	"fresh-shop/server/model/shop"
 	shopReq "fresh-shop/server/model/shop/request"
+	"fresh-shop/server/service/common"
+	"fresh-shop/server/utils"
+	"gorm.io/gorm"
+	"math"
+	"strings"
+	"time"
 )
 
 type OrderReturnService struct {
 }
 
-// CreateOrderReturn 创建OrderReturn记录
+// CreateOrderReturn 用户申请售后，按订单商品逐行校验可售后数量，退货退款按实付金额分摊退款金额
 // Author [likfees](https://github.com/likfees)
-func (orderReturnService *OrderReturnService) CreateOrderReturn(orderReturn shop.OrderReturn) (err error) {
-	err = global.DB.Create(&orderReturn).Error
-	return err
+func (orderReturnService *OrderReturnService) CreateOrderReturn(req shopReq.OrderReturnApply, userId uint, op OrderOperator) (orderReturn shop.OrderReturn, err error) {
+	if req.Type != shop.ReturnTypeRefund && req.Type != shop.ReturnTypeExchange {
+		return orderReturn, errors.New("售后类型错误")
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return orderReturn, errors.New("请填写售后原因")
+	}
+	if len(req.Details) == 0 {
+		return orderReturn, errors.New("请选择售后商品")
+	}
+	var order shop.Order
+	if errors.Is(global.DB.Where("id = ? and user_id = ?", req.OrderId, userId).First(&order).Error, gorm.ErrRecordNotFound) {
+		return orderReturn, errors.New("订单不存在")
+	}
+	if req.PickUp && order.ShipmentAddress == "" {
+		return orderReturn, errors.New("订单没有收货地址，不能上门取件")
+	}
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		if txErr := orderReturnApply(tx, order, op, req.Reason); txErr != nil {
+			return txErr
+		}
+		details, returned, refunded, txErr := orderReturnable(tx, order.ID)
+		if txErr != nil {
+			return txErr
+		}
+		lines := make(map[uint]int, len(req.Details))
+		for _, d := range req.Details {
+			if d.Num <= 0 {
+				return errors.New("售后数量必须大于 0")
+			}
+			if _, ok := lines[d.OrderDetailId]; ok {
+				return errors.New("售后商品重复")
+			}
+			lines[d.OrderDetailId] = d.Num
+		}
+		for id, num := range lines {
+			detail, ok := findOrderDetail(details, id)
+			if !ok {
+				return errors.New("售后商品不属于该订单")
+			}
+			if num > detail.Num-returned[id] {
+				return fmt.Errorf("%s 最多可申请售后 %d 件", detail.GoodsName, detail.Num-returned[id])
+			}
+		}
+		orderReturn = shop.OrderReturn{
+			UserId:       utils.Pointer(int(userId)),
+			OrderId:      utils.Pointer(int(order.ID)),
+			Type:         utils.Pointer(req.Type),
+			Reason:       req.Reason,
+			Images:       req.Images,
+			Amount:       utils.Pointer(0.0),
+			Status:       utils.Pointer(0),
+			RefundStatus: utils.Pointer(0),
+		}
+		if req.PickUp {
+			orderReturn.PickUp = 1
+		}
+		if req.Type == shop.ReturnTypeRefund {
+			if txErr = calcReturnAmount(tx, order, details, refunded, lines, &orderReturn); txErr != nil {
+				return txErr
+			}
+		}
+		for _, d := range req.Details {
+			orderReturn.Details = append(orderReturn.Details, shop.OrderReturnDetails{
+				OrderDetailId: utils.Pointer(int(d.OrderDetailId)),
+				Num:           utils.Pointer(d.Num),
+			})
+		}
+		return tx.Create(&orderReturn).Error
+	})
+	return
 }
 
 // DeleteOrderReturn 删除OrderReturn记录
@@ -31,17 +108,443 @@ func (orderReturnService *OrderReturnService) DeleteOrderReturnByIds(ids request
 	return err
 }
 
-// UpdateOrderReturn 更新OrderReturn记录
+// ReviewOrderReturn 审核售后，拒绝时记录回复，同意退货退款时按原支付方式退款，同意换货时生成零元补发订单
 // Author [likfees](https://github.com/likfees)
-func (orderReturnService *OrderReturnService) UpdateOrderReturn(orderReturn shop.OrderReturn) (err error) {
-	err = global.DB.Save(&orderReturn).Error
-	return err
+func (orderReturnService *OrderReturnService) ReviewOrderReturn(req shopReq.OrderReturnReview, op OrderOperator) (err error) {
+	var orderReturn shop.OrderReturn
+	if errors.Is(global.DB.Where("id = ?", req.ID).Preload("Details").First(&orderReturn).Error, gorm.ErrRecordNotFound) {
+		return errors.New("售后记录不存在")
+	}
+	switch req.Status {
+	case -1:
+		// 退款失败的售后已经扣回积分和库存，只能重新退款
+		if *orderReturn.RefundStatus != 0 {
+			return ErrOrderTransition
+		}
+		return global.DB.Transaction(func(tx *gorm.DB) error {
+			return orderReturnTransit(tx, &orderReturn, OrderEventReturnReject, op, req.Reply, map[string]interface{}{
+				"reply":        req.Reply,
+				"process_time": time.Now(),
+			})
+		})
+	case 1:
+		if orderReturn.Type != nil && *orderReturn.Type == shop.ReturnTypeExchange {
+			if err = global.DB.Transaction(func(tx *gorm.DB) error {
+				return approveOrderExchange(tx, &orderReturn, req, op)
+			}); err != nil {
+				return err
+			}
+			if req.Restock {
+				releaseReturnStockReserve(orderReturn)
+			}
+			// 补发订单扣减了库存，清除库存缓存后重新从数据库加载
+			var goodsIds []uint
+			global.DB.Model(&shop.OrderDetails{}).Where("order_id = ?", orderReturn.ExchangeOrderId).Pluck("goods_id", &goodsIds)
+			for _, id := range goodsIds {
+				clearStockReserve(id)
+			}
+			return nil
+		}
+		firstRefund := *orderReturn.RefundStatus == 0
+		if err = global.DB.Transaction(func(tx *gorm.DB) error {
+			return approveOrderReturn(tx, &orderReturn, req, op)
+		}); err != nil {
+			return err
+		}
+		if firstRefund && req.Restock {
+			releaseReturnStockReserve(orderReturn)
+		}
+		submitRefund(uint(*orderReturn.OrderId), op)
+		return nil
+	default:
+		return ErrOrderTransition
+	}
+}
+
+// approveOrderReturn 同意售后并发起退款，订单退款状态与售后退款状态同步流转
+// 首次退款时扣回赠送积分、退回抵扣积分并按需退回库存，退款失败后重新发起只重新退款
+func approveOrderReturn(tx *gorm.DB, orderReturn *shop.OrderReturn, req shopReq.OrderReturnReview, op OrderOperator) error {
+	if *orderReturn.Status != 0 || (*orderReturn.RefundStatus != 0 && *orderReturn.RefundStatus != 3) {
+		return ErrOrderTransition
+	}
+	if orderReturn.Amount == nil {
+		return errors.New("售后退款金额错误")
+	}
+	var order shop.Order
+	if err := tx.Where("id = ?", orderReturn.OrderId).First(&order).Error; err != nil {
+		return err
+	}
+	firstRefund := *orderReturn.RefundStatus == 0
+	refundSn := utils.GenerateOrderNumber("RF")
+	updates := map[string]interface{}{
+		"refund_sn":     refundSn,
+		"refund_status": 1,
+		"reply":         req.Reply,
+		"process_time":  time.Now(),
+	}
+	if firstRefund && req.Restock {
+		updates["restock"] = 1
+	}
+	result := tx.Model(&shop.OrderReturn{}).
+		Where("id = ? and status = 0 and refund_status = ?", orderReturn.ID, *orderReturn.RefundStatus).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOrderStateChanged
+	}
+	reason := "售后退款"
+	if err := orderTransit(tx, &order, OrderEventRefund, op, reason, nil); err != nil {
+		return err
+	}
+	if firstRefund {
+		if err := settleOrderReturn(tx, order, *orderReturn, req.Restock); err != nil {
+			return err
+		}
+		if err := createReturnPickUp(tx, order, *orderReturn, req); err != nil {
+			return err
+		}
+	}
+	refundStatus, err := refundOrder(tx, order, refundSn, *orderReturn.Amount, reason)
+	if err != nil {
+		return err
+	}
+	if refundStatus != 2 {
+		// 在线支付的退款在事务提交后向支付渠道申请，到账后在退款结果中完成售后
+		return nil
+	}
+	if err = orderReturnTransit(tx, orderReturn, OrderEventReturnFinish, op, req.Reply, map[string]interface{}{"refund_status": 2}); err != nil {
+		return err
+	}
+	event, err := returnRefundEvent(tx, order.ID)
+	if err != nil {
+		return err
+	}
+	return orderTransit(tx, &order, event, op, reason, nil)
+}
+
+// returnRefundEvent 售后退款完成后订单的流转事件，订单商品已全部退款时为退款成功，否则恢复为未退款
+// 需要在售后记录完成后调用
+func returnRefundEvent(tx *gorm.DB, orderId uint) (OrderEvent, error) {
+	details, _, refunded, err := orderReturnable(tx, orderId)
+	if err != nil {
+		return "", err
+	}
+	for _, d := range details {
+		if refunded[d.ID] < d.Num {
+			return OrderEventPartRefund, nil
+		}
+	}
+	return OrderEventRefundSuccess, nil
+}
+
+// settleOrderReturn 处理售后退款的积分、库存和佣金，订单商品全部售后时同时退回优惠券
+// 用户积分不足以扣回本次售后对应的赠送积分时返回错误，售后审核不通过
+// 佣金和团队业绩按本次退款的商品金额同比例扣回，订单商品全部退款时扣回剩余部分
+func settleOrderReturn(tx *gorm.DB, order shop.Order, orderReturn shop.OrderReturn, restock bool) error {
+	user, err := orderUser(tx, order)
+	if err != nil {
+		return err
+	}
+	if orderReturn.GiftPoints > 0 {
+		f := common.NewFinance(common.OptionTypeCASH, common.FinanceTypeGiftPointReturn, user.ID, user.Username, -orderReturn.GiftPoints, order.OrderSn, user.ID, user.Username, "售后退款扣回赠送积分")
+		if err = common.AccountUnifyDeductionTx(tx, common.POINT, f); err != nil {
+			// 赠送积分已被用户消耗时不能同意售后，避免退款后积分无法追回
+			global.SugarLog.Errorf("扣回赠送积分失败 UserFinance:%v, error: %v", f, err)
+			return fmt.Errorf("扣回赠送积分失败: %v", err)
+		}
+	}
+	if orderReturn.Points > 0 {
+		f := common.NewFinance(common.OptionTypeCASH, common.FinanceTypePointOffsetReturn, user.ID, user.Username, orderReturn.Points, order.OrderSn, user.ID, user.Username, "售后退款退回积分")
+		if err = common.AccountUnifyDeductionTx(tx, common.POINT, f); err != nil {
+			return err
+		}
+	}
+	details, _, refunded, err := orderReturnable(tx, order.ID)
+	if err != nil {
+		return err
+	}
+	full := true
+	for _, d := range details {
+		num := returnDetailNum(orderReturn, d.ID)
+		if refunded[d.ID]+num < d.Num {
+			full = false
+		}
+		if restock && num > 0 {
+			if err = restoreGoodsStock(tx, d, num); err != nil {
+				return err
+			}
+		}
+	}
+	if !full {
+		// 部分售后的退款金额按商品实付金额分摊，不含运费，即为本次退款的计佣金额
+		return reverseOrderCommission(tx, order, *orderReturn.Amount, false)
+	}
+	if err = returnOrderCoupon(tx, order); err != nil {
+		return err
+	}
+	// 全部退款时的退款金额含运费，计佣金额取订单计佣金额扣除之前部分售后已退的部分
+	var done float64
+	err = tx.Model(&shop.OrderReturn{}).Select("COALESCE(SUM(amount), 0)").
+		Where("order_id = ? and type = ? and status = 1", order.ID, shop.ReturnTypeRefund).Scan(&done).Error
+	if err != nil {
+		return err
+	}
+	return reverseOrderCommission(tx, order, commissionBase(order)-done, true)
+}
+
+// releaseReturnStockReserve 售后商品退回库存的事务提交后归还 Redis 预占库存
+func releaseReturnStockReserve(orderReturn shop.OrderReturn) {
+	if !stockReserveEnabled() {
+		return
+	}
+	var details []shop.OrderDetails
+	if err := global.DB.Where("order_id = ?", orderReturn.OrderId).Find(&details).Error; err != nil {
+		global.SugarLog.Errorf("归还预占库存查询订单商品失败 returnId:%d, err:%v \n", orderReturn.ID, err)
+		return
+	}
+	for _, d := range details {
+		if num := returnDetailNum(orderReturn, d.ID); num > 0 {
+			releaseStockReserve(d.GoodsId, d.SpecId, num)
+		}
+	}
+}
+
+// approveOrderExchange 同意换货，按换货商品和数量生成零元补发订单并完成售后，补发订单按原订单的收货信息发货
+func approveOrderExchange(tx *gorm.DB, orderReturn *shop.OrderReturn, req shopReq.OrderReturnReview, op OrderOperator) error {
+	if *orderReturn.Status != 0 {
+		return ErrOrderTransition
+	}
+	var order shop.Order
+	if err := tx.Where("id = ?", orderReturn.OrderId).First(&order).Error; err != nil {
+		return err
+	}
+	details, _, _, err := orderReturnable(tx, order.ID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	exchange := shop.Order{
+		UserId:          order.UserId,
+		OrderSn:         utils.GenerateOrderNumber("SN"),
+		GoodsArea:       order.GoodsArea,
+		ShipmentName:    order.ShipmentName,
+		ShipmentMobile:  order.ShipmentMobile,
+		ShipmentAddress: order.ShipmentAddress,
+		ShipmentLng:     order.ShipmentLng,
+		ShipmentLat:     order.ShipmentLat,
+		ShipmentType:    order.ShipmentType,
+		SourceOrderId:   order.ID,
+		Payment:         order.Payment,
+		Status:          utils.Pointer(1), // 零元订单直接为已付款待发货
+		StatusCancel:    utils.Pointer(0),
+		StatusRefund:    utils.Pointer(0),
+		PayTime:         &now,
+		Remarks:         "换货补发 原订单号:" + order.OrderSn,
+	}
+	var exchangeDetails []shop.OrderDetails
+	for _, d := range details {
+		num := returnDetailNum(*orderReturn, d.ID)
+		if num == 0 {
+			continue
+		}
+		if err = deductStock(tx, d.GoodsId, d.SpecId, num); err != nil {
+			if errors.Is(err, ErrStockNotEnough) {
+				return fmt.Errorf("%s 库存不足，不能换货", d.GoodsName)
+			}
+			return err
+		}
+		exchange.Num += num
+		exchangeDetails = append(exchangeDetails, shop.OrderDetails{
+			GoodsId:     d.GoodsId,
+			GoodsName:   d.GoodsName,
+			SpecId:      d.SpecId,
+			SpecKeyName: d.SpecKeyName,
+			GoodsImage:  d.GoodsImage,
+			Unit:        d.Unit,
+			Num:         num,
+		})
+	}
+	if len(exchangeDetails) == 0 {
+		return errors.New("换货商品不存在")
+	}
+	if *exchange.ShipmentType == 1 {
+		if exchange.PickUpNumber, exchange.PickUpCode, err = nextPickUpNumber(tx); err != nil {
+			return errors.New("取餐号码生成失败")
+		}
+	}
+	if err = tx.Create(&exchange).Error; err != nil {
+		return err
+	}
+	if err = writeOrderLog(tx, exchange.ID, OrderEventCreate, "换货补发订单已提交", currentOrderState(exchange), op, order.OrderSn); err != nil {
+		return err
+	}
+	for i := range exchangeDetails {
+		exchangeDetails[i].OrderId = exchange.ID
+	}
+	if err = tx.Create(&exchangeDetails).Error; err != nil {
+		return err
+	}
+	if req.Restock {
+		for _, d := range details {
+			if num := returnDetailNum(*orderReturn, d.ID); num > 0 {
+				if err = restoreGoodsStock(tx, d, num); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if err = createReturnPickUp(tx, order, *orderReturn, req); err != nil {
+		return err
+	}
+	updates := map[string]interface{}{
+		"reply":             req.Reply,
+		"process_time":      now,
+		"exchange_order_id": exchange.ID,
+	}
+	if req.Restock {
+		updates["restock"] = 1
+	}
+	if err = orderReturnTransit(tx, orderReturn, OrderEventReturnFinish, op, req.Reply, updates); err != nil {
+		return err
+	}
+	orderReturn.ExchangeOrderId = exchange.ID
+	return nil
+}
+
+// createReturnPickUp 用户选择上门取回时指派配送员取回售后商品，取回进度记录在售后取回类型的配送信息中
+func createReturnPickUp(tx *gorm.DB, order shop.Order, orderReturn shop.OrderReturn, req shopReq.OrderReturnReview) error {
+	if orderReturn.PickUp != 1 {
+		return nil
+	}
+	if req.RiderId == 0 {
+		return errors.New("请选择上门取件的配送员")
+	}
+	var rider business.UserDelivery
+	if errors.Is(tx.Where("id = ? and status = 1", req.RiderId).First(&rider).Error, gorm.ErrRecordNotFound) {
+		return errors.New("配送员不存在或已禁用")
+	}
+	scheduled := time.Now()
+	if req.ScheduledTime != nil && req.ScheduledTime.After(scheduled) {
+		scheduled = *req.ScheduledTime
+	}
+	return tx.Create(&shop.OrderDelivery{
+		OrderId:       utils.Pointer(int(order.ID)),
+		Type:          utils.Pointer(shop.DeliveryTypeReturn),
+		ReturnId:      orderReturn.ID,
+		ScheduledTime: scheduled,
+		DeliverName:   rider.Name,
+		DeliveryId:    utils.Pointer(int(rider.ID)),
+		DeliverMobile: rider.Mobile,
+	}).Error
+}
+
+// returnDetailNum 售后申请中订单商品的数量
+func returnDetailNum(orderReturn shop.OrderReturn, orderDetailId uint) int {
+	for _, rd := range orderReturn.Details {
+		if rd.OrderDetailId != nil && uint(*rd.OrderDetailId) == orderDetailId && rd.Num != nil {
+			return *rd.Num
+		}
+	}
+	return 0
+}
+
+// orderReturnable 查询订单商品和已售后完成的数量，returned 包含换货数量，refunded 只包含退货退款数量
+func orderReturnable(tx *gorm.DB, orderId uint) (details []shop.OrderDetails, returned, refunded map[uint]int, err error) {
+	if err = tx.Where("order_id = ?", orderId).Order("id asc").Find(&details).Error; err != nil {
+		return
+	}
+	var rows []struct {
+		OrderDetailId uint
+		Num           int
+		RefundNum     int
+	}
+	err = tx.Model(&shop.OrderReturnDetails{}).
+		Select("shop_order_return_details.order_detail_id, SUM(shop_order_return_details.num) as num, "+
+			"SUM(CASE WHEN r.type = ? THEN shop_order_return_details.num ELSE 0 END) as refund_num", shop.ReturnTypeRefund).
+		Joins("JOIN shop_order_return r ON r.id = shop_order_return_details.return_id AND r.deleted_at IS NULL").
+		Where("r.order_id = ? and r.status = 1", orderId).
+		Group("shop_order_return_details.order_detail_id").
+		Scan(&rows).Error
+	if err != nil {
+		return
+	}
+	returned = make(map[uint]int, len(rows))
+	refunded = make(map[uint]int, len(rows))
+	for _, r := range rows {
+		returned[r.OrderDetailId] = r.Num
+		refunded[r.OrderDetailId] = r.RefundNum
+	}
+	return
+}
+
+// calcReturnAmount 计算售后的退款金额、退回的抵扣积分和扣回的赠送积分
+// 商品实付金额和抵扣积分按扣除优惠券后的商品金额分摊到订单商品，再按售后数量折算；
+// 订单商品全部退货时退回剩余的全部实付金额(含运费)和积分，避免分摊的尾差；refunded 为已退货退款的数量
+func calcReturnAmount(tx *gorm.DB, order shop.Order, details []shop.OrderDetails, refunded map[uint]int, lines map[uint]int, orderReturn *shop.OrderReturn) error {
+	weights := make([]float64, len(details))
+	for i, d := range details {
+		weights[i] = d.Total - d.CouponAmount
+	}
+	cash := utils.ProRate(weights, goodsPayAmount(order))
+	points := utils.ProRate(weights, order.PointOffset)
+	// 赠送积分只在普通商品确认收货时发放
+	giftPoints := *order.GoodsArea == 0 && order.GiftPoints > 0
+	var amount, point, gift float64
+	full := true
+	for i, d := range details {
+		num := lines[d.ID]
+		if refunded[d.ID]+num < d.Num {
+			full = false
+		}
+		if num == 0 || d.Num == 0 {
+			continue
+		}
+		ratio := float64(num) / float64(d.Num)
+		amount += cash[i] * ratio
+		point += points[i] * ratio
+		if giftPoints {
+			gift += d.GiftPoints * ratio
+		}
+	}
+	if full {
+		var done struct {
+			Amount     float64
+			Points     float64
+			GiftPoints float64
+		}
+		err := tx.Model(&shop.OrderReturn{}).
+			Select("COALESCE(SUM(amount), 0) as amount, COALESCE(SUM(points), 0) as points, COALESCE(SUM(gift_points), 0) as gift_points").
+			Where("order_id = ? and type = ? and status = 1", order.ID, shop.ReturnTypeRefund).Scan(&done).Error
+		if err != nil {
+			return err
+		}
+		amount, point = order.Finish-done.Amount, order.PointOffset-done.Points
+		if giftPoints {
+			gift = order.GiftPoints - done.GiftPoints
+		}
+	}
+	orderReturn.Amount = utils.Pointer(math.Max(math.Round(amount*100)/100, 0))
+	orderReturn.Points = math.Max(math.Round(point*100)/100, 0)
+	orderReturn.GiftPoints = math.Max(math.Round(gift*100)/100, 0)
+	return nil
+}
+
+// findOrderDetail 按订单商品id查找订单商品
+func findOrderDetail(details []shop.OrderDetails, id uint) (shop.OrderDetails, bool) {
+	for _, d := range details {
+		if d.ID == id {
+			return d, true
+		}
+	}
+	return shop.OrderDetails{}, false
 }
 
 // GetOrderReturn 根据id获取OrderReturn记录
 // Author [likfees](https://github.com/likfees)
 func (orderReturnService *OrderReturnService) GetOrderReturn(id uint) (orderReturn shop.OrderReturn, err error) {
-	err = global.DB.Where("id = ?", id).First(&orderReturn).Error
+	err = global.DB.Where("id = ?", id).Preload("Details").Preload("Delivery").First(&orderReturn).Error
 	return
 }
 
@@ -57,6 +560,15 @@ func (orderReturnService *OrderReturnService) GetOrderReturnInfoList(info shopRe
 	if info.StartCreatedAt != nil && info.EndCreatedAt != nil {
 		db = db.Where("created_at BETWEEN ? AND ?", info.StartCreatedAt, info.EndCreatedAt)
 	}
+	if info.UserId != nil {
+		db = db.Where("user_id = ?", info.UserId)
+	}
+	if info.OrderId != nil {
+		db = db.Where("order_id = ?", info.OrderId)
+	}
+	if info.Type != nil {
+		db = db.Where("type = ?", info.Type)
+	}
 	if info.Status != nil {
 		db = db.Where("status = ?", info.Status)
 	}
@@ -71,6 +583,6 @@ func (orderReturnService *OrderReturnService) GetOrderReturnInfoList(info shopRe
 		return
 	}
 
-	err = db.Limit(limit).Offset(offset).Find(&orderReturns).Error
+	err = db.Limit(limit).Offset(offset).Preload("Details").Preload("Delivery").Order("id desc").Find(&orderReturns).Error
 	return orderReturns, total, err
 }
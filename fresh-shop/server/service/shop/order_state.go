@@ -0,0 +1,310 @@
+package shop
+
+import (
+	"errors"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/business"
+	"fresh-shop/server/model/shop"
+	sysModel "fresh-shop/server/model/system"
+	systemReq "fresh-shop/server/model/system/request"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 订单状态机
+// 订单状态由 Status、StatusCancel、StatusRefund 三个字段共同决定，售后状态记录在 OrderReturn.Status。
+// 所有状态修改必须通过 orderTransit / orderReturnTransit 进行，非法流转会被拒绝，
+// 每一次流转都会写入 shop_order_log，用于订单时间线展示
+
+// OrderEvent 订单流转事件
+type OrderEvent string
+
+const (
+	OrderEventCreate        OrderEvent = "create"         // 提交订单
+	OrderEventPay           OrderEvent = "pay"            // 支付
+	OrderEventShip          OrderEvent = "ship"           // 发货
+	OrderEventReceive       OrderEvent = "receive"        // 收货
+	OrderEventCancel        OrderEvent = "cancel"         // 取消
+	OrderEventRefund        OrderEvent = "refund"         // 发起退款
+	OrderEventRefundSuccess OrderEvent = "refund_success" // 退款成功
+	OrderEventRefundFail    OrderEvent = "refund_fail"    // 退款失败
+	OrderEventPartRefund    OrderEvent = "part_refund"    // 售后部分退款成功 订单恢复为未退款
+	OrderEventReturnApply   OrderEvent = "return_apply"   // 申请售后
+	OrderEventReturnReject  OrderEvent = "return_reject"  // 拒绝售后
+	OrderEventReturnFinish  OrderEvent = "return_finish"  // 售后完成
+	OrderEventPickUp        OrderEvent = "pick_up"        // 配送员取货 只记录日志不改变订单状态
+	OrderEventReturnPickUp  OrderEvent = "return_pick_up" // 配送员上门取回售后商品 只记录日志
+	OrderEventReturnBack    OrderEvent = "return_back"    // 售后商品送回门店 只记录日志
+	OrderEventGroupPay      OrderEvent = "group_pay"      // 拼团订单支付 等待成团
+	OrderEventGroupSuccess  OrderEvent = "group_success"  // 拼团成功
+)
+
+// orderStatusGrouping 拼团订单已支付等待成团，成团后流转为已付款待发货，未成团自动退款
+const orderStatusGrouping = 4
+
+var (
+	ErrOrderTransition   = errors.New("订单当前状态不允许该操作")
+	ErrOrderStateChanged = errors.New("订单状态已变化，请刷新后重试")
+)
+
+// userAuthorityId 小程序普通用户角色
+const userAuthorityId = 1000
+
+// OrderOperator 订单操作人
+type OrderOperator struct {
+	Source int // 操作来源 shop.OrderSourceXxx
+	Id     uint
+	Name   string
+}
+
+var (
+	TimerOperator    = OrderOperator{Source: shop.OrderSourceTimer, Name: "系统"}
+	CallbackOperator = OrderOperator{Source: shop.OrderSourceCallback, Name: "支付回调"}
+)
+
+// NewOrderOperator 根据登录信息生成操作人，普通用户为用户操作，配送员为配送员操作，其他角色为后台操作
+func NewOrderOperator(claims *systemReq.CustomClaims) OrderOperator {
+	if claims == nil {
+		return OrderOperator{Source: shop.OrderSourceAdmin}
+	}
+	op := OrderOperator{Source: shop.OrderSourceAdmin, Id: claims.ID, Name: claims.Username}
+	switch claims.AuthorityId {
+	case userAuthorityId:
+		op.Source = shop.OrderSourceUser
+	case business.RiderAuthorityId:
+		op.Source = shop.OrderSourceRider
+	}
+	return op
+}
+
+// UserOperator 用户本人操作
+func UserOperator(user sysModel.SysUser) OrderOperator {
+	return OrderOperator{Source: shop.OrderSourceUser, Id: user.ID, Name: user.Username}
+}
+
+// orderState 订单状态快照
+type orderState struct {
+	status int
+	cancel int
+	refund int
+}
+
+func currentOrderState(order shop.Order) orderState {
+	var s orderState
+	if order.Status != nil {
+		s.status = *order.Status
+	}
+	if order.StatusCancel != nil {
+		s.cancel = *order.StatusCancel
+	}
+	if order.StatusRefund != nil {
+		s.refund = *order.StatusRefund
+	}
+	return s
+}
+
+// 是否为正常进行中的订单(未取消、未退款)
+func (s orderState) normal() bool {
+	return s.cancel == 0 && s.refund == 0
+}
+
+type orderTransition struct {
+	title string
+	allow func(s orderState) bool
+	next  func(s orderState, op OrderOperator) orderState
+}
+
+var orderTransitions = map[OrderEvent]orderTransition{
+	OrderEventPay: {
+		title: "订单已支付",
+		allow: func(s orderState) bool { return s.status == 0 && s.normal() },
+		next:  func(s orderState, op OrderOperator) orderState { s.status = 1; return s },
+	},
+	OrderEventGroupPay: {
+		title: "订单已支付，等待成团",
+		allow: func(s orderState) bool { return s.status == 0 && s.normal() },
+		next:  func(s orderState, op OrderOperator) orderState { s.status = orderStatusGrouping; return s },
+	},
+	OrderEventGroupSuccess: {
+		title: "拼团成功",
+		allow: func(s orderState) bool { return s.status == orderStatusGrouping && s.normal() },
+		next:  func(s orderState, op OrderOperator) orderState { s.status = 1; return s },
+	},
+	OrderEventShip: {
+		title: "商家已发货",
+		allow: func(s orderState) bool { return s.status == 1 && s.normal() },
+		next:  func(s orderState, op OrderOperator) orderState { s.status = 2; return s },
+	},
+	OrderEventReceive: {
+		title: "订单已收货",
+		allow: func(s orderState) bool { return s.status == 2 && s.normal() },
+		next:  func(s orderState, op OrderOperator) orderState { s.status = 3; return s },
+	},
+	OrderEventCancel: {
+		title: "订单已取消",
+		// 发货后不允许取消
+		allow: func(s orderState) bool { return s.status <= 1 && s.normal() },
+		next: func(s orderState, op OrderOperator) orderState {
+			switch op.Source {
+			case shop.OrderSourceUser:
+				s.cancel = 1
+			case shop.OrderSourceTimer:
+				s.cancel = 3
+			default:
+				s.cancel = 2
+			}
+			return s
+		},
+	},
+	OrderEventRefund: {
+		title: "退款处理中",
+		// 已支付订单可以退款，退款失败后允许重新发起
+		allow: func(s orderState) bool { return s.status >= 1 && s.refund != 1 },
+		next:  func(s orderState, op OrderOperator) orderState { s.refund = 1; return s },
+	},
+	OrderEventRefundSuccess: {
+		title: "退款成功",
+		allow: func(s orderState) bool { return s.refund == 1 },
+		next:  func(s orderState, op OrderOperator) orderState { s.refund = 2; return s },
+	},
+	OrderEventPartRefund: {
+		title: "部分退款成功",
+		// 订单还有未退款的商品，退款状态恢复为未退款，允许继续售后
+		allow: func(s orderState) bool { return s.refund == 1 },
+		next:  func(s orderState, op OrderOperator) orderState { s.refund = 0; return s },
+	},
+	OrderEventRefundFail: {
+		title: "退款失败",
+		allow: func(s orderState) bool { return s.refund == 1 },
+		next:  func(s orderState, op OrderOperator) orderState { s.refund = 3; return s },
+	},
+}
+
+// canOrderTransit 判断订单当前状态是否允许执行该事件
+func canOrderTransit(order shop.Order, event OrderEvent) bool {
+	t, ok := orderTransitions[event]
+	return ok && t.allow(currentOrderState(order))
+}
+
+// orderTransit 在事务中执行订单状态流转并记录日志
+// 使用流转前的状态作为更新条件，并发修改时返回 ErrOrderStateChanged；updates 为需要同时更新的其他字段
+func orderTransit(tx *gorm.DB, order *shop.Order, event OrderEvent, op OrderOperator, remark string, updates map[string]interface{}) error {
+	t, ok := orderTransitions[event]
+	from := currentOrderState(*order)
+	if !ok || !t.allow(from) {
+		global.SugarLog.Errorf("订单状态流转失败 orderId:%d, event:%s, state:%+v \n", order.ID, event, from)
+		return ErrOrderTransition
+	}
+	to := t.next(from, op)
+	values := map[string]interface{}{
+		"status":        to.status,
+		"status_cancel": to.cancel,
+		"status_refund": to.refund,
+	}
+	for k, v := range updates {
+		values[k] = v
+	}
+	result := tx.Model(&shop.Order{}).
+		Where("id = ? and status = ? and status_cancel = ? and status_refund = ?", order.ID, from.status, from.cancel, from.refund).
+		Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOrderStateChanged
+	}
+	order.Status, order.StatusCancel, order.StatusRefund = &to.status, &to.cancel, &to.refund
+	return writeOrderLog(tx, order.ID, event, t.title, to, op, remark)
+}
+
+// region 售后状态 OrderReturn.Status (-1拒绝售后 0未处理 1已完成)
+
+var orderReturnTransitions = map[OrderEvent]struct {
+	title string
+	from  int
+	to    int
+}{
+	OrderEventReturnReject: {title: "售后已拒绝", from: 0, to: -1},
+	OrderEventReturnFinish: {title: "售后已完成", from: 0, to: 1},
+}
+
+// orderReturnApply 校验订单是否可以申请售后并记录日志
+// 确认收货后才能申请售后，未发货的订单直接取消；之前的售后已退款的订单可以对剩余商品再次申请
+func orderReturnApply(tx *gorm.DB, order shop.Order, op OrderOperator, remark string) error {
+	// 锁定订单行，同一订单的售后申请排队校验，避免并发申请时都通过未处理售后的检查
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", order.ID).First(&order).Error; err != nil {
+		return err
+	}
+	s := currentOrderState(order)
+	if s.status != 3 || s.cancel != 0 || s.refund == 1 {
+		return ErrOrderTransition
+	}
+	var count int64
+	if err := tx.Model(&shop.OrderReturn{}).Where("order_id = ? and status = 0", order.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("订单已申请售后，请等待处理")
+	}
+	return writeOrderLog(tx, order.ID, OrderEventReturnApply, "申请售后", s, op, remark)
+}
+
+// orderReturnTransit 在事务中执行售后状态流转并记录日志
+func orderReturnTransit(tx *gorm.DB, orderReturn *shop.OrderReturn, event OrderEvent, op OrderOperator, remark string, updates map[string]interface{}) error {
+	t, ok := orderReturnTransitions[event]
+	if !ok || orderReturn.Status == nil || *orderReturn.Status != t.from {
+		return ErrOrderTransition
+	}
+	values := map[string]interface{}{"status": t.to}
+	for k, v := range updates {
+		values[k] = v
+	}
+	result := tx.Model(&shop.OrderReturn{}).Where("id = ? and status = ?", orderReturn.ID, t.from).Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOrderStateChanged
+	}
+	orderReturn.Status = &t.to
+	var order shop.Order
+	if err := tx.Where("id = ?", orderReturn.OrderId).First(&order).Error; err != nil {
+		return err
+	}
+	return writeOrderLog(tx, order.ID, event, t.title, currentOrderState(order), op, remark)
+}
+
+// endregion
+
+// writeOrderLog 写入订单流转记录，state 为流转后的状态
+func writeOrderLog(tx *gorm.DB, orderId uint, event OrderEvent, title string, state orderState, op OrderOperator, remark string) error {
+	return tx.Create(&shop.OrderLog{
+		OrderId:      orderId,
+		Event:        string(event),
+		Title:        title,
+		Status:       state.status,
+		StatusCancel: state.cancel,
+		StatusRefund: state.refund,
+		Source:       op.Source,
+		OperatorId:   op.Id,
+		Operator:     op.Name,
+		Remark:       remark,
+	}).Error
+}
+
+// GetOrderTimeline 获取订单时间线，userId 不为 0 时只能查询自己的订单
+func (orderService *OrderService) GetOrderTimeline(orderId, userId uint) (list []shop.OrderLog, err error) {
+	db := global.DB.Model(&shop.Order{}).Where("id = ?", orderId)
+	if userId != 0 {
+		db = db.Where("user_id = ?", userId)
+	}
+	var count int64
+	if err = db.Count(&count).Error; err != nil {
+		return
+	}
+	if count == 0 {
+		return nil, errors.New("订单不存在")
+	}
+	err = global.DB.Where("order_id = ?", orderId).Order("id asc").Find(&list).Error
+	return
+}
@@ -0,0 +1,197 @@
+package shop
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/shop"
+	shopReq "fresh-shop/server/model/shop/request"
+	emailUtils "fresh-shop/server/plugin/email/utils"
+	"fresh-shop/server/service/payment"
+	"net/http"
+	"strings"
+)
+
+type PaymentNotifyLogService struct {
+}
+
+// PayNotify 记录支付通知原文后处理支付结果，返回 nil 时应答渠道成功，渠道不再重复通知
+// 重复通知和异常通知同样应答成功，异常通知由人工审核处理
+func (orderService *OrderService) PayNotify(channel string, header http.Header, raw []byte, result payment.TradeResult, verifyErr error) error {
+	notifyLog := createNotifyLog(channel, shop.NotifyTypePay, header, raw)
+	if verifyErr != nil {
+		finishNotifyLog(&notifyLog, shop.NotifyStatusFailed, verifyErr.Error())
+		return verifyErr
+	}
+	notifyLog.OrderSn, notifyLog.TransactionId, notifyLog.Amount = result.OrderSn, result.TransactionId, result.Amount
+	if !result.Paid {
+		finishNotifyLog(&notifyLog, shop.NotifyStatusIgnored, "交易状态 "+result.State)
+		return nil
+	}
+	if err := payment.CheckMerchant(channel, result); err != nil {
+		finishNotifyLog(&notifyLog, shop.NotifyStatusAnomaly, err.Error())
+		return nil
+	}
+	if notifyProcessed(notifyLog, "transaction_id = ?", result.TransactionId) {
+		finishNotifyLog(&notifyLog, shop.NotifyStatusDuplicate, "交易已处理")
+		return nil
+	}
+	return orderService.handlePayResult(&notifyLog, result)
+}
+
+// RefundNotify 记录退款通知原文后处理退款结果，返回 nil 时应答渠道成功
+func (orderService *OrderService) RefundNotify(channel string, header http.Header, raw []byte, result payment.RefundResult, verifyErr error) error {
+	notifyLog := createNotifyLog(channel, shop.NotifyTypeRefund, header, raw)
+	if verifyErr != nil {
+		finishNotifyLog(&notifyLog, shop.NotifyStatusFailed, verifyErr.Error())
+		return verifyErr
+	}
+	notifyLog.OrderSn, notifyLog.RefundSn = result.OrderSn, result.RefundSn
+	if notifyProcessed(notifyLog, "refund_sn = ?", result.RefundSn) {
+		finishNotifyLog(&notifyLog, shop.NotifyStatusDuplicate, "退款已处理")
+		return nil
+	}
+	if err := orderService.RefundNotifyLogic(result); err != nil {
+		finishNotifyLog(&notifyLog, shop.NotifyStatusFailed, err.Error())
+		return err
+	}
+	finishNotifyLog(&notifyLog, shop.NotifyStatusProcessed, "退款状态 "+result.State)
+	return nil
+}
+
+// handlePayResult 处理已校验的支付结果并记录处理状态，重复和异常的支付结果不需要渠道重新通知
+func (orderService *OrderService) handlePayResult(notifyLog *shop.PaymentNotifyLog, result payment.TradeResult) error {
+	err := orderService.PayNotifyLogic(result)
+	switch {
+	case err == nil:
+		finishNotifyLog(notifyLog, shop.NotifyStatusProcessed, "")
+	case errors.Is(err, ErrPayDuplicate):
+		finishNotifyLog(notifyLog, shop.NotifyStatusDuplicate, "交易已处理")
+	case errors.Is(err, ErrPayAnomaly):
+		finishNotifyLog(notifyLog, shop.NotifyStatusAnomaly, err.Error())
+	default:
+		finishNotifyLog(notifyLog, shop.NotifyStatusFailed, err.Error())
+		return err
+	}
+	return nil
+}
+
+// createNotifyLog 保存通知原文，保存失败不影响通知处理
+func createNotifyLog(channel string, notifyType int, header http.Header, raw []byte) shop.PaymentNotifyLog {
+	notifyLog := shop.PaymentNotifyLog{
+		Channel:    channel,
+		NotifyType: notifyType,
+		Headers:    notifyHeaders(header),
+		RawBody:    string(raw),
+	}
+	if err := global.DB.Create(&notifyLog).Error; err != nil {
+		global.SugarLog.Errorf("保存支付通知记录失败 channel:%s, err:%v, body:%s \n", channel, err, string(raw))
+	}
+	return notifyLog
+}
+
+// finishNotifyLog 更新通知的解析结果和处理状态，异常通知发送邮件提醒管理员审核
+func finishNotifyLog(notifyLog *shop.PaymentNotifyLog, status int, remark string) {
+	notifyLog.Status, notifyLog.Remark = status, remark
+	if status == shop.NotifyStatusAnomaly {
+		global.SugarLog.Errorf("支付通知异常待人工审核 orderSn:%s, transactionId:%s, %s \n", notifyLog.OrderSn, notifyLog.TransactionId, remark)
+		notifyPaymentAnomaly(*notifyLog)
+	}
+	if notifyLog.ID == 0 {
+		return
+	}
+	err := global.DB.Model(&shop.PaymentNotifyLog{}).Where("id = ?", notifyLog.ID).Updates(map[string]interface{}{
+		"order_sn":       notifyLog.OrderSn,
+		"transaction_id": notifyLog.TransactionId,
+		"refund_sn":      notifyLog.RefundSn,
+		"amount":         notifyLog.Amount,
+		"status":         status,
+		"remark":         remark,
+	}).Error
+	if err != nil {
+		global.SugarLog.Errorf("更新支付通知记录失败 id:%d, err:%v \n", notifyLog.ID, err)
+	}
+}
+
+// notifyProcessed 同一笔交易或退款是否已有处理完成的通知
+func notifyProcessed(notifyLog shop.PaymentNotifyLog, query string, key string) bool {
+	if key == "" {
+		return false
+	}
+	var count int64
+	global.DB.Model(&shop.PaymentNotifyLog{}).
+		Where("notify_type in ? and id <> ? and status in ?", []int{notifyLog.NotifyType, shop.NotifyTypeQuery}, notifyLog.ID,
+			[]int{shop.NotifyStatusProcessed, shop.NotifyStatusAnomaly}).
+		Where(query, key).Count(&count)
+	return count > 0
+}
+
+// notifyHeaders 只保留验签相关的请求头，便于事后核对签名
+func notifyHeaders(header http.Header) string {
+	values := map[string]string{}
+	for k := range header {
+		if strings.HasPrefix(k, "Wechatpay-") || k == "Content-Type" {
+			values[k] = header.Get(k)
+		}
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// notifyPaymentAnomaly 邮件提醒管理员审核异常支付，收件人为 email 配置中的 to
+func notifyPaymentAnomaly(notifyLog shop.PaymentNotifyLog) {
+	subject := fmt.Sprintf("支付异常待审核 订单号:%s", notifyLog.OrderSn)
+	body := fmt.Sprintf("支付渠道: %s<br/>订单号: %s<br/>交易号: %s<br/>金额: %.2f<br/>异常原因: %s",
+		notifyLog.Channel, notifyLog.OrderSn, notifyLog.TransactionId, notifyLog.Amount, notifyLog.Remark)
+	if err := emailUtils.ErrorToEmail(subject, body); err != nil {
+		global.SugarLog.Errorf("支付异常邮件发送失败 orderSn:%s, err:%v \n", notifyLog.OrderSn, err)
+	}
+}
+
+// GetPaymentNotifyLogInfoList 分页获取支付通知记录
+func (paymentNotifyLogService *PaymentNotifyLogService) GetPaymentNotifyLogInfoList(info shopReq.PaymentNotifyLogSearch) (list []shop.PaymentNotifyLog, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.DB.Model(&shop.PaymentNotifyLog{})
+	if info.Channel != "" {
+		db = db.Where("channel = ?", info.Channel)
+	}
+	if info.NotifyType != 0 {
+		db = db.Where("notify_type = ?", info.NotifyType)
+	}
+	if info.OrderSn != "" {
+		db = db.Where("order_sn = ?", info.OrderSn)
+	}
+	if info.TransactionId != "" {
+		db = db.Where("transaction_id = ?", info.TransactionId)
+	}
+	if info.Status != nil {
+		db = db.Where("status = ?", *info.Status)
+	}
+	if info.Handled != nil {
+		db = db.Where("handled = ?", *info.Handled)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Limit(limit).Offset(offset).Order("id desc").Find(&list).Error
+	return
+}
+
+// HandlePaymentNotifyLog 人工审核异常通知后标记已处理，退款等处理需要另行操作
+func (paymentNotifyLogService *PaymentNotifyLogService) HandlePaymentNotifyLog(req shopReq.HandleNotifyLogReq) error {
+	result := global.DB.Model(&shop.PaymentNotifyLog{}).
+		Where("id = ? and status = ? and handled = 0", req.ID, shop.NotifyStatusAnomaly).
+		Updates(map[string]interface{}{"handled": 1, "handle_remark": req.HandleRemark})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("记录不存在或不是待处理的异常通知")
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package shop
+
+import (
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/shop"
+	shopReq "fresh-shop/server/model/shop/request"
+	emailUtils "fresh-shop/server/plugin/email/utils"
+	"fresh-shop/server/service/payment"
+	"fresh-shop/server/utils"
+	"gorm.io/gorm"
+	"math"
+	"strings"
+	"time"
+)
+
+type PaymentReconcileService struct {
+}
+
+// reconcileMailLimit 对账差异邮件中最多列出的明细条数，完整差异在后台查看
+const reconcileMailLimit = 50
+
+// DailyReconcile 下载前一天的微信支付账单进行对账，定时任务调用
+func (s *PaymentReconcileService) DailyReconcile() {
+	if global.Config.Payment.Sandbox.Enable {
+		global.SugarLog.Infof("沙箱支付已开启, 跳过微信支付账单对账 \n")
+		return
+	}
+	billDate := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	if _, err := s.Reconcile(billDate, nil); err != nil {
+		global.SugarLog.Errorf("微信支付账单对账失败 billDate:%s, err:%v \n", billDate, err)
+	}
+}
+
+// Reconcile 对账指定日期的微信支付交易账单，data 为空时从微信支付下载账单
+// 重复对账同一天时覆盖之前的对账结果，有差异时发送邮件通知管理员
+func (s *PaymentReconcileService) Reconcile(billDate string, data []byte) (reconcile shop.PaymentReconcile, err error) {
+	day, err := time.ParseInLocation("2006-01-02", billDate, time.Local)
+	if err != nil {
+		return reconcile, errors.New("账单日期格式错误")
+	}
+	if len(data) == 0 {
+		if data, err = downloadWechatBill(day); err != nil {
+			return
+		}
+	}
+	records, err := utils.ParseWechatBill(data)
+	if err != nil {
+		return reconcile, fmt.Errorf("账单解析失败 %v", err)
+	}
+	reconcile, diffs, err := reconcileWechatBill(billDate, day, records)
+	if err != nil {
+		return
+	}
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		var old shop.PaymentReconcile
+		if err := tx.Where("bill_date = ? and payment = ?", billDate, payment.Wechat).Limit(1).Find(&old).Error; err != nil {
+			return err
+		}
+		if old.ID > 0 {
+			if err := tx.Unscoped().Where("reconcile_id = ?", old.ID).Delete(&shop.PaymentReconcileDiff{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Delete(&old).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&reconcile).Error; err != nil {
+			return err
+		}
+		if len(diffs) == 0 {
+			return nil
+		}
+		for i := range diffs {
+			diffs[i].ReconcileId = reconcile.ID
+		}
+		return tx.CreateInBatches(&diffs, 100).Error
+	})
+	if err != nil {
+		return
+	}
+	global.SugarLog.Infof("微信支付账单对账完成 billDate:%s, 账单笔数:%d, 订单笔数:%d, 差异笔数:%d \n",
+		billDate, reconcile.BillCount, reconcile.OrderCount, reconcile.DiffCount)
+	if reconcile.DiffCount > 0 {
+		notifyReconcileDiffs(reconcile, diffs)
+	}
+	return reconcile, nil
+}
+
+// downloadWechatBill 从微信支付下载交易账单
+func downloadWechatBill(day time.Time) ([]byte, error) {
+	provider, err := payment.GetProvider(payment.Wechat)
+	if err != nil {
+		return nil, err
+	}
+	downloader, ok := provider.(payment.BillDownloader)
+	if !ok {
+		return nil, errors.New("当前支付渠道不支持下载账单，请上传账单文件")
+	}
+	return downloader.DownloadBill(day)
+}
+
+// reconcileWechatBill 比对账单明细与平台订单，账单支付成功的交易与当天微信支付的订单逐笔核对
+func reconcileWechatBill(billDate string, day time.Time, records []utils.WechatBillRecord) (reconcile shop.PaymentReconcile, diffs []shop.PaymentReconcileDiff, err error) {
+	reconcile = shop.PaymentReconcile{BillDate: billDate, Payment: payment.Wechat}
+	// 当天支付的微信订单
+	var paidOrders []shop.Order
+	err = global.DB.Where("payment = ? and pay_time >= ? and pay_time < ?", payment.Wechat, day, day.AddDate(0, 0, 1)).
+		Find(&paidOrders).Error
+	if err != nil {
+		return
+	}
+	orders := make(map[string]shop.Order, len(paidOrders))
+	for _, o := range paidOrders {
+		orders[o.OrderSn] = o
+		reconcile.OrderCount++
+		reconcile.OrderAmount += o.Finish
+	}
+	// 账单中有但不在当天支付订单里的，按订单号补充查询
+	var missing []string
+	for _, r := range records {
+		if _, ok := orders[r.OrderSn]; !ok && r.OrderSn != "" {
+			missing = append(missing, r.OrderSn)
+		}
+	}
+	if len(missing) > 0 {
+		var others []shop.Order
+		if err = global.DB.Where("order_sn in ?", missing).Find(&others).Error; err != nil {
+			return
+		}
+		for _, o := range others {
+			orders[o.OrderSn] = o
+		}
+	}
+
+	matched := make(map[string]bool, len(records))
+	for _, r := range records {
+		diff := shop.PaymentReconcileDiff{
+			OrderSn:       r.OrderSn,
+			TransactionId: r.TransactionId,
+			BillState:     r.TradeState,
+			BillAmount:    r.Amount,
+		}
+		order, ok := orders[r.OrderSn]
+		if ok {
+			diff.OrderId = order.ID
+			diff.OrderAmount = order.Finish
+		}
+		switch r.TradeState {
+		case "SUCCESS":
+			reconcile.BillCount++
+			reconcile.BillAmount += r.Amount
+			matched[r.OrderSn] = true
+			switch {
+			case !ok:
+				diff.DiffType, diff.Remark = shop.ReconcileDiffMissingOrder, "账单有支付成功的交易，平台没有对应订单"
+			case *order.Status == 0:
+				diff.DiffType, diff.Remark = shop.ReconcileDiffStatus, fmt.Sprintf("账单已支付，订单未支付 取消状态:%d", *order.StatusCancel)
+			case math.Abs(r.Amount-order.Finish) > 0.001:
+				diff.DiffType, diff.Remark = shop.ReconcileDiffAmount, fmt.Sprintf("账单金额 %.2f 与订单实付金额 %.2f 不一致", r.Amount, order.Finish)
+			}
+		case "REFUND":
+			diff.BillAmount = r.RefundAmount
+			switch {
+			case !ok:
+				diff.DiffType, diff.Remark = shop.ReconcileDiffMissingOrder, "账单有退款交易，平台没有对应订单"
+			case order.StatusRefund == nil || *order.StatusRefund == 0 || *order.StatusRefund == 3:
+				diff.DiffType, diff.Remark = shop.ReconcileDiffStatus, fmt.Sprintf("账单已退款 %.2f，订单未退款", r.RefundAmount)
+			}
+		}
+		if diff.DiffType > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+	for _, o := range paidOrders {
+		if !matched[o.OrderSn] {
+			diffs = append(diffs, shop.PaymentReconcileDiff{
+				DiffType:      shop.ReconcileDiffMissingBill,
+				OrderId:       o.ID,
+				OrderSn:       o.OrderSn,
+				TransactionId: o.TransationId,
+				OrderAmount:   o.Finish,
+				Remark:        "订单已支付，账单中没有对应交易",
+			})
+		}
+	}
+	reconcile.BillAmount = math.Round(reconcile.BillAmount*100) / 100
+	reconcile.OrderAmount = math.Round(reconcile.OrderAmount*100) / 100
+	reconcile.DiffCount = len(diffs)
+	return reconcile, diffs, nil
+}
+
+// notifyReconcileDiffs 邮件通知管理员对账差异，收件人为 email 配置中的 to
+func notifyReconcileDiffs(reconcile shop.PaymentReconcile, diffs []shop.PaymentReconcileDiff) {
+	var body strings.Builder
+	body.WriteString(fmt.Sprintf("账单日期: %s<br/>账单笔数: %d, 账单金额: %.2f<br/>订单笔数: %d, 订单金额: %.2f<br/>差异笔数: %d<br/><br/>",
+		reconcile.BillDate, reconcile.BillCount, reconcile.BillAmount, reconcile.OrderCount, reconcile.OrderAmount, reconcile.DiffCount))
+	for i, d := range diffs {
+		if i >= reconcileMailLimit {
+			body.WriteString(fmt.Sprintf("... 其余 %d 条差异请在后台查看<br/>", len(diffs)-reconcileMailLimit))
+			break
+		}
+		body.WriteString(fmt.Sprintf("订单号: %s, 交易号: %s, %s<br/>", d.OrderSn, d.TransactionId, d.Remark))
+	}
+	subject := fmt.Sprintf("微信支付对账差异 %s 共 %d 笔", reconcile.BillDate, reconcile.DiffCount)
+	if err := emailUtils.ErrorToEmail(subject, body.String()); err != nil {
+		global.SugarLog.Errorf("对账差异邮件发送失败 billDate:%s, err:%v \n", reconcile.BillDate, err)
+	}
+}
+
+// GetPaymentReconcileInfoList 分页获取对账记录
+func (s *PaymentReconcileService) GetPaymentReconcileInfoList(info shopReq.PaymentReconcileSearch) (list []shop.PaymentReconcile, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.DB.Model(&shop.PaymentReconcile{})
+	if info.Payment != 0 {
+		db = db.Where("payment = ?", info.Payment)
+	}
+	if info.StartBillDate != "" && info.EndBillDate != "" {
+		db = db.Where("bill_date BETWEEN ? AND ?", info.StartBillDate, info.EndBillDate)
+	}
+	if info.OnlyDifference {
+		db = db.Where("diff_count > 0")
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Limit(limit).Offset(offset).Order("bill_date desc").Find(&list).Error
+	return
+}
+
+// GetPaymentReconcileDiffInfoList 分页获取对账差异明细
+func (s *PaymentReconcileService) GetPaymentReconcileDiffInfoList(info shopReq.PaymentReconcileDiffSearch) (list []shop.PaymentReconcileDiff, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.DB.Model(&shop.PaymentReconcileDiff{})
+	if info.ReconcileId != 0 {
+		db = db.Where("reconcile_id = ?", info.ReconcileId)
+	}
+	if info.OrderSn != "" {
+		db = db.Where("order_sn = ?", info.OrderSn)
+	}
+	if info.DiffType != 0 {
+		db = db.Where("diff_type = ?", info.DiffType)
+	}
+	if info.Handled != nil {
+		db = db.Where("handled = ?", *info.Handled)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Limit(limit).Offset(offset).Order("id asc").Find(&list).Error
+	return
+}
+
+// HandlePaymentReconcileDiff 标记对账差异已处理
+func (s *PaymentReconcileService) HandlePaymentReconcileDiff(id uint) error {
+	result := global.DB.Model(&shop.PaymentReconcileDiff{}).Where("id = ?", id).Update("handled", 1)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("差异记录不存在")
+	}
+	return nil
+}
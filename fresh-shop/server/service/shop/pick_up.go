@@ -0,0 +1,32 @@
+package shop
+
+import (
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/shop"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strconv"
+	"time"
+)
+
+// nextPickUpNumber 在下单事务中生成当天的取餐号码，返回号码和带前缀的取餐码
+// 使用 INSERT ... ON DUPLICATE KEY UPDATE 原子递增当天的序列行，行锁持有到事务结束，
+// 并发下单不会拿到相同的号码；订单创建失败回滚时号码也一并回滚
+func nextPickUpNumber(tx *gorm.DB) (number int, code string, err error) {
+	cfg := global.Config.Order
+	day := time.Now().Format("20060102")
+	seq := shop.PickUpSequence{Day: day, Value: cfg.GetPickUpStart()}
+	err = tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"value":      gorm.Expr("value + 1"),
+			"updated_at": time.Now(),
+		}),
+	}).Create(&seq).Error
+	if err != nil {
+		return
+	}
+	if err = tx.Model(&shop.PickUpSequence{}).Where("day = ?", day).Pluck("value", &number).Error; err != nil {
+		return
+	}
+	return number, cfg.PickUpPrefix + strconv.Itoa(number), nil
+}
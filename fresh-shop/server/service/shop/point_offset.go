@@ -0,0 +1,114 @@
+package shop
+
+import (
+	"errors"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/shop"
+	sysModel "fresh-shop/server/model/system"
+	"fresh-shop/server/service/common"
+	"fresh-shop/server/utils"
+	"gorm.io/gorm"
+	"math"
+	"strconv"
+)
+
+// pointOffsetConfig 获取积分抵扣配置，pointRatio 为多少积分抵扣 1 元，pointOffsetMax 为订单商品金额最多可抵扣的百分比
+// 任一配置不存在或禁用时不能使用积分抵扣
+func pointOffsetConfig() (ratio, maxPercent float64, ok bool) {
+	for name, v := range map[string]*float64{"pointRatio": &ratio, "pointOffsetMax": &maxPercent} {
+		value, err := common.GetSysConfig(name)
+		if err != nil {
+			if !errors.Is(err, common.ErrConfigDisabled) && !errors.Is(err, gorm.ErrRecordNotFound) {
+				global.SugarLog.Errorf("查询积分抵扣配置异常 %s, err:%v \n", name, err)
+			}
+			return 0, 0, false
+		}
+		if *v, err = strconv.ParseFloat(value, 64); err != nil || *v <= 0 {
+			global.SugarLog.Errorf("积分抵扣配置参数错误 %s:%s \n", name, value)
+			return 0, 0, false
+		}
+	}
+	return ratio, maxPercent, true
+}
+
+// calcPointOffset 根据用户提交的积分数量计算实际使用的积分和抵扣金额，goodsAmount 为扣除优惠券后的商品金额
+func calcPointOffset(goodsAmount, points float64) (usePoints, amount float64, err error) {
+	if points <= 0 {
+		return 0, 0, nil
+	}
+	ratio, maxPercent, ok := pointOffsetConfig()
+	if !ok {
+		return 0, 0, errors.New("积分抵扣未开启")
+	}
+	usePoints, amount = utils.PointOffset(goodsAmount, points, ratio, maxPercent)
+	return
+}
+
+// freezeOrderPoints 创建订单时冻结抵扣使用的积分
+func freezeOrderPoints(tx *gorm.DB, order shop.Order, user sysModel.SysUser) error {
+	if order.PointOffset <= 0 {
+		return nil
+	}
+	f := common.NewFinance(common.OptionTypeCASH, common.FinanceTypePointOffset, user.ID, user.Username, -order.PointOffset, order.OrderSn, user.ID, user.Username, "订单积分抵扣")
+	if err := common.AccountUnifyDeductionTx(tx, common.POINT, f); err != nil {
+		return err
+	}
+	f = common.NewFinance(common.OptionTypeFreeze, common.FinanceTypePointOffset, user.ID, user.Username, order.PointOffset, order.OrderSn, user.ID, user.Username, "订单积分抵扣冻结")
+	return common.AccountUnifyDeductionTx(tx, common.POINT, f)
+}
+
+// settleOrderPoints 订单支付完成后扣除冻结的抵扣积分
+func settleOrderPoints(tx *gorm.DB, order shop.Order) error {
+	if order.PointOffset <= 0 {
+		return nil
+	}
+	user, err := orderUser(tx, order)
+	if err != nil {
+		return err
+	}
+	f := common.NewFinance(common.OptionTypeFreeze, common.FinanceTypePointOffset, user.ID, user.Username, -order.PointOffset, order.OrderSn, user.ID, user.Username, "订单支付扣除冻结积分")
+	return common.AccountUnifyDeductionTx(tx, common.POINT, f)
+}
+
+// unfreezeOrderPoints 未支付订单取消时解冻抵扣积分，退回可用积分
+func unfreezeOrderPoints(tx *gorm.DB, order shop.Order) error {
+	if order.PointOffset <= 0 {
+		return nil
+	}
+	user, err := orderUser(tx, order)
+	if err != nil {
+		return err
+	}
+	f := common.NewFinance(common.OptionTypeFreeze, common.FinanceTypePointOffsetReturn, user.ID, user.Username, -order.PointOffset, order.OrderSn, user.ID, user.Username, "订单取消解冻积分")
+	if err = common.AccountUnifyDeductionTx(tx, common.POINT, f); err != nil {
+		return err
+	}
+	f = common.NewFinance(common.OptionTypeCASH, common.FinanceTypePointOffsetReturn, user.ID, user.Username, order.PointOffset, order.OrderSn, user.ID, user.Username, "订单取消退回积分")
+	return common.AccountUnifyDeductionTx(tx, common.POINT, f)
+}
+
+// returnOrderPoints 已支付订单退款时退回抵扣积分
+func returnOrderPoints(tx *gorm.DB, order shop.Order) error {
+	if order.PointOffset <= 0 {
+		return nil
+	}
+	user, err := orderUser(tx, order)
+	if err != nil {
+		return err
+	}
+	f := common.NewFinance(common.OptionTypeCASH, common.FinanceTypePointOffsetReturn, user.ID, user.Username, order.PointOffset, order.OrderSn, user.ID, user.Username, "订单退款退回积分")
+	return common.AccountUnifyDeductionTx(tx, common.POINT, f)
+}
+
+// orderUser 查询订单所属用户，账户变动需要用户名
+func orderUser(tx *gorm.DB, order shop.Order) (user sysModel.SysUser, err error) {
+	if err = tx.Where("id = ?", order.UserId).First(&user).Error; err != nil {
+		return user, errors.New("用户查询失败")
+	}
+	return
+}
+
+// goodsPayAmount 订单商品部分的现金金额，商品总金额扣除优惠券和积分抵扣
+func goodsPayAmount(order shop.Order) float64 {
+	return math.Max(math.Round((order.Total-order.CouponAmount-order.PointAmount)*100)/100, 0)
+}
@@ -0,0 +1,181 @@
+package shop
+
+import (
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/shop"
+	"fresh-shop/server/service/common"
+	"fresh-shop/server/utils"
+	"gorm.io/gorm"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 运费规则在系统配置 sys_config 中设置，配置不存在或禁用时该项规则不参与计算，全部未配置时免运费
+// postageFee       基础运费 例: 5
+// postageFreeTotal 满额包邮，商品总金额达到该值免运费 例: 39
+// postageWeight    按重量计费 首重(g),续重(g),续重运费 例: 1000,500,1
+// postageDistance  按距离计费 距离(km):运费 多个用逗号分隔 例: 3:0,5:2,10:5
+// storeLocation    门店坐标 经度,纬度 例: 116.397477,39.908692
+
+// PostageRule 运费规则
+type PostageRule struct {
+	Fee         float64           // 基础运费
+	FreeTotal   float64           // 满额包邮金额 0不包邮
+	WeightFirst int               // 首重(g) 首重内不收续重费
+	WeightStep  int               // 续重单位(g)
+	WeightFee   float64           // 每续重单位运费
+	Distances   []PostageDistance // 距离区间运费 按距离从小到大
+}
+
+// PostageDistance 距离区间运费，配送距离不超过 Km 时收取 Fee
+type PostageDistance struct {
+	Km  float64
+	Fee float64
+}
+
+// Calc 计算运费 total 商品总金额，weight 商品总重量(g)，distance 配送距离(km) 小于 0 表示未知
+func (r PostageRule) Calc(total float64, weight int, distance float64) float64 {
+	if r.FreeTotal > 0 && total >= r.FreeTotal {
+		return 0
+	}
+	fee := r.Fee
+	// 续重运费 不足一个续重单位按一个计算
+	if r.WeightStep > 0 && weight > r.WeightFirst {
+		steps := math.Ceil(float64(weight-r.WeightFirst) / float64(r.WeightStep))
+		fee += steps * r.WeightFee
+	}
+	// 距离运费 超出最大区间按最大区间计算
+	if distance >= 0 && len(r.Distances) > 0 {
+		band := r.Distances[len(r.Distances)-1]
+		for _, d := range r.Distances {
+			if distance <= d.Km {
+				band = d
+				break
+			}
+		}
+		fee += band.Fee
+	}
+	return math.Round(fee*100) / 100
+}
+
+// loadPostageRule 从系统配置中加载运费规则
+func loadPostageRule() (rule PostageRule, err error) {
+	if rule.Fee, err = postageConfigFloat("postageFee"); err != nil {
+		return
+	}
+	if rule.FreeTotal, err = postageConfigFloat("postageFreeTotal"); err != nil {
+		return
+	}
+	if v, ok, cfgErr := postageConfig("postageWeight"); cfgErr != nil {
+		return rule, cfgErr
+	} else if ok {
+		parts := strings.Split(v, ",")
+		if len(parts) != 3 {
+			return rule, fmt.Errorf("运费配置 postageWeight 格式错误: %s", v)
+		}
+		if rule.WeightFirst, err = strconv.Atoi(strings.TrimSpace(parts[0])); err != nil {
+			return
+		}
+		if rule.WeightStep, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+			return
+		}
+		if rule.WeightFee, err = strconv.ParseFloat(strings.TrimSpace(parts[2]), 64); err != nil {
+			return
+		}
+	}
+	if v, ok, cfgErr := postageConfig("postageDistance"); cfgErr != nil {
+		return rule, cfgErr
+	} else if ok {
+		for _, item := range strings.Split(v, ",") {
+			kv := strings.Split(strings.TrimSpace(item), ":")
+			if len(kv) != 2 {
+				return rule, fmt.Errorf("运费配置 postageDistance 格式错误: %s", v)
+			}
+			var d PostageDistance
+			if d.Km, err = strconv.ParseFloat(kv[0], 64); err != nil {
+				return
+			}
+			if d.Fee, err = strconv.ParseFloat(kv[1], 64); err != nil {
+				return
+			}
+			rule.Distances = append(rule.Distances, d)
+		}
+		sort.Slice(rule.Distances, func(i, j int) bool { return rule.Distances[i].Km < rule.Distances[j].Km })
+	}
+	return
+}
+
+// postageConfig 读取系统配置，未配置或禁用时 ok 返回 false
+func postageConfig(name string) (value string, ok bool, err error) {
+	value, err = common.GetSysConfig(name)
+	if err != nil {
+		if errors.Is(err, common.ErrConfigDisabled) || errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	value = strings.TrimSpace(value)
+	return value, value != "", nil
+}
+
+func postageConfigFloat(name string) (float64, error) {
+	v, ok, err := postageConfig(name)
+	if err != nil || !ok {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("运费配置 %s 格式错误: %s", name, v)
+	}
+	return f, nil
+}
+
+// getStoreLocation 获取系统配置中的门店坐标
+func getStoreLocation() (lng, lat float64, ok bool) {
+	v, ok, err := postageConfig("storeLocation")
+	if err != nil || !ok {
+		return 0, 0, false
+	}
+	parts := strings.Split(v, ",")
+	if len(parts) != 2 {
+		global.SugarLog.Errorf("门店坐标配置 storeLocation 格式错误: %s \n", v)
+		return 0, 0, false
+	}
+	lng, lngErr := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	lat, latErr := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if lngErr != nil || latErr != nil {
+		global.SugarLog.Errorf("门店坐标配置 storeLocation 格式错误: %s \n", v)
+		return 0, 0, false
+	}
+	return lng, lat, true
+}
+
+// addressDistance 计算收货地址到门店的距离(km)，地址或门店未设置坐标时返回 -1
+func addressDistance(address shop.UserAddress) float64 {
+	if address.Longitude == nil || address.Latitude == nil || (*address.Longitude == 0 && *address.Latitude == 0) {
+		return -1
+	}
+	lng, lat, ok := getStoreLocation()
+	if !ok {
+		return -1
+	}
+	return utils.Distance(lng, lat, *address.Longitude, *address.Latitude) / 1000
+}
+
+// calcOrderPostage 计算订单运费，自提订单免运费
+func calcOrderPostage(shipmentType int, address shop.UserAddress, total float64, weight int) (postage float64, distance float64, err error) {
+	if shipmentType == 1 {
+		return 0, -1, nil
+	}
+	rule, err := loadPostageRule()
+	if err != nil {
+		global.SugarLog.Errorf("加载运费规则失败 err:%v \n", err)
+		return 0, -1, errors.New("运费计算失败")
+	}
+	distance = addressDistance(address)
+	return rule.Calc(total, weight, distance), distance, nil
+}
@@ -0,0 +1,235 @@
+package shop
+
+import (
+	"errors"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/business"
+	"fresh-shop/server/model/shop"
+	shopReq "fresh-shop/server/model/shop/request"
+	shopResp "fresh-shop/server/model/shop/response"
+	sysModel "fresh-shop/server/model/system"
+	"fresh-shop/server/utils/upload"
+	"gorm.io/gorm"
+	"mime/multipart"
+	"strings"
+	"time"
+)
+
+// RiderService 配送员端 配送员只能查看和操作指派给自己的订单
+type RiderService struct {
+}
+
+// maxProofImages 送达凭证最多上传的图片数量
+const maxProofImages = 9
+
+// GetRider 根据登录账号获取启用中的配送员
+func (riderService *RiderService) GetRider(userId uint) (rider business.UserDelivery, err error) {
+	err = global.DB.Where("user_id = ?", userId).First(&rider).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return rider, errors.New("配送员不存在")
+	}
+	if err != nil {
+		return
+	}
+	if rider.Status == nil || *rider.Status != 1 {
+		return rider, errors.New("配送员已禁用")
+	}
+	return
+}
+
+// GetRiderOrderList 分页获取指派给配送员的订单，按预计到达时间排序，包含售后上门取回的订单
+func (riderService *RiderService) GetRiderOrderList(userId uint, info shopReq.RiderOrderSearch) (list []shopResp.RiderOrder, total int64, err error) {
+	rider, err := riderService.GetRider(userId)
+	if err != nil {
+		return
+	}
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.DB.Model(&shop.OrderDelivery{}).Where("delivery_id = ?", rider.ID)
+	if info.Delivered != nil {
+		if *info.Delivered == 1 {
+			db = db.Where("receipt_time IS NOT NULL")
+		} else {
+			db = db.Where("receipt_time IS NULL")
+		}
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	var deliveries []shop.OrderDelivery
+	if err = db.Order("scheduled_time asc, id asc").Limit(limit).Offset(offset).Find(&deliveries).Error; err != nil {
+		return
+	}
+	orderIds := make([]int, 0, len(deliveries))
+	for _, d := range deliveries {
+		orderIds = append(orderIds, *d.OrderId)
+	}
+	var orders []shop.Order
+	if err = global.DB.Where("id in ?", orderIds).Preload("OrderDetails").Find(&orders).Error; err != nil {
+		return
+	}
+	orderMap := make(map[uint]shop.Order, len(orders))
+	for _, o := range orders {
+		orderMap[o.ID] = o
+	}
+	for _, d := range deliveries {
+		list = append(list, shopResp.RiderOrder{Delivery: d, Order: orderMap[uint(*d.OrderId)]})
+	}
+	return
+}
+
+// PickUpOrder 配送员取货
+func (riderService *RiderService) PickUpOrder(userId uint, orderId uint, op OrderOperator) error {
+	_, delivery, order, err := riderService.riderOrder(userId, orderId)
+	if err != nil {
+		return err
+	}
+	if delivery.PickUpTime != nil {
+		return errors.New("订单已取货")
+	}
+	if !canOrderTransit(order, OrderEventReceive) {
+		return ErrOrderTransition
+	}
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&shop.OrderDelivery{}).Where("id = ? and pick_up_time IS NULL", delivery.ID).Update("pick_up_time", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("订单已取货")
+		}
+		return writeOrderLog(tx, order.ID, OrderEventPickUp, "配送员已取货", currentOrderState(order), op, "")
+	})
+}
+
+// DeliverOrder 配送员确认送达，与后台确认收货一样完成收货并发放赠送积分
+func (riderService *RiderService) DeliverOrder(userId uint, orderId uint, op OrderOperator) error {
+	rider, delivery, order, err := riderService.riderOrder(userId, orderId)
+	if err != nil {
+		return err
+	}
+	if delivery.ReceiptTime != nil {
+		return errors.New("订单已送达")
+	}
+	var user sysModel.SysUser
+	if err = global.DB.Where("id = ?", order.UserId).First(&user).Error; err != nil {
+		global.SugarLog.Errorf("获取用户信息失败 userId:%d, error: %v", order.UserId, err)
+		return err
+	}
+	now := time.Now()
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := receiveOrder(tx, &order, user, op, now); err != nil {
+			return err
+		}
+		values := map[string]interface{}{"receipt_time": now}
+		if delivery.PickUpTime == nil {
+			values["pick_up_time"] = now
+		}
+		if err := tx.Model(&shop.OrderDelivery{}).Where("id = ?", delivery.ID).Updates(values).Error; err != nil {
+			return err
+		}
+		return tx.Model(&business.UserDelivery{}).Where("id = ?", rider.ID).Update("deliver_count", gorm.Expr("IFNULL(deliver_count, 0) + 1")).Error
+	})
+}
+
+// UploadProof 上传送达凭证图片，返回图片地址
+func (riderService *RiderService) UploadProof(userId uint, orderId uint, header *multipart.FileHeader) (url string, err error) {
+	_, delivery, _, err := riderService.riderOrder(userId, orderId)
+	if err != nil {
+		return
+	}
+	var images []string
+	if delivery.ProofImages != "" {
+		images = strings.Split(delivery.ProofImages, ",")
+	}
+	if len(images) >= maxProofImages {
+		return "", errors.New("送达凭证图片数量已达上限")
+	}
+	oss := upload.NewOss()
+	url, _, err = oss.UploadFile(header)
+	if err != nil {
+		global.SugarLog.Errorf("上传送达凭证失败 orderId:%d, err:%v \n", orderId, err)
+		return "", errors.New("上传文件失败")
+	}
+	images = append(images, url)
+	err = global.DB.Model(&shop.OrderDelivery{}).Where("id = ?", delivery.ID).Update("proof_images", strings.Join(images, ",")).Error
+	return
+}
+
+// PickUpReturn 配送员上门取回售后商品
+func (riderService *RiderService) PickUpReturn(userId uint, returnId uint, op OrderOperator) error {
+	delivery, order, err := riderService.riderReturn(userId, returnId)
+	if err != nil {
+		return err
+	}
+	if delivery.PickUpTime != nil {
+		return errors.New("售后商品已取件")
+	}
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&shop.OrderDelivery{}).Where("id = ? and pick_up_time IS NULL", delivery.ID).Update("pick_up_time", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("售后商品已取件")
+		}
+		return writeOrderLog(tx, order.ID, OrderEventReturnPickUp, "配送员已上门取件", currentOrderState(order), op, "")
+	})
+}
+
+// DeliverReturn 配送员将取回的售后商品送回门店
+func (riderService *RiderService) DeliverReturn(userId uint, returnId uint, op OrderOperator) error {
+	delivery, order, err := riderService.riderReturn(userId, returnId)
+	if err != nil {
+		return err
+	}
+	if delivery.ReceiptTime != nil {
+		return errors.New("售后商品已送回")
+	}
+	now := time.Now()
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		values := map[string]interface{}{"receipt_time": now}
+		if delivery.PickUpTime == nil {
+			values["pick_up_time"] = now
+		}
+		result := tx.Model(&shop.OrderDelivery{}).Where("id = ? and receipt_time IS NULL", delivery.ID).Updates(values)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("售后商品已送回")
+		}
+		return writeOrderLog(tx, order.ID, OrderEventReturnBack, "售后商品已送回", currentOrderState(order), op, "")
+	})
+}
+
+// riderReturn 获取配送员的售后取回信息，未指派给该配送员时返回错误
+func (riderService *RiderService) riderReturn(userId uint, returnId uint) (delivery shop.OrderDelivery, order shop.Order, err error) {
+	rider, err := riderService.GetRider(userId)
+	if err != nil {
+		return
+	}
+	if errors.Is(global.DB.Where("return_id = ? and delivery_id = ? and type = ?", returnId, rider.ID, shop.DeliveryTypeReturn).First(&delivery).Error, gorm.ErrRecordNotFound) {
+		err = errors.New("售后取件不存在或未指派给当前配送员")
+		return
+	}
+	if errors.Is(global.DB.Where("id = ?", delivery.OrderId).First(&order).Error, gorm.ErrRecordNotFound) {
+		err = errors.New("订单不存在")
+	}
+	return
+}
+
+// riderOrder 获取配送员的订单，订单未指派给该配送员时返回错误
+func (riderService *RiderService) riderOrder(userId uint, orderId uint) (rider business.UserDelivery, delivery shop.OrderDelivery, order shop.Order, err error) {
+	if rider, err = riderService.GetRider(userId); err != nil {
+		return
+	}
+	if errors.Is(global.DB.Where("order_id = ? and delivery_id = ? and type = ?", orderId, rider.ID, shop.DeliveryTypeShip).First(&delivery).Error, gorm.ErrRecordNotFound) {
+		err = errors.New("订单不存在或未指派给当前配送员")
+		return
+	}
+	if errors.Is(global.DB.Where("id = ?", orderId).First(&order).Error, gorm.ErrRecordNotFound) {
+		err = errors.New("订单不存在")
+	}
+	return
+}
@@ -0,0 +1,186 @@
+package shop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"fresh-shop/server/model/shop"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+	"strconv"
+	"time"
+)
+
+var ErrStockNotEnough = errors.New("商品库存不足")
+
+// deductStock 扣减商品库存并增加销量，库存不足时返回 ErrStockNotEnough
+// 使用 store >= num 条件更新，由数据库行锁保证并发下不会超卖
+func deductStock(tx *gorm.DB, goodsId uint, specId int, num int) error {
+	res := tx.Model(&shop.Goods{}).Where("id = ? and store >= ?", goodsId, num).Updates(map[string]interface{}{
+		"store": gorm.Expr("store - ?", num),
+		"sale":  gorm.Expr("sale + ?", num),
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrStockNotEnough
+	}
+	// 多规格商品同时扣减规格库存
+	if specId > 0 {
+		res = tx.Model(&shop.GoodsSpecValue{}).Where("id = ? and goods_id = ? and store >= ?", specId, goodsId, num).Updates(map[string]interface{}{
+			"store": gorm.Expr("store - ?", num),
+			"sale":  gorm.Expr("sale + ?", num),
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrStockNotEnough
+		}
+	}
+	return nil
+}
+
+// region Redis 库存预占
+// 开启 order.stock-reserve 后，下单前先在 Redis 中预占库存，热门商品的大部分请求在 Redis 层就被拦截，
+// 不会全部落到数据库行锁上。Redis 只做前置过滤，最终以数据库条件扣减结果为准。
+// 每个商品一个 hash，field 为规格值 id（单规格商品为 0），value 为剩余库存
+
+// stockReserveTTL 预占库存缓存有效期，过期后从数据库重新加载，避免与后台修改的库存长期不一致
+const stockReserveTTL = 30 * time.Minute
+
+// 返回 -1 缓存不存在，0 库存不足，1 预占成功
+var reserveScript = redis.NewScript(`
+local store = redis.call('HGET', KEYS[1], ARGV[1])
+if not store then
+	return -1
+end
+if tonumber(store) < tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('HINCRBY', KEYS[1], ARGV[1], -tonumber(ARGV[2]))
+return 1
+`)
+
+// 仅在缓存存在时归还，缓存不存在时下次预占会从数据库重新加载
+var releaseScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then
+	redis.call('HINCRBY', KEYS[1], ARGV[1], tonumber(ARGV[2]))
+end
+return 1
+`)
+
+// stockReservation 一次下单中已预占的库存，下单失败或订单取消时归还
+type stockReservation struct {
+	items []stockReserveItem
+}
+
+type stockReserveItem struct {
+	goodsId uint
+	specId  int
+	num     int
+}
+
+func stockReserveEnabled() bool {
+	return global.Config.Order.StockReserve && global.Redis != nil
+}
+
+func stockReserveKey(goodsId uint) string {
+	return fmt.Sprintf("shop:stock:%d", goodsId)
+}
+
+// reserve 预占库存，Redis 异常时放行交由数据库条件扣减兜底
+func (r *stockReservation) reserve(goodsId uint, specId int, num int) error {
+	if !stockReserveEnabled() {
+		return nil
+	}
+	ctx := context.Background()
+	key := stockReserveKey(goodsId)
+	field := strconv.Itoa(specId)
+	for i := 0; i < 2; i++ {
+		ret, err := reserveScript.Run(ctx, global.Redis, []string{key}, field, num).Int()
+		if err != nil {
+			global.SugarLog.Errorf("Redis 预占库存异常 goodsId:%d, specId:%d, err:%v \n", goodsId, specId, err)
+			return nil
+		}
+		switch ret {
+		case 1:
+			r.items = append(r.items, stockReserveItem{goodsId: goodsId, specId: specId, num: num})
+			return nil
+		case 0:
+			return ErrStockNotEnough
+		}
+		// 缓存不存在 从数据库加载后重试
+		if err = loadStockReserve(ctx, key, field, goodsId, specId); err != nil {
+			global.SugarLog.Errorf("Redis 加载商品库存异常 goodsId:%d, specId:%d, err:%v \n", goodsId, specId, err)
+			return nil
+		}
+	}
+	return nil
+}
+
+// release 归还本次预占的全部库存
+func (r *stockReservation) release() {
+	for _, item := range r.items {
+		releaseStockReserve(item.goodsId, item.specId, item.num)
+	}
+	r.items = nil
+}
+
+func loadStockReserve(ctx context.Context, key, field string, goodsId uint, specId int) error {
+	var store int
+	if specId > 0 {
+		if err := global.DB.Model(&shop.GoodsSpecValue{}).Where("id = ? and goods_id = ?", specId, goodsId).Pluck("store", &store).Error; err != nil {
+			return err
+		}
+	} else {
+		if err := global.DB.Model(&shop.Goods{}).Where("id = ?", goodsId).Pluck("store", &store).Error; err != nil {
+			return err
+		}
+	}
+	pipe := global.Redis.TxPipeline()
+	pipe.HSetNX(ctx, key, field, store)
+	pipe.Expire(ctx, key, stockReserveTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// releaseStockReserve 归还 Redis 预占库存
+func releaseStockReserve(goodsId uint, specId int, num int) {
+	if !stockReserveEnabled() {
+		return
+	}
+	field := strconv.Itoa(specId)
+	if err := releaseScript.Run(context.Background(), global.Redis, []string{stockReserveKey(goodsId)}, field, num).Err(); err != nil {
+		global.SugarLog.Errorf("Redis 归还预占库存异常 goodsId:%d, specId:%d, err:%v \n", goodsId, specId, err)
+	}
+}
+
+// releaseOrderStockReserve 归还订单全部商品的 Redis 预占库存，在归还数据库库存的事务提交后调用
+func releaseOrderStockReserve(orderId uint) {
+	if !stockReserveEnabled() {
+		return
+	}
+	var details []shop.OrderDetails
+	if err := global.DB.Where("order_id = ?", orderId).Find(&details).Error; err != nil {
+		global.SugarLog.Errorf("归还预占库存查询订单商品失败 orderId:%d, err:%v \n", orderId, err)
+		return
+	}
+	for _, d := range details {
+		releaseStockReserve(d.GoodsId, d.SpecId, d.Num)
+	}
+}
+
+// clearStockReserve 清除商品的库存缓存，后台修改库存后调用
+func clearStockReserve(goodsId uint) {
+	if global.Redis == nil {
+		return
+	}
+	if err := global.Redis.Del(context.Background(), stockReserveKey(goodsId)).Err(); err != nil {
+		global.SugarLog.Errorf("Redis 清除商品库存缓存异常 goodsId:%d, err:%v \n", goodsId, err)
+	}
+}
+
+// endregion
@@ -6,6 +6,8 @@ import (
 	"fresh-shop/server/model/common/request"
 	"fresh-shop/server/model/shop"
 	shopReq "fresh-shop/server/model/shop/request"
+	"fresh-shop/server/service/common"
+	"fresh-shop/server/utils"
 	"gorm.io/gorm"
 )
 
@@ -15,6 +17,9 @@ type UserAddressService struct {
 // CreateUserAddress 创建UserAddress记录
 // Author [likfees](https://github.com/likfees)
 func (userAddressService *UserAddressService) CreateUserAddress(userAddress shop.UserAddress) (address shop.UserAddress, err error) {
+	if err = markAddressZone(&userAddress); err != nil {
+		return userAddress, err
+	}
 	err = global.DB.Transaction(func(tx *gorm.DB) error {
 		if *userAddress.IsDefault == 1 {
 			// 更新所有默认值为 0
@@ -51,6 +56,9 @@ func (userAddressService *UserAddressService) DeleteUserAddressByIds(ids request
 // UpdateUserAddress 更新UserAddress记录
 // Author [likfees](https://github.com/likfees)
 func (userAddressService *UserAddressService) UpdateUserAddress(userAddress shop.UserAddress) (err error) {
+	if err = markAddressZone(&userAddress); err != nil {
+		return err
+	}
 	err = global.DB.Transaction(func(tx *gorm.DB) error {
 		if *userAddress.IsDefault == 1 {
 			// 更新所有默认值为 0
@@ -99,3 +107,26 @@ func (userAddressService *UserAddressService) GetUserAddressInfoList(info shopRe
 	err = db.Order("is_default desc").Find(&userAddresss).Error
 	return userAddresss, err
 }
+
+// markAddressZone 根据地址坐标匹配配送区域，标记地址是否可配送
+func markAddressZone(address *shop.UserAddress) error {
+	var lng, lat float64
+	if address.Longitude != nil && address.Latitude != nil {
+		lng, lat = *address.Longitude, *address.Latitude
+	}
+	zone, deliverable, err := common.MatchDeliveryZone(lng, lat)
+	if err != nil {
+		global.SugarLog.Errorf("匹配配送区域失败 err:%v \n", err)
+		return errors.New("配送区域查询失败")
+	}
+	address.ZoneId = 0
+	if zone != nil {
+		address.ZoneId = zone.ID
+	}
+	if deliverable {
+		address.Deliverable = utils.Pointer(1)
+	} else {
+		address.Deliverable = utils.Pointer(0)
+	}
+	return nil
+}
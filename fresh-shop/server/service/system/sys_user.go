@@ -6,6 +6,7 @@ import (
 	"fresh-shop/server/model/account"
 	sysReq "fresh-shop/server/model/system/request"
 	"fresh-shop/server/model/wechat/request"
+	"fresh-shop/server/service/common"
 	"go.uber.org/zap"
 	"time"
 
@@ -55,6 +56,7 @@ func (userService *UserService) LoginWx(req request.LoginReq) (user *system.SysU
 			Enable:      1,
 			Phone:       d.PhoneNumber,
 			OpenId:      req.OpenId,
+			InviterId:   userService.FindInviterId(req.InviteCode),
 		}
 		regUser, err := userService.Register(u)
 		user = &regUser
@@ -130,6 +132,18 @@ func (userService *UserService) Register(u system.SysUser) (userInter system.Sys
 			global.SugarLog.Errorf("注册用户 --- 用户名：%s, 创建账户配置失败, 插入数据: %#v ,err: %s", u.Username, groupData, txErr.Error())
 			return txErr
 		}
+		// 记录邀请关系，累计上级团队人数
+		if txErr = common.IncTeamMember(tx, u.ID, u.InviterId); txErr != nil {
+			global.SugarLog.Errorf("注册用户 --- 用户名：%s, 累计团队人数失败, inviterId: %d, err: %s", u.Username, u.InviterId, txErr.Error())
+			return txErr
+		}
+		// 小程序用户注册赠送优惠券
+		if u.AuthorityId == 1000 {
+			if txErr = common.IssueRegisterCoupons(tx, u.ID); txErr != nil {
+				global.SugarLog.Errorf("注册用户 --- 用户名：%s, 发放注册优惠券失败, err: %s", u.Username, txErr.Error())
+				return txErr
+			}
+		}
 		// 提交事务
 		return nil
 	})
@@ -137,6 +151,19 @@ func (userService *UserService) Register(u system.SysUser) (userInter system.Sys
 	return u, err
 }
 
+// FindInviterId 根据邀请码查找邀请人id，邀请码为空或不存在时返回 0
+func (userService *UserService) FindInviterId(code string) uint {
+	if code == "" {
+		return 0
+	}
+	var inviter system.SysUser
+	if err := global.DB.Select("id").Where("invitation_code = ?", code).First(&inviter).Error; err != nil {
+		global.SugarLog.Warnf("邀请码查询失败 code: %s, err: %s", code, err.Error())
+		return 0
+	}
+	return inviter.ID
+}
+
 // @author: [likfees](https://github.com/likfees)
 // @author: [SliverHorn](https://github.com/SliverHorn)
 // @function: LoginWx
@@ -207,6 +234,24 @@ func (userService *UserService) ChangePassword(u *system.SysUser, newPassword st
 
 }
 
+// SetSafePassword 设置安全密码，已经设置过时需要校验原安全密码
+func (userService *UserService) SetSafePassword(id uint, req sysReq.SetSafePasswordReq) (err error) {
+	var user system.SysUser
+	if err = global.DB.Where("id = ?", id).First(&user).Error; err != nil {
+		return errors.New("用户不存在")
+	}
+	if user.SafePassword != "" {
+		// 与余额支付共用错误次数锁定，防止通过修改安全密码接口无限次尝试
+		if err = common.CheckSafePassword(user.ID, req.SafePassword, user.SafePassword); err != nil {
+			if errors.Is(err, common.ErrSafePasswordLocked) {
+				return err
+			}
+			return errors.New("原" + err.Error())
+		}
+	}
+	return global.DB.Model(&user).Update("safe_password", utils.BcryptHash(req.NewSafePassword)).Error
+}
+
 //@author: [likfees](https://github.com/likfees)
 //@function: GetUserInfoList
 //@description: 分页获取数据
@@ -2,4 +2,5 @@ package wechat
 
 type ServiceGroup struct {
 	WechatService
+	RefundService
 }
@@ -0,0 +1,141 @@
+package wechat
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"fresh-shop/server/global"
+	"github.com/silenceper/wechat/v2/pay/notify"
+	"github.com/silenceper/wechat/v2/pay/refund"
+	"github.com/silenceper/wechat/v2/util"
+	"io"
+	"net/http"
+	"time"
+)
+
+// 微信支付 v2 申请退款接口
+const refundGateway = "https://api.mch.weixin.qq.com/secapi/pay/refund"
+
+type RefundService struct {
+}
+
+// refundRequest 申请退款请求参数
+type refundRequest struct {
+	XMLName     xml.Name `xml:"xml"`
+	AppID       string   `xml:"appid"`
+	MchID       string   `xml:"mch_id"`
+	NonceStr    string   `xml:"nonce_str"`
+	Sign        string   `xml:"sign"`
+	SignType    string   `xml:"sign_type,omitempty"`
+	OutTradeNo  string   `xml:"out_trade_no"`
+	OutRefundNo string   `xml:"out_refund_no"`
+	TotalFee    string   `xml:"total_fee"`
+	RefundFee   string   `xml:"refund_fee"`
+	RefundDesc  string   `xml:"refund_desc,omitempty"`
+	NotifyURL   string   `xml:"notify_url,omitempty"`
+}
+
+// Refund 申请微信支付退款，退款结果通过退款回调异步通知
+// orderSn 商户订单号 refundSn 商户退款单号 total 订单实付金额 amount 退款金额
+func Refund(orderSn, refundSn string, total, amount float64, reason string) error {
+	log := fmt.Sprintf("微信支付 - 申请退款 orderSn:%s, refundSn:%s, total:%.2f, amount:%.2f, ", orderSn, refundSn, total, amount)
+	cfg := global.Config.WechatPay
+	param := global.WxPay.GetRefund().GetSignParam(&refund.Params{
+		OutTradeNo:  orderSn,
+		OutRefundNo: refundSn,
+		TotalFee:    fmt.Sprintf("%.0f", total*100), // 单位为分
+		RefundFee:   fmt.Sprintf("%.0f", amount*100),
+		RefundDesc:  reason,
+		NotifyURL:   cfg.RefundNotifyURL,
+	})
+	sign, err := util.ParamSign(param, cfg.ApiV2Key)
+	if err != nil {
+		global.SugarLog.Errorf(log+"签名失败, err:%s", err.Error())
+		return err
+	}
+	req := refundRequest{
+		AppID:       param["appid"],
+		MchID:       param["mch_id"],
+		NonceStr:    param["nonce_str"],
+		Sign:        sign,
+		SignType:    param["sign_type"],
+		OutTradeNo:  param["out_trade_no"],
+		OutRefundNo: param["out_refund_no"],
+		TotalFee:    param["total_fee"],
+		RefundFee:   param["refund_fee"],
+		RefundDesc:  param["refund_desc"],
+		NotifyURL:   param["notify_url"],
+	}
+	raw, err := postXMLWithCert(refundGateway, req)
+	if err != nil {
+		global.SugarLog.Errorf(log+"请求失败, err:%s", err.Error())
+		return err
+	}
+	var rsp refund.Response
+	if err = xml.Unmarshal(raw, &rsp); err != nil {
+		global.SugarLog.Errorf(log+"解析响应失败, raw:%s, err:%s", string(raw), err.Error())
+		return err
+	}
+	if rsp.ReturnCode != "SUCCESS" {
+		global.SugarLog.Errorf(log+"通信失败, ReturnMsg:%s", rsp.ReturnMsg)
+		return errors.New(rsp.ReturnMsg)
+	}
+	if rsp.ResultCode != "SUCCESS" {
+		global.SugarLog.Errorf(log+"业务失败, ErrCode:%s, ErrCodeDes:%s", rsp.ErrCode, rsp.ErrCodeDes)
+		return errors.New(rsp.ErrCodeDes)
+	}
+	global.SugarLog.Infof(log+"申请成功, refundId:%s", rsp.RefundID)
+	return nil
+}
+
+// postXMLWithCert 使用商户证书发送 XML 请求
+func postXMLWithCert(url string, obj interface{}) ([]byte, error) {
+	cfg := global.Config.WechatPay
+	cert, err := tls.LoadX509KeyPair(cfg.CertPath, cfg.KeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("加载商户证书失败 certPath:%s, keyPath:%s, err:%v", cfg.CertPath, cfg.KeyPath, err)
+	}
+	body, err := xml.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+		},
+	}
+	resp, err := client.Post(url, "application/xml;charset=utf-8", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http code error: url=%s, statusCode=%d", url, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// DecryptRefundNotify 校验并解密退款结果通知
+// 退款结果使用 ApiV2Key 加密，能够解密即说明通知来自微信
+func (s *RefundService) DecryptRefundNotify(req *notify.RefundedResult) (*notify.RefundedReqInfo, error) {
+	if req.ReturnCode == nil || *req.ReturnCode != "SUCCESS" {
+		return nil, errors.New("退款通知返回失败")
+	}
+	if req.AppID == nil || *req.AppID != global.Config.Wechat.Appid {
+		return nil, errors.New("appid 不匹配")
+	}
+	if req.MchID == nil || *req.MchID != global.Config.WechatPay.MchId {
+		return nil, errors.New("mch_id 不匹配")
+	}
+	info, err := global.WxPay.GetNotify().DecryptReqInfo(req)
+	if err != nil {
+		return nil, err
+	}
+	if info.OutTradeNO == nil || info.OutRefundNO == nil || info.RefundStatus == nil {
+		return nil, errors.New("退款通知参数不完整")
+	}
+	return info, nil
+}
@@ -4,13 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"fresh-shop/server/global"
-	"fresh-shop/server/model/shop"
 	"fresh-shop/server/model/wechat/request"
-	"fresh-shop/server/utils"
 	"github.com/silenceper/wechat/v2/miniprogram/auth"
-	"github.com/silenceper/wechat/v2/pay/notify"
 	orderPay "github.com/silenceper/wechat/v2/pay/order"
-	"gorm.io/gorm"
 	"strconv"
 	"time"
 )
@@ -18,6 +14,9 @@ import (
 type WechatService struct {
 }
 
+// ErrOrderPaid 微信侧订单已支付
+var ErrOrderPaid = errors.New("订单已支付")
+
 func (s *WechatService) Code2SessionKey(session request.Jscode2SessionReq) (auth.ResCode2Session, error) {
 	a := global.MiniProgram.GetAuth()
 	code2Session, err := a.Code2Session(session.Jscode)
@@ -39,13 +38,9 @@ func JSAPIPay(openId, orderSn string, orderId uint, amount float64, createIp str
 		OutTradeNo: orderSn,
 		TotalFee:   fmt.Sprintf("%.0f", amount*100), // 订单总金额，单位为分，详见支付金额
 		CreateIP:   createIp,
-		TimeExpire: time.Now().Add(30 * time.Minute).Format("20060102150405"), // 30 分钟过期
-		TradeType:  "JSAPI",                                                   // 交易类型
-		Attach:     strconv.Itoa(int(orderId)),                                // 附加数据，在查询API和支付通知中原样返回，可作为自定义参数使用。
-	}
-	if global.Config.WechatPay.Debug {
-		param.Body = "测试支付"
-		param.TotalFee = "1"
+		TimeExpire: time.Now().Add(global.Config.Order.GetPayTimeout()).Format("20060102150405"), // 与订单超时取消时间保持一致
+		TradeType:  "JSAPI",                                                                      // 交易类型
+		Attach:     strconv.Itoa(int(orderId)),                                                   // 附加数据，在查询API和支付通知中原样返回，可作为自定义参数使用。
 	}
 	preOrder, err := order.BridgeConfig(param)
 	if err != nil {
@@ -56,49 +51,22 @@ func JSAPIPay(openId, orderSn string, orderId uint, amount float64, createIp str
 	return
 }
 
-// NotifyLogic 支付回调逻辑处理
-func (s *WechatService) NotifyLogic(req *notify.PaidResult) error {
-	orderSn := *req.OutTradeNo
-	log := fmt.Sprintf("订单支付回调逻辑: 订单号：%s, ", orderSn)
-	var order shop.Order
-	if errors.Is(global.DB.Where("order_sn = ?", orderSn).First(&order).Error, gorm.ErrRecordNotFound) {
-		global.SugarLog.Errorf(log + "订单不存在 \n")
-		return errors.New("订单不存在")
-	}
-	// 如果订单已经支付则直接结束
-	if *order.Status == 1 {
-		global.SugarLog.Errorf(log + "订单已支付 \n")
+// CloseOrder 关闭微信预支付订单，关闭后用户无法再对该订单发起支付
+// 订单已关闭或不存在视为成功，订单已支付返回 ErrOrderPaid
+func CloseOrder(orderSn string) error {
+	order := global.WxPay.GetOrder()
+	result, err := order.CloseOrder(&orderPay.CloseParams{OutTradeNo: orderSn})
+	if err == nil {
 		return nil
 	}
-	if *order.Status != 0 {
-		global.SugarLog.Errorf(log+"订单状态不正确, Status：%d \n", *order.Status)
-		return errors.New("订单状态不正确")
-	}
-	finishStr := fmt.Sprintf("%.2f", float64(*req.TotalFee)/100)
-	finish, err := strconv.ParseFloat(finishStr, 64)
-	if err != nil {
-		global.SugarLog.Errorf(log+"finishStr 转换 float64 失败, req.TotalFee:%s finishStr:%d \n", *req.TotalFee, finishStr)
-		return err
-	}
-	// req.TimeEnd 转换为 time.Time 类型
-	timeEnd, err := time.Parse("20060102150405", *req.TimeEnd)
-	if err != nil {
-		global.SugarLog.Errorf(log+"timeEnd 格式化时间失败, req.TimeEnd:%s \n", *req.TimeEnd)
-		return err
-	}
-	order.Finish = finish
-	order.Status = utils.Pointer(1)
-	order.PayTime = &timeEnd
-	order.PaymentOpenid = *req.OpenID
-	order.PaymentInfo = *req.Attach
-	order.TransationId = *req.TransactionID
-	if err := global.DB.Save(&order).Error; err != nil {
-		global.SugarLog.Errorf(log+"保存订单信息失败, err:%s \n", err.Error())
-		return err
+	if result.ErrCode != nil {
+		switch *result.ErrCode {
+		case "ORDERCLOSED", "ORDERNOTEXIST":
+			return nil
+		case "ORDERPAID":
+			return ErrOrderPaid
+		}
 	}
-
-	// 生成流水记录
-
-	global.SugarLog.Infof(log + "支付成功")
-	return nil
+	global.SugarLog.Errorf("微信支付 - 关闭订单发生错误 orderSn:%s, err:%s", orderSn, err.Error())
+	return err
 }
@@ -234,6 +234,15 @@ func (i *initCasbin) InitializeData(ctx context.Context) (context.Context, error
 		{Ptype: "p", V0: "9528", V1: "/customer/customerList", V2: "GET"},
 		{Ptype: "p", V0: "9528", V1: "/autoCode/createTemp", V2: "POST"},
 		{Ptype: "p", V0: "9528", V1: "/user/getUserInfo", V2: "GET"},
+
+		{Ptype: "p", V0: "2000", V1: "/jwt/jsonInBlacklist", V2: "POST"},
+		{Ptype: "p", V0: "2000", V1: "/user/getUserInfo", V2: "GET"},
+		{Ptype: "p", V0: "2000", V1: "/rider/getRiderOrderList", V2: "GET"},
+		{Ptype: "p", V0: "2000", V1: "/rider/pickUpOrder", V2: "PUT"},
+		{Ptype: "p", V0: "2000", V1: "/rider/deliverOrder", V2: "PUT"},
+		{Ptype: "p", V0: "2000", V1: "/rider/pickUpReturn", V2: "PUT"},
+		{Ptype: "p", V0: "2000", V1: "/rider/deliverReturn", V2: "PUT"},
+		{Ptype: "p", V0: "2000", V1: "/rider/uploadProof", V2: "POST"},
 	}
 	if err := db.Create(&entities).Error; err != nil {
 		return ctx, errors.Wrap(err, "Casbin 表 ("+i.InitializerName()+") 数据初始化失败!")
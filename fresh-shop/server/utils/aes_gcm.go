@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+)
+
+// AesGcmDecrypt AEAD_AES_256_GCM 解密，ciphertext 为 base64 编码的密文(含 16 字节认证标签)
+// 微信支付 APIv3 的回调通知和平台证书使用 APIv3 密钥以此方式加密
+func AesGcmDecrypt(key, nonce, associatedData, ciphertext string) ([]byte, error) {
+	if len(key) != 32 {
+		return nil, errors.New("密钥长度必须为 32 字节")
+	}
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(nonce))
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, []byte(nonce), data, []byte(associatedData))
+}
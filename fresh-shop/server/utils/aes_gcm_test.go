@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"testing"
+)
+
+func TestAesGcmDecrypt(t *testing.T) {
+	key := "0123456789abcdef0123456789abcdef"
+	nonce := "abcdefghijkl"
+	plain := `{"out_trade_no":"SN001","trade_state":"SUCCESS"}`
+	block, _ := aes.NewCipher([]byte(key))
+	gcm, _ := cipher.NewGCM(block)
+	ciphertext := base64.StdEncoding.EncodeToString(gcm.Seal(nil, []byte(nonce), []byte(plain), []byte("transaction")))
+
+	got, err := AesGcmDecrypt(key, nonce, "transaction", ciphertext)
+	if err != nil || string(got) != plain {
+		t.Errorf("AesGcmDecrypt() = %s, %v; want %s", got, err, plain)
+	}
+	if _, err = AesGcmDecrypt(key, nonce, "certificate", ciphertext); err == nil {
+		t.Error("附加数据不一致时应解密失败")
+	}
+	if _, err = AesGcmDecrypt("short", nonce, "transaction", ciphertext); err == nil {
+		t.Error("密钥长度错误时应解密失败")
+	}
+}
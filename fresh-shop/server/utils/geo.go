@@ -0,0 +1,32 @@
+package utils
+
+import "math"
+
+// earthRadius 地球平均半径，单位米
+const earthRadius = 6371000.0
+
+// Distance 计算两个经纬度坐标之间的球面距离(Haversine 公式)，单位米
+func Distance(lng1, lat1, lng2, lat2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// InPolygon 判断坐标是否在多边形内(射线法)，polygon 为按顺序排列的 [经度, 纬度] 顶点
+func InPolygon(lng, lat float64, polygon [][2]float64) bool {
+	if len(polygon) < 3 {
+		return false
+	}
+	in := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		xi, yi := polygon[i][0], polygon[i][1]
+		xj, yj := polygon[j][0], polygon[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			in = !in
+		}
+	}
+	return in
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	// 天安门 -> 北京西站 约 6.6km
+	d := Distance(116.397477, 39.908692, 116.322056, 39.89491)
+	if math.Abs(d-6600) > 500 {
+		t.Errorf("Distance = %.0f, 期望约 6600 米", d)
+	}
+	if d := Distance(116.397477, 39.908692, 116.397477, 39.908692); d != 0 {
+		t.Errorf("相同坐标距离应为 0, 实际 %.2f", d)
+	}
+}
+
+func TestInPolygon(t *testing.T) {
+	square := [][2]float64{{116.0, 39.0}, {117.0, 39.0}, {117.0, 40.0}, {116.0, 40.0}}
+	cases := []struct {
+		lng, lat float64
+		want     bool
+	}{
+		{116.5, 39.5, true},
+		{117.5, 39.5, false},
+		{116.5, 40.5, false},
+		{115.9, 39.1, false},
+	}
+	for _, c := range cases {
+		if got := InPolygon(c.lng, c.lat, square); got != c.want {
+			t.Errorf("InPolygon(%v, %v) = %v, 期望 %v", c.lng, c.lat, got, c.want)
+		}
+	}
+	if InPolygon(116.5, 39.5, square[:2]) {
+		t.Error("少于 3 个顶点不能构成多边形")
+	}
+}
@@ -0,0 +1,18 @@
+package utils
+
+import "math"
+
+// PointOffset 计算积分抵扣，ratio 为多少积分抵扣 1 元，maxPercent 为最多可抵扣 goodsAmount 的百分比
+// 抵扣金额向下取整到分，返回实际使用的积分数量和抵扣金额
+func PointOffset(goodsAmount, points, ratio, maxPercent float64) (usePoints, amount float64) {
+	if goodsAmount <= 0 || points <= 0 || ratio <= 0 || maxPercent <= 0 {
+		return 0, 0
+	}
+	amount = math.Floor(points/ratio*100+1e-9) / 100
+	limit := math.Floor(goodsAmount*math.Min(maxPercent, 100)+1e-9) / 100
+	if amount > limit {
+		amount = limit
+	}
+	usePoints = math.Round(amount*ratio*100) / 100
+	return usePoints, amount
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPointOffset(t *testing.T) {
+	tests := []struct {
+		name                            string
+		goodsAmount, points, ratio, max float64
+		wantPoints, wantAmount          float64
+	}{
+		{"积分足够时按比例抵扣", 100, 500, 100, 50, 500, 5},
+		{"超过最高抵扣比例时截断", 20, 5000, 100, 30, 600, 6},
+		{"不足一分的积分不使用", 100, 150.5, 100, 50, 150, 1.5},
+		{"比例超过 100 按 100 计算", 10, 5000, 100, 200, 1000, 10},
+		{"未开启抵扣", 100, 500, 0, 50, 0, 0},
+		{"未使用积分", 100, 0, 100, 50, 0, 0},
+	}
+	for _, tt := range tests {
+		points, amount := PointOffset(tt.goodsAmount, tt.points, tt.ratio, tt.max)
+		if math.Abs(points-tt.wantPoints) > 1e-9 || math.Abs(amount-tt.wantAmount) > 1e-9 {
+			t.Errorf("%s: PointOffset = (%v, %v), 期望 (%v, %v)", tt.name, points, amount, tt.wantPoints, tt.wantAmount)
+		}
+	}
+}
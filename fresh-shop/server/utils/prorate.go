@@ -0,0 +1,30 @@
+package utils
+
+import "math"
+
+// ProRate 将 total 按 weights 的比例分摊，结果保留两位小数，舍入误差计入最后一个权重不为 0 的项
+// 权重之和为 0 时全部返回 0
+func ProRate(weights []float64, total float64) []float64 {
+	shares := make([]float64, len(weights))
+	sum := 0.0
+	last := -1
+	for i, w := range weights {
+		if w > 0 {
+			sum += w
+			last = i
+		}
+	}
+	if sum == 0 {
+		return shares
+	}
+	allocated := 0.0
+	for i, w := range weights {
+		if w <= 0 || i == last {
+			continue
+		}
+		shares[i] = math.Round(total*w/sum*100) / 100
+		allocated += shares[i]
+	}
+	shares[last] = math.Round((total-allocated)*100) / 100
+	return shares
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestProRate(t *testing.T) {
+	shares := ProRate([]float64{10, 20, 0, 30}, 10)
+	want := []float64{1.67, 3.33, 0, 5}
+	sum := 0.0
+	for i := range shares {
+		if math.Abs(shares[i]-want[i]) > 1e-9 {
+			t.Errorf("ProRate[%d] = %v, 期望 %v", i, shares[i], want[i])
+		}
+		sum += shares[i]
+	}
+	if math.Abs(sum-10) > 1e-9 {
+		t.Errorf("分摊合计 = %v, 期望 10", sum)
+	}
+	// 舍入误差计入最后一个权重不为 0 的项
+	shares = ProRate([]float64{1, 1, 1, 0}, 0.1)
+	if math.Abs(shares[0]+shares[1]+shares[2]-0.1) > 1e-9 || shares[3] != 0 {
+		t.Errorf("ProRate 分摊结果错误 %v", shares)
+	}
+	if shares := ProRate([]float64{0, 0}, 5); shares[0] != 0 || shares[1] != 0 {
+		t.Errorf("权重全为 0 时应全部返回 0, 实际 %v", shares)
+	}
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"math"
+	"sort"
+)
+
+// RoutePoint 路线坐标点
+type RoutePoint struct {
+	Lng float64
+	Lat float64
+}
+
+func pointDistance(a, b RoutePoint) float64 {
+	return Distance(a.Lng, a.Lat, b.Lng, b.Lat)
+}
+
+// PlanRoute 规划从起点出发依次经过所有坐标点的路线(不返回起点)，返回 points 的下标顺序
+// 先用最近邻算法生成初始路线，再用 2-opt 反转路段消除交叉，结果为近似最优解
+func PlanRoute(start RoutePoint, points []RoutePoint) []int {
+	n := len(points)
+	if n == 0 {
+		return nil
+	}
+	// 最近邻
+	route := make([]int, 0, n)
+	visited := make([]bool, n)
+	cur := start
+	for len(route) < n {
+		best, bestDist := -1, 0.0
+		for i, p := range points {
+			if visited[i] {
+				continue
+			}
+			if d := pointDistance(cur, p); best < 0 || d < bestDist {
+				best, bestDist = i, d
+			}
+		}
+		visited[best] = true
+		route = append(route, best)
+		cur = points[best]
+	}
+	// 2-opt 反转 route[i..k] 后路线变短则保留，直到没有可改进的路段
+	for pass := 0; pass < 100; pass++ {
+		improved := false
+		for i := 0; i < n-1; i++ {
+			prev := start
+			if i > 0 {
+				prev = points[route[i-1]]
+			}
+			for k := i + 1; k < n; k++ {
+				a, b := points[route[i]], points[route[k]]
+				before := pointDistance(prev, a)
+				after := pointDistance(prev, b)
+				if k+1 < n {
+					next := points[route[k+1]]
+					before += pointDistance(b, next)
+					after += pointDistance(a, next)
+				}
+				if after < before-1e-6 {
+					for l, r := i, k; l < r; l, r = l+1, r-1 {
+						route[l], route[r] = route[r], route[l]
+					}
+					improved = true
+				}
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+	return route
+}
+
+// RouteDistance 计算从起点出发按 route 顺序经过坐标点的总距离，单位米
+func RouteDistance(start RoutePoint, points []RoutePoint, route []int) float64 {
+	total := 0.0
+	cur := start
+	for _, i := range route {
+		total += pointDistance(cur, points[i])
+		cur = points[i]
+	}
+	return total
+}
+
+// SweepPartition 以 center 为中心按方位角扫描，将坐标点尽量平均地分成 n 组，返回每组 points 的下标
+// 从相邻两点方位角间隔最大处开始扫描，避免同一方向的点被分到首尾两组
+func SweepPartition(center RoutePoint, points []RoutePoint, n int) [][]int {
+	if n <= 0 {
+		return nil
+	}
+	groups := make([][]int, n)
+	if len(points) == 0 {
+		return groups
+	}
+	angles := make([]float64, len(points))
+	idx := make([]int, len(points))
+	for i, p := range points {
+		angles[i] = math.Atan2(p.Lat-center.Lat, p.Lng-center.Lng)
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return angles[idx[a]] < angles[idx[b]] })
+	// 找到最大的方位角间隔，从间隔之后的点开始
+	startAt, maxGap := 0, -1.0
+	for i := range idx {
+		prev := idx[(i-1+len(idx))%len(idx)]
+		gap := angles[idx[i]] - angles[prev]
+		if gap < 0 || i == 0 {
+			gap += 2 * math.Pi
+		}
+		if gap > maxGap {
+			startAt, maxGap = i, gap
+		}
+	}
+	idx = append(idx[startAt:], idx[:startAt]...)
+	// 前 len%n 组多分一个点
+	size, extra := len(idx)/n, len(idx)%n
+	pos := 0
+	for g := 0; g < n; g++ {
+		end := pos + size
+		if g < extra {
+			end++
+		}
+		groups[g] = append([]int{}, idx[pos:end]...)
+		pos = end
+	}
+	return groups
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanRoute(t *testing.T) {
+	start := RoutePoint{Lng: 116.0, Lat: 39.0}
+	// 同一条线上的点应按由近到远的顺序访问
+	line := []RoutePoint{{116.03, 39.0}, {116.01, 39.0}, {116.04, 39.0}, {116.02, 39.0}}
+	if got, want := PlanRoute(start, line), []int{1, 3, 0, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PlanRoute = %v, 期望 %v", got, want)
+	}
+	if got := PlanRoute(start, nil); got != nil {
+		t.Errorf("没有坐标点时应返回 nil, 实际 %v", got)
+	}
+
+	// 2-opt 后的路线不应比最近邻更长，且每个点只访问一次
+	points := []RoutePoint{
+		{116.01, 39.00}, {116.02, 39.02}, {116.00, 39.03}, {116.03, 39.01},
+		{116.05, 39.04}, {116.01, 39.05}, {116.04, 39.00}, {116.02, 39.04},
+	}
+	route := PlanRoute(start, points)
+	seen := make(map[int]bool)
+	for _, i := range route {
+		seen[i] = true
+	}
+	if len(route) != len(points) || len(seen) != len(points) {
+		t.Fatalf("路线应包含全部坐标点各一次, 实际 %v", route)
+	}
+	for i := 0; i < len(route)-1; i++ {
+		for k := i + 1; k < len(route); k++ {
+			swapped := append([]int{}, route...)
+			for l, r := i, k; l < r; l, r = l+1, r-1 {
+				swapped[l], swapped[r] = swapped[r], swapped[l]
+			}
+			if RouteDistance(start, points, swapped) < RouteDistance(start, points, route)-1e-6 {
+				t.Fatalf("路线 %v 仍可通过反转 [%d,%d] 缩短", route, i, k)
+			}
+		}
+	}
+}
+
+func TestSweepPartition(t *testing.T) {
+	center := RoutePoint{Lng: 116.0, Lat: 39.0}
+	// 东西两侧各 3 个点，分成 2 组时同侧的点应在同一组
+	points := []RoutePoint{
+		{116.02, 39.001}, {115.98, 39.0}, {116.02, 38.999},
+		{115.98, 39.001}, {116.02, 39.0}, {115.98, 38.999},
+	}
+	groups := SweepPartition(center, points, 2)
+	if len(groups) != 2 || len(groups[0]) != 3 || len(groups[1]) != 3 {
+		t.Fatalf("SweepPartition 分组数量错误 %v", groups)
+	}
+	for _, g := range groups {
+		east := points[g[0]].Lng > center.Lng
+		for _, i := range g {
+			if (points[i].Lng > center.Lng) != east {
+				t.Errorf("分组 %v 同时包含东西两侧的点", g)
+			}
+		}
+	}
+
+	// 点数不能整除时前面的组多分一个，点数少于组数时多出的组为空
+	if groups := SweepPartition(center, points[:5], 2); len(groups[0]) != 3 || len(groups[1]) != 2 {
+		t.Errorf("5 个点分 2 组应为 3,2, 实际 %v", groups)
+	}
+	if groups := SweepPartition(center, points[:1], 3); len(groups[0]) != 1 || len(groups[1]) != 0 || len(groups[2]) != 0 {
+		t.Errorf("1 个点分 3 组应为 1,0,0, 实际 %v", groups)
+	}
+}
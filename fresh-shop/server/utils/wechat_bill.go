@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// WechatBillRecord 微信支付交易账单中的一条明细
+type WechatBillRecord struct {
+	TradeTime     string  // 交易时间
+	TransactionId string  // 微信订单号
+	OrderSn       string  // 商户订单号
+	TradeState    string  // 交易状态 SUCCESS 支付成功 REFUND 退款 REVOKED 已撤销
+	Amount        float64 // 订单金额
+	RefundSn      string  // 商户退款单号
+	RefundAmount  float64 // 退款金额
+	RefundStatus  string  // 退款状态
+}
+
+// ParseWechatBill 解析微信支付交易账单(bill_type=ALL)，APIv2 和 APIv3 下载的账单格式相同
+// 第一行为表头，字段值以 ` 开头，明细之后是以"总交易单数"开头的汇总行
+func ParseWechatBill(data []byte) ([]WechatBillRecord, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("账单内容为空")
+	}
+	index := make(map[string]int, len(rows[0]))
+	for i, name := range rows[0] {
+		index[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"交易时间", "微信订单号", "商户订单号", "交易状态", "订单金额"} {
+		if _, ok := index[name]; !ok {
+			return nil, errors.New("账单缺少字段 " + name)
+		}
+	}
+	field := func(row []string, name string) string {
+		i, ok := index[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimPrefix(strings.TrimSpace(row[i]), "`")
+	}
+	amount := func(row []string, name string) float64 {
+		v, _ := strconv.ParseFloat(field(row, name), 64)
+		return v
+	}
+	var records []WechatBillRecord
+	for _, row := range rows[1:] {
+		if len(row) == 0 || strings.HasPrefix(strings.TrimSpace(row[0]), "总交易单数") {
+			break
+		}
+		records = append(records, WechatBillRecord{
+			TradeTime:     field(row, "交易时间"),
+			TransactionId: field(row, "微信订单号"),
+			OrderSn:       field(row, "商户订单号"),
+			TradeState:    field(row, "交易状态"),
+			Amount:        amount(row, "订单金额"),
+			RefundSn:      field(row, "商户退款单号"),
+			RefundAmount:  amount(row, "退款金额"),
+			RefundStatus:  field(row, "退款状态"),
+		})
+	}
+	return records, nil
+}
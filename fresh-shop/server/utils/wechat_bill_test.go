@@ -0,0 +1,27 @@
+package utils
+
+import "testing"
+
+func TestParseWechatBill(t *testing.T) {
+	bill := "\xef\xbb\xbf交易时间,公众账号ID,商户号,微信订单号,商户订单号,用户标识,交易类型,交易状态,应结订单金额,商户退款单号,退款金额,退款状态,订单金额\r\n" +
+		"`2024-01-02 10:00:00,`wx01,`m1,`T001,`SN001,`o1,`JSAPI,`SUCCESS,`12.34,`0,`0.00,`,`12.34\r\n" +
+		"`2024-01-02 11:00:00,`wx01,`m1,`T002,`SN002,`o2,`JSAPI,`REFUND,`0.00,`RF002,`5.00,`SUCCESS,`20.00\r\n" +
+		"总交易单数,应结订单总金额,退款总金额\r\n" +
+		"`2,`12.34,`5.00\r\n"
+	records, err := ParseWechatBill([]byte(bill))
+	if err != nil {
+		t.Fatalf("ParseWechatBill() err = %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("ParseWechatBill() got %d records, want 2", len(records))
+	}
+	if r := records[0]; r.OrderSn != "SN001" || r.TransactionId != "T001" || r.TradeState != "SUCCESS" || r.Amount != 12.34 {
+		t.Errorf("records[0] = %+v", r)
+	}
+	if r := records[1]; r.TradeState != "REFUND" || r.RefundSn != "RF002" || r.RefundAmount != 5 || r.RefundStatus != "SUCCESS" {
+		t.Errorf("records[1] = %+v", r)
+	}
+	if _, err = ParseWechatBill([]byte("a,b\r\n1,2\r\n")); err == nil {
+		t.Error("缺少字段时应返回错误")
+	}
+}
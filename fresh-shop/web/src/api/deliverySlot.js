@@ -0,0 +1,113 @@
+import service from '@/utils/request'
+
+// @Tags DeliverySlot
+// @Summary 创建DeliverySlot
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body model.DeliverySlot true "创建DeliverySlot"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /deliverySlot/createDeliverySlot [post]
+export const createDeliverySlot = (data) => {
+  return service({
+    url: '/deliverySlot/createDeliverySlot',
+    method: 'post',
+    data
+  })
+}
+
+// @Tags DeliverySlot
+// @Summary 删除DeliverySlot
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body model.DeliverySlot true "删除DeliverySlot"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"删除成功"}"
+// @Router /deliverySlot/deleteDeliverySlot [delete]
+export const deleteDeliverySlot = (data) => {
+  return service({
+    url: '/deliverySlot/deleteDeliverySlot',
+    method: 'delete',
+    data
+  })
+}
+
+// @Tags DeliverySlot
+// @Summary 删除DeliverySlot
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.IdsReq true "批量删除DeliverySlot"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"删除成功"}"
+// @Router /deliverySlot/deleteDeliverySlot [delete]
+export const deleteDeliverySlotByIds = (data) => {
+  return service({
+    url: '/deliverySlot/deleteDeliverySlotByIds',
+    method: 'delete',
+    data
+  })
+}
+
+// @Tags DeliverySlot
+// @Summary 更新DeliverySlot
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body model.DeliverySlot true "更新DeliverySlot"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"更新成功"}"
+// @Router /deliverySlot/updateDeliverySlot [put]
+export const updateDeliverySlot = (data) => {
+  return service({
+    url: '/deliverySlot/updateDeliverySlot',
+    method: 'put',
+    data
+  })
+}
+
+// @Tags DeliverySlot
+// @Summary 用id查询DeliverySlot
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query model.DeliverySlot true "用id查询DeliverySlot"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"查询成功"}"
+// @Router /deliverySlot/findDeliverySlot [get]
+export const findDeliverySlot = (params) => {
+  return service({
+    url: '/deliverySlot/findDeliverySlot',
+    method: 'get',
+    params
+  })
+}
+
+// @Tags DeliverySlot
+// @Summary 分页获取DeliverySlot列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query request.PageInfo true "分页获取DeliverySlot列表"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /deliverySlot/getDeliverySlotList [get]
+export const getDeliverySlotList = (params) => {
+  return service({
+    url: '/deliverySlot/getDeliverySlotList',
+    method: 'get',
+    params
+  })
+}
+
+// @Tags DeliverySlot
+// @Summary 获取收货地址可预约的配送时段
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query request.AvailableDeliverySlotReq true "获取收货地址可预约的配送时段"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /deliverySlot/getAvailableDeliverySlots [get]
+export const getAvailableDeliverySlots = (params) => {
+  return service({
+    url: '/deliverySlot/getAvailableDeliverySlots',
+    method: 'get',
+    params
+  })
+}
@@ -0,0 +1,113 @@
+import service from '@/utils/request'
+
+// @Tags DeliveryZone
+// @Summary 创建DeliveryZone
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body model.DeliveryZone true "创建DeliveryZone"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /deliveryZone/createDeliveryZone [post]
+export const createDeliveryZone = (data) => {
+  return service({
+    url: '/deliveryZone/createDeliveryZone',
+    method: 'post',
+    data
+  })
+}
+
+// @Tags DeliveryZone
+// @Summary 删除DeliveryZone
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body model.DeliveryZone true "删除DeliveryZone"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"删除成功"}"
+// @Router /deliveryZone/deleteDeliveryZone [delete]
+export const deleteDeliveryZone = (data) => {
+  return service({
+    url: '/deliveryZone/deleteDeliveryZone',
+    method: 'delete',
+    data
+  })
+}
+
+// @Tags DeliveryZone
+// @Summary 删除DeliveryZone
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.IdsReq true "批量删除DeliveryZone"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"删除成功"}"
+// @Router /deliveryZone/deleteDeliveryZone [delete]
+export const deleteDeliveryZoneByIds = (data) => {
+  return service({
+    url: '/deliveryZone/deleteDeliveryZoneByIds',
+    method: 'delete',
+    data
+  })
+}
+
+// @Tags DeliveryZone
+// @Summary 更新DeliveryZone
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body model.DeliveryZone true "更新DeliveryZone"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"更新成功"}"
+// @Router /deliveryZone/updateDeliveryZone [put]
+export const updateDeliveryZone = (data) => {
+  return service({
+    url: '/deliveryZone/updateDeliveryZone',
+    method: 'put',
+    data
+  })
+}
+
+// @Tags DeliveryZone
+// @Summary 用id查询DeliveryZone
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query model.DeliveryZone true "用id查询DeliveryZone"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"查询成功"}"
+// @Router /deliveryZone/findDeliveryZone [get]
+export const findDeliveryZone = (params) => {
+  return service({
+    url: '/deliveryZone/findDeliveryZone',
+    method: 'get',
+    params
+  })
+}
+
+// @Tags DeliveryZone
+// @Summary 分页获取DeliveryZone列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query request.PageInfo true "分页获取DeliveryZone列表"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /deliveryZone/getDeliveryZoneList [get]
+export const getDeliveryZoneList = (params) => {
+  return service({
+    url: '/deliveryZone/getDeliveryZoneList',
+    method: 'get',
+    params
+  })
+}
+
+// @Tags DeliveryZone
+// @Summary 根据坐标查询所在配送区域
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query request.DeliveryZoneLocation true "根据坐标查询所在配送区域"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"查询成功"}"
+// @Router /deliveryZone/findDeliveryZoneByLocation [get]
+export const findDeliveryZoneByLocation = (params) => {
+  return service({
+    url: '/deliveryZone/findDeliveryZoneByLocation',
+    method: 'get',
+    params
+  })
+}
@@ -0,0 +1,97 @@
+import service from '@/utils/request'
+
+// @Tags FlashSale
+// @Summary 创建FlashSale
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body model.FlashSale true "创建FlashSale"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /flashSale/createFlashSale [post]
+export const createFlashSale = (data) => {
+  return service({
+    url: '/flashSale/createFlashSale',
+    method: 'post',
+    data
+  })
+}
+
+// @Tags FlashSale
+// @Summary 删除FlashSale
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body model.FlashSale true "删除FlashSale"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"删除成功"}"
+// @Router /flashSale/deleteFlashSale [delete]
+export const deleteFlashSale = (data) => {
+  return service({
+    url: '/flashSale/deleteFlashSale',
+    method: 'delete',
+    data
+  })
+}
+
+// @Tags FlashSale
+// @Summary 删除FlashSale
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.IdsReq true "批量删除FlashSale"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"删除成功"}"
+// @Router /flashSale/deleteFlashSale [delete]
+export const deleteFlashSaleByIds = (data) => {
+  return service({
+    url: '/flashSale/deleteFlashSaleByIds',
+    method: 'delete',
+    data
+  })
+}
+
+// @Tags FlashSale
+// @Summary 更新FlashSale
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body model.FlashSale true "更新FlashSale"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"更新成功"}"
+// @Router /flashSale/updateFlashSale [put]
+export const updateFlashSale = (data) => {
+  return service({
+    url: '/flashSale/updateFlashSale',
+    method: 'put',
+    data
+  })
+}
+
+// @Tags FlashSale
+// @Summary 用id查询FlashSale
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query model.FlashSale true "用id查询FlashSale"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"查询成功"}"
+// @Router /flashSale/findFlashSale [get]
+export const findFlashSale = (params) => {
+  return service({
+    url: '/flashSale/findFlashSale',
+    method: 'get',
+    params
+  })
+}
+
+// @Tags FlashSale
+// @Summary 分页获取FlashSale列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query request.PageInfo true "分页获取FlashSale列表"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /flashSale/getFlashSaleList [get]
+export const getFlashSaleList = (params) => {
+  return service({
+    url: '/flashSale/getFlashSaleList',
+    method: 'get',
+    params
+  })
+}
@@ -0,0 +1,113 @@
+import service from '@/utils/request'
+
+// @Tags GroupBuy
+// @Summary 创建GroupBuy
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body model.GroupBuy true "创建GroupBuy"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /groupBuy/createGroupBuy [post]
+export const createGroupBuy = (data) => {
+  return service({
+    url: '/groupBuy/createGroupBuy',
+    method: 'post',
+    data
+  })
+}
+
+// @Tags GroupBuy
+// @Summary 删除GroupBuy
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body model.GroupBuy true "删除GroupBuy"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"删除成功"}"
+// @Router /groupBuy/deleteGroupBuy [delete]
+export const deleteGroupBuy = (data) => {
+  return service({
+    url: '/groupBuy/deleteGroupBuy',
+    method: 'delete',
+    data
+  })
+}
+
+// @Tags GroupBuy
+// @Summary 删除GroupBuy
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.IdsReq true "批量删除GroupBuy"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"删除成功"}"
+// @Router /groupBuy/deleteGroupBuy [delete]
+export const deleteGroupBuyByIds = (data) => {
+  return service({
+    url: '/groupBuy/deleteGroupBuyByIds',
+    method: 'delete',
+    data
+  })
+}
+
+// @Tags GroupBuy
+// @Summary 更新GroupBuy
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body model.GroupBuy true "更新GroupBuy"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"更新成功"}"
+// @Router /groupBuy/updateGroupBuy [put]
+export const updateGroupBuy = (data) => {
+  return service({
+    url: '/groupBuy/updateGroupBuy',
+    method: 'put',
+    data
+  })
+}
+
+// @Tags GroupBuy
+// @Summary 用id查询GroupBuy
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query model.GroupBuy true "用id查询GroupBuy"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"查询成功"}"
+// @Router /groupBuy/findGroupBuy [get]
+export const findGroupBuy = (params) => {
+  return service({
+    url: '/groupBuy/findGroupBuy',
+    method: 'get',
+    params
+  })
+}
+
+// @Tags GroupBuy
+// @Summary 分页获取GroupBuy列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query request.PageInfo true "分页获取GroupBuy列表"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /groupBuy/getGroupBuyList [get]
+export const getGroupBuyList = (params) => {
+  return service({
+    url: '/groupBuy/getGroupBuyList',
+    method: 'get',
+    params
+  })
+}
+
+// @Tags GroupBuy
+// @Summary 分页获取拼团团队及团员订单
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query request.GroupBuyTeamSearch true "分页获取拼团团队"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /groupBuy/getGroupBuyTeamList [get]
+export const getGroupBuyTeamList = (params) => {
+  return service({
+    url: '/groupBuy/getGroupBuyTeamList',
+    method: 'get',
+    params
+  })
+}
@@ -95,3 +95,35 @@ export const getOrderList = (params) => {
     params
   })
 }
+
+// @Tags Order
+// @Summary 获取订单时间线
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query model.Order true "获取订单时间线"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"查询成功"}"
+// @Router /order/getOrderTimeline [get]
+export const getOrderTimeline = (params) => {
+  return service({
+    url: '/order/getOrderTimeline',
+    method: 'get',
+    params
+  })
+}
+
+// @Tags Order
+// @Summary 向支付渠道查询待支付订单的支付结果
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body model.Order true "订单ID"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"查询成功"}"
+// @Router /order/syncPayment [post]
+export const syncOrderPayment = (data) => {
+  return service({
+    url: '/order/syncPayment',
+    method: 'post',
+    data
+  })
+}
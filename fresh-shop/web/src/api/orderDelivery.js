@@ -95,3 +95,19 @@ export const getOrderDeliveryList = (params) => {
     params
   })
 }
+
+// @Tags OrderDelivery
+// @Summary 批量派单 按配送路线为多个配送员发货
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.BatchDispatchReq true "订单id, 配送员id, 出发时间"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"派单成功"}"
+// @Router /orderDelivery/batchDispatch [post]
+export const batchDispatch = (data) => {
+  return service({
+    url: '/orderDelivery/batchDispatch',
+    method: 'post',
+    data
+  })
+}
@@ -1,12 +1,12 @@
 import service from '@/utils/request'
 
 // @Tags OrderReturn
-// @Summary 创建OrderReturn
+// @Summary 申请售后，type 0退货退款 1换货，details 为订单商品id和售后数量，pickUp 是否上门取件
 // @Security ApiKeyAuth
 // @accept application/json
 // @Produce application/json
-// @Param data body model.OrderReturn true "创建OrderReturn"
-// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Param data body shopReq.OrderReturnApply true "申请售后"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"申请成功"}"
 // @Router /orderReturn/createOrderReturn [post]
 export const createOrderReturn = (data) => {
   return service({
@@ -49,14 +49,15 @@ export const deleteOrderReturnByIds = (data) => {
 }
 
 // @Tags OrderReturn
-// @Summary 更新OrderReturn
+// @Summary 审核售后，status 1 同意 -1 拒绝，换货同意后生成补发订单，上门取件需要指定 riderId
 // @Security ApiKeyAuth
 // @accept application/json
 // @Produce application/json
-// @Param data body model.OrderReturn true "更新OrderReturn"
-// @Success 200 {string} string "{"success":true,"data":{},"msg":"更新成功"}"
+// @Param data body shopReq.OrderReturnReview true "审核售后"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"操作成功"}"
 // @Router /orderReturn/updateOrderReturn [put]
-export const updateOrderReturn = (data) => {
+// 沿用原更新接口地址，已部署环境的角色权限中只授权了该地址
+export const reviewOrderReturn = (data) => {
   return service({
     url: '/orderReturn/updateOrderReturn',
     method: 'put',
@@ -95,3 +96,19 @@ export const getOrderReturnList = (params) => {
     params
   })
 }
+
+// @Tags OrderReturn
+// @Summary 用户分页获取自己的售后列表，包含售后商品和上门取件进度
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query shopReq.OrderReturnSearch true "用户分页获取自己的售后列表"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /orderReturn/getUserOrderReturnList [get]
+export const getUserOrderReturnList = (params) => {
+  return service({
+    url: '/orderReturn/getUserOrderReturnList',
+    method: 'get',
+    params
+  })
+}
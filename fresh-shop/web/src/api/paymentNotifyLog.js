@@ -0,0 +1,33 @@
+import service from '@/utils/request'
+
+// @Tags PaymentNotifyLog
+// @Summary 分页获取支付通知记录
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query request.PaymentNotifyLogSearch true "分页获取支付通知记录"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /paymentNotifyLog/getPaymentNotifyLogList [get]
+export const getPaymentNotifyLogList = (params) => {
+  return service({
+    url: '/paymentNotifyLog/getPaymentNotifyLogList',
+    method: 'get',
+    params
+  })
+}
+
+// @Tags PaymentNotifyLog
+// @Summary 标记异常通知已人工处理
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.HandleNotifyLogReq true "通知记录ID和处理说明"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"处理成功"}"
+// @Router /paymentNotifyLog/handlePaymentNotifyLog [put]
+export const handlePaymentNotifyLog = (data) => {
+  return service({
+    url: '/paymentNotifyLog/handlePaymentNotifyLog',
+    method: 'put',
+    data
+  })
+}
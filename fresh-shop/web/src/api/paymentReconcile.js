@@ -0,0 +1,66 @@
+import service from '@/utils/request'
+
+// @Tags PaymentReconcile
+// @Summary 对账指定日期的微信支付账单，上传账单文件时使用文件内容，否则从微信支付下载
+// @Security ApiKeyAuth
+// @accept multipart/form-data
+// @Produce application/json
+// @Param billDate formData string true "账单日期 2006-01-02"
+// @Param file formData file false "微信支付交易账单 CSV"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"对账完成"}"
+// @Router /paymentReconcile/reconcile [post]
+export const reconcile = (data) => {
+  return service({
+    url: '/paymentReconcile/reconcile',
+    method: 'post',
+    data
+  })
+}
+
+// @Tags PaymentReconcile
+// @Summary 分页获取对账记录
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query request.PaymentReconcileSearch true "分页获取对账记录"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /paymentReconcile/getPaymentReconcileList [get]
+export const getPaymentReconcileList = (params) => {
+  return service({
+    url: '/paymentReconcile/getPaymentReconcileList',
+    method: 'get',
+    params
+  })
+}
+
+// @Tags PaymentReconcile
+// @Summary 分页获取对账差异明细
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query request.PaymentReconcileDiffSearch true "分页获取对账差异明细"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /paymentReconcile/getPaymentReconcileDiffList [get]
+export const getPaymentReconcileDiffList = (params) => {
+  return service({
+    url: '/paymentReconcile/getPaymentReconcileDiffList',
+    method: 'get',
+    params
+  })
+}
+
+// @Tags PaymentReconcile
+// @Summary 标记对账差异已处理
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.GetById true "差异记录ID"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"处理成功"}"
+// @Router /paymentReconcile/handlePaymentReconcileDiff [put]
+export const handlePaymentReconcileDiff = (data) => {
+  return service({
+    url: '/paymentReconcile/handlePaymentReconcileDiff',
+    method: 'put',
+    data
+  })
+}
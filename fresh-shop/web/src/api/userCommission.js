@@ -0,0 +1,17 @@
+import service from '@/utils/request'
+
+// @Tags UserCommission
+// @Summary 分页获取分销佣金记录
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query request.UserCommissionSearch true "分页获取分销佣金记录"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /userCommission/getUserCommissionList [get]
+export const getUserCommissionList = (params) => {
+  return service({
+    url: '/userCommission/getUserCommissionList',
+    method: 'get',
+    params
+  })
+}
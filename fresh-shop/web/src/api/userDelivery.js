@@ -111,3 +111,19 @@ export const getUserDeliveryAllList = (params) => {
     params
   })
 }
+
+// @Tags UserDelivery
+// @Summary 设置配送员登录账号
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.RiderAccountReq true "配送员id, 登录密码"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"设置成功"}"
+// @Router /userDelivery/setRiderAccount [put]
+export const setRiderAccount = (data) => {
+  return service({
+    url: '/userDelivery/setRiderAccount',
+    method: 'put',
+    data
+  })
+}